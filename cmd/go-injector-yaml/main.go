@@ -0,0 +1,204 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/crypto"
+	"github.com/orange0224/go-injector-yaml/config/type"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const usage = `usage: go-injector-yaml <command> [flags] [files]
+
+commands:
+  keygen                               print a new encryption key
+  encrypt [-key file] files...         encrypt values tagged with !encrypt
+  decrypt [-key file] files...         turn ENC[...] values back into !encrypt values
+  rotate  [-key file] -new-key file files...
+                                       re-encrypt ENC[...] values with a new key
+  schema  -dir dir [-tag t] [-o file]  write the JSON Schema of the @Configuration
+                                       types in dir/config
+  docs    -dir dir [-env-prefix p] [-tag t] [-o file]
+                                       write a Markdown reference of all config keys
+  sample  -dir dir [-tag t] [-o file]  write a commented example application.yaml
+  check   -dir dir [-tag t]            report duplicate types, @Alias collisions,
+                                       recursive config types and @Component
+                                       dependency errors
+
+schema, docs, sample and check also accept -packages, -include, -exclude and
+-tags (comma separated) to scan config types outside dir/config
+
+config keys come from the yaml struct tag (or -tag), untagged fields use the
+lowerCamel field name, e.g. MaxConn is maxConn
+
+without -key the key is read from CONFIG_ENCRYPTION_KEY or CONFIG_ENCRYPTION_KEY_FILE
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "keygen":
+		err = keygen()
+	case "encrypt", "decrypt", "rotate":
+		err = rewrite(os.Args[1], os.Args[2:])
+	case "schema":
+		err = schema(os.Args[2:])
+	case "docs":
+		err = docs(os.Args[2:])
+	case "sample":
+		err = sample(os.Args[2:])
+	case "check":
+		err = check(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func keygen() error {
+	key, err := config_crypto.GenerateKey()
+	if err != nil {
+		return err
+	}
+	fmt.Println(config_crypto.EncodeKey(key))
+	return nil
+}
+
+func schema(args []string) error {
+	flags := flag.NewFlagSet("schema", flag.ExitOnError)
+	scanner := scannerFlags(flags)
+	output := flags.String("o", "", "output file, stdout if empty")
+	flags.Parse(args)
+	result, err := scanner.Schema()
+	if err != nil {
+		return err
+	}
+	return writeOutput(*output, result)
+}
+
+func docs(args []string) error {
+	flags := flag.NewFlagSet("docs", flag.ExitOnError)
+	scanner := scannerFlags(flags)
+	flags.StringVar(&scanner.EnvPrefix, "env-prefix", "", "prefix of the environment variables")
+	output := flags.String("o", "", "output file, stdout if empty")
+	flags.Parse(args)
+	result, err := scanner.Docs()
+	if err != nil {
+		return err
+	}
+	return writeOutput(*output, result)
+}
+
+func sample(args []string) error {
+	flags := flag.NewFlagSet("sample", flag.ExitOnError)
+	scanner := scannerFlags(flags)
+	output := flags.String("o", "", "output file, stdout if empty")
+	flags.Parse(args)
+	result, err := scanner.Sample()
+	if err != nil {
+		return err
+	}
+	return writeOutput(*output, result)
+}
+
+func check(args []string) error {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	scanner := scannerFlags(flags)
+	flags.Parse(args)
+	return scanner.Check()
+}
+
+// listFlag 逗号分隔或多次给出的参数
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// scannerFlags 注册扫描配置类型用到的参数，flags解析后返回的TypeScanner即可使用
+func scannerFlags(flags *flag.FlagSet) *config_type.TypeScanner {
+	scanner := &config_type.TypeScanner{}
+	flags.StringVar(&scanner.ConfigDir, "dir", ".", "project directory that contains the config package")
+	flags.StringVar(&scanner.TagKey, "tag", "", "struct tag that holds the config key, yaml if empty")
+	flags.Var((*listFlag)(&scanner.Packages), "packages", "package patterns relative to dir to scan, e.g. ./...; ./config if empty")
+	flags.Var((*listFlag)(&scanner.Include), "include", "only scan files matching these globs")
+	flags.Var((*listFlag)(&scanner.Exclude), "exclude", "skip files matching these globs, dir/... skips a directory")
+	flags.Var((*listFlag)(&scanner.BuildTags), "tags", "additional build tags")
+	return scanner
+}
+
+func writeOutput(path string, content []byte) error {
+	if path == "" {
+		_, err := os.Stdout.Write(content)
+		return err
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+func rewrite(command string, args []string) error {
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	keyPath := flags.String("key", "", "key file")
+	newKeyPath := flags.String("new-key", "", "new key file (rotate only)")
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		return fmt.Errorf("%s: no files given", command)
+	}
+	key, err := config_crypto.LoadKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	var newKey []byte
+	if command == "rotate" {
+		if *newKeyPath == "" {
+			return fmt.Errorf("rotate: -new-key is required")
+		}
+		if newKey, err = config_crypto.LoadKey(*newKeyPath); err != nil {
+			return err
+		}
+	}
+	for _, file := range flags.Args() {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		var result []byte
+		switch command {
+		case "encrypt":
+			result, err = config_crypto.EncryptDocument(data, key)
+		case "decrypt":
+			result, err = config_crypto.DecryptDocument(data, key)
+		case "rotate":
+			result, err = config_crypto.RotateDocument(data, key, newKey)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", file, err)
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(file, result, info.Mode()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
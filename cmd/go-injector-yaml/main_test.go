@@ -0,0 +1,46 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testdata 与config/type的golden测试共用同一个项目和golden文件
+var testdata = filepath.Join("..", "..", "config", "type", "testdata")
+
+// runCommand 执行子命令，把输出写到临时文件后和golden文件对比
+func runCommand(t *testing.T, command func([]string) error, golden string, args ...string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "go-injector-yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "output")
+	args = append([]string{"-dir", filepath.Join(testdata, "project"), "-o", output}, args...)
+	if err := command(args); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile(filepath.Join(testdata, golden))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("output differs from %s\ngot:\n%s", golden, got)
+	}
+}
+
+func TestDocsCommand(t *testing.T) {
+	runCommand(t, docs, "docs.golden.md")
+	runCommand(t, docs, "docs_env.golden.md", "-env-prefix", "APP_")
+}
+
+func TestSampleCommand(t *testing.T) {
+	runCommand(t, sample, "sample.golden.yaml")
+}
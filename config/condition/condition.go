@@ -0,0 +1,201 @@
+package config_condition
+
+import (
+	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/source"
+	"reflect"
+	"strings"
+)
+
+// Condition 一个条件的判断结果，Reason说明匹配或不匹配的原因
+type Condition struct {
+	// Annotation 条件的注解，例如 @ConditionalOnProperty(cache.enabled=true)
+	Annotation string
+	Matched    bool
+	Reason     string
+}
+
+// Property @ConditionalOnProperty：value不为空时key的值等于value即匹配，为空时key有值且不是false即匹配；
+// values为配置项名称到值，值为nil指针时视为没有值
+func Property(values map[string]interface{}, key, value string) Condition {
+	annotation := "@ConditionalOnProperty(" + key + ")"
+	if value != "" {
+		annotation = "@ConditionalOnProperty(" + key + "=" + value + ")"
+	}
+	actual, ok := propertyValue(values[key])
+	switch {
+	case !ok:
+		return Condition{Annotation: annotation, Reason: key + " is not set"}
+	case value != "":
+		return Condition{Annotation: annotation, Matched: actual == value, Reason: fmt.Sprintf("%s is %q", key, actual)}
+	}
+	return Condition{Annotation: annotation, Matched: actual != "false", Reason: fmt.Sprintf("%s is %q", key, actual)}
+}
+
+func propertyValue(value interface{}) (string, bool) {
+	if value == nil {
+		return "", false
+	}
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	actual := fmt.Sprint(v.Interface())
+	return actual, actual != ""
+}
+
+// Profile @Profile：expression匹配激活的profile，支持 prod、!prod、prod & cloud、prod | dev
+func Profile(profiles []string, expression string) Condition {
+	condition := Condition{Annotation: "@Profile(" + expression + ")"}
+	matched, err := config_source.MatchProfiles(expression, profiles)
+	if err != nil {
+		condition.Reason = err.Error()
+		return condition
+	}
+	condition.Matched = matched
+	condition.Reason = fmt.Sprintf("active profiles are [%s]", strings.Join(profiles, ", "))
+	return condition
+}
+
+// Result 一个组件或配置节所有条件的判断结果，条件全部匹配时Matched为true
+type Result struct {
+	// Name 例如 section cache、component RedisCache
+	Name       string
+	Matched    bool
+	Conditions []Condition
+}
+
+// Evaluate 合并多个条件的结果
+func Evaluate(name string, conditions ...Condition) Result {
+	result := Result{Name: name, Matched: true, Conditions: conditions}
+	for _, condition := range conditions {
+		result.Matched = result.Matched && condition.Matched
+	}
+	return result
+}
+
+// Report 启动时判断的所有条件，每一项说明启用或未启用的原因
+type Report []Result
+
+func (r Report) String() string {
+	var builder strings.Builder
+	for _, result := range r {
+		state := "enabled"
+		if !result.Matched {
+			state = "disabled"
+		}
+		builder.WriteString(fmt.Sprintf("%s: %s\n", result.Name, state))
+		for _, condition := range result.Conditions {
+			mark := "+"
+			if !condition.Matched {
+				mark = "-"
+			}
+			builder.WriteString(fmt.Sprintf("\t%s %s: %s\n", mark, condition.Annotation, condition.Reason))
+		}
+	}
+	return builder.String()
+}
+
+// Evaluator 启动时按依赖顺序判断组件的条件，记录哪些组件被启用
+type Evaluator struct {
+	Values   map[string]interface{}
+	Profiles []string
+	Report   Report
+	results  map[string]Result
+	replaced map[string]bool
+}
+
+// NewEvaluator 使用加载后的配置项和激活的profile创建Evaluator
+func NewEvaluator(values map[string]interface{}, profiles []string) *Evaluator {
+	return &Evaluator{Values: values, Profiles: profiles, Report: make(Report, 0), results: make(map[string]Result), replaced: make(map[string]bool)}
+}
+
+// Replace 组件name被替换为其他实例，例如测试中的fake，Enable时不判断它的条件和依赖，总是启用
+func (e *Evaluator) Replace(name string) {
+	e.replaced[name] = true
+}
+
+// Property 见Property函数
+func (e *Evaluator) Property(key, value string) Condition {
+	return Property(e.Values, key, value)
+}
+
+// Profile 见Profile函数
+func (e *Evaluator) Profile(expression string) Condition {
+	return Profile(e.Profiles, expression)
+}
+
+// Component @ConditionalOnComponent、@ConditionalOnMissingComponent：组件name已经启用或没有启用时匹配
+func (e *Evaluator) Component(name string, missing bool) Condition {
+	annotation := "@ConditionalOnComponent(" + name + ")"
+	if missing {
+		annotation = "@ConditionalOnMissingComponent(" + name + ")"
+	}
+	enabled := e.Enabled(name)
+	reason := "component " + name + " is enabled"
+	if !enabled {
+		reason = "component " + name + " is disabled"
+	}
+	return Condition{Annotation: annotation, Matched: enabled != missing, Reason: reason}
+}
+
+// Enable 记录组件name的判断结果，有条件或被替换时加入Report；条件都匹配但依赖的组件没有启用时返回错误
+func (e *Evaluator) Enable(name string, dependencies []string, conditions ...Condition) error {
+	if e.replaced[name] {
+		conditions, dependencies = []Condition{{Annotation: "replaced", Matched: true, Reason: "component " + name + " is replaced"}}, nil
+	}
+	result := Evaluate("component "+name, conditions...)
+	e.results[name] = result
+	if len(conditions) > 0 {
+		e.Report = append(e.Report, result)
+	}
+	if !result.Matched {
+		return nil
+	}
+	for _, dependency := range dependencies {
+		if !e.Enabled(dependency) {
+			return fmt.Errorf("component %s depends on disabled component %s, add @ConditionalOnComponent(%s) to %s", name, dependency, dependency, name)
+		}
+	}
+	return nil
+}
+
+// Enabled 组件name是否启用
+func (e *Evaluator) Enabled(name string) bool {
+	return e.results[name].Matched
+}
+
+// One 返回names中唯一启用的组件，用于多个带条件的组件提供同一个类型typ时选择注入的组件
+func (e *Evaluator) One(typ string, names ...string) (string, error) {
+	enabled := make([]string, 0, 1)
+	for _, name := range names {
+		if e.Enabled(name) {
+			enabled = append(enabled, name)
+		}
+	}
+	switch len(enabled) {
+	case 0:
+		return "", fmt.Errorf("none of the components providing %s is enabled: %s", typ, strings.Join(names, ", "))
+	case 1:
+		return enabled[0], nil
+	}
+	return "", fmt.Errorf("more than one enabled component provides %s: %s", typ, strings.Join(enabled, ", "))
+}
+
+// Check 组件name没有启用时返回说明原因的错误
+func (e *Evaluator) Check(name string) error {
+	result, ok := e.results[name]
+	if !ok || result.Matched {
+		return nil
+	}
+	reasons := make([]string, 0, len(result.Conditions))
+	for _, condition := range result.Conditions {
+		if !condition.Matched {
+			reasons = append(reasons, condition.Annotation+": "+condition.Reason)
+		}
+	}
+	return fmt.Errorf("component %s is disabled: %s", name, strings.Join(reasons, "; "))
+}
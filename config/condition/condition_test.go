@@ -0,0 +1,219 @@
+package config_condition
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestProperty(t *testing.T) {
+	port, empty := 8080, ""
+	var missing *int
+	values := map[string]interface{}{
+		"cache.enabled": true,
+		"cache.type":    "redis",
+		"debug":         false,
+		"server.port":   &port,
+		"server.host":   &empty,
+		"server.max":    missing,
+		"server.name":   "",
+	}
+	tests := []struct {
+		key, value string
+		want       Condition
+	}{
+		{"cache.enabled", "true", Condition{"@ConditionalOnProperty(cache.enabled=true)", true, `cache.enabled is "true"`}},
+		{"cache.enabled", "", Condition{"@ConditionalOnProperty(cache.enabled)", true, `cache.enabled is "true"`}},
+		{"cache.type", "memory", Condition{"@ConditionalOnProperty(cache.type=memory)", false, `cache.type is "redis"`}},
+		{"debug", "", Condition{"@ConditionalOnProperty(debug)", false, `debug is "false"`}},
+		{"debug", "false", Condition{"@ConditionalOnProperty(debug=false)", true, `debug is "false"`}},
+		{"server.port", "8080", Condition{"@ConditionalOnProperty(server.port=8080)", true, `server.port is "8080"`}},
+		{"server.host", "", Condition{"@ConditionalOnProperty(server.host)", false, "server.host is not set"}},
+		{"server.max", "", Condition{"@ConditionalOnProperty(server.max)", false, "server.max is not set"}},
+		{"server.name", "", Condition{"@ConditionalOnProperty(server.name)", false, "server.name is not set"}},
+		{"unknown", "x", Condition{"@ConditionalOnProperty(unknown=x)", false, "unknown is not set"}},
+	}
+	for _, test := range tests {
+		if got := Property(values, test.key, test.value); got != test.want {
+			t.Errorf("Property(%s, %q) = %+v, want %+v", test.key, test.value, got, test.want)
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	tests := []struct {
+		profiles   []string
+		expression string
+		want       Condition
+	}{
+		{[]string{"prod"}, "prod", Condition{"@Profile(prod)", true, "active profiles are [prod]"}},
+		{nil, "prod", Condition{"@Profile(prod)", false, "active profiles are []"}},
+		{nil, "!prod", Condition{"@Profile(!prod)", true, "active profiles are []"}},
+		{[]string{"prod", "cloud"}, "prod & cloud", Condition{"@Profile(prod & cloud)", true, "active profiles are [prod, cloud]"}},
+		{[]string{"dev"}, "prod | test", Condition{"@Profile(prod | test)", false, "active profiles are [dev]"}},
+	}
+	for _, test := range tests {
+		if got := Profile(test.profiles, test.expression); got != test.want {
+			t.Errorf("Profile(%v, %q) = %+v, want %+v", test.profiles, test.expression, got, test.want)
+		}
+	}
+	if got := Profile(nil, "prod &"); got.Matched || got.Reason == "" {
+		t.Errorf("malformed expression: %+v", got)
+	}
+}
+
+// component 按顺序交给Evaluator.Enable的组件
+type component struct {
+	name         string
+	dependencies []string
+	conditions   func(e *Evaluator) []Condition
+}
+
+func TestEvaluator(t *testing.T) {
+	property := func(key, value string) func(e *Evaluator) []Condition {
+		return func(e *Evaluator) []Condition { return []Condition{e.Property(key, value)} }
+	}
+	tests := []struct {
+		name       string
+		values     map[string]interface{}
+		profiles   []string
+		replaced   []string
+		components []component
+		enabled    []string
+		// errors 每个组件Enable返回的错误，没有错误的组件不出现
+		errors map[string]string
+		report string
+	}{
+		{
+			name:   "component conditions in dependency order",
+			values: map[string]interface{}{"cache.type": "redis"},
+			components: []component{
+				{name: "RedisCache", conditions: property("cache.type", "redis")},
+				{name: "MemoryCache", conditions: func(e *Evaluator) []Condition {
+					return []Condition{e.Component("RedisCache", true)}
+				}},
+				{name: "CacheMetrics", conditions: func(e *Evaluator) []Condition {
+					return []Condition{e.Component("RedisCache", false)}
+				}},
+				{name: "Service", dependencies: []string{"RedisCache"}},
+			},
+			enabled: []string{"RedisCache", "CacheMetrics", "Service"},
+			report: "component RedisCache: enabled\n\t+ @ConditionalOnProperty(cache.type=redis): cache.type is \"redis\"\n" +
+				"component MemoryCache: disabled\n\t- @ConditionalOnMissingComponent(RedisCache): component RedisCache is enabled\n" +
+				"component CacheMetrics: enabled\n\t+ @ConditionalOnComponent(RedisCache): component RedisCache is enabled\n",
+		},
+		{
+			name:     "all conditions must match",
+			values:   map[string]interface{}{"cache.enabled": true},
+			profiles: []string{"dev"},
+			components: []component{
+				{name: "Cache", conditions: func(e *Evaluator) []Condition {
+					return []Condition{e.Property("cache.enabled", ""), e.Profile("prod")}
+				}},
+			},
+			enabled: []string{},
+			report:  "component Cache: disabled\n\t+ @ConditionalOnProperty(cache.enabled): cache.enabled is \"true\"\n\t- @Profile(prod): active profiles are [dev]\n",
+		},
+		{
+			name: "enabled component depends on a disabled one",
+			components: []component{
+				{name: "RedisCache", conditions: property("cache.type", "redis")},
+				{name: "Service", dependencies: []string{"RedisCache"}},
+				{name: "Optional", dependencies: []string{"RedisCache"}, conditions: property("cache.type", "redis")},
+			},
+			enabled: []string{"Service"},
+			errors: map[string]string{
+				"Service": "component Service depends on disabled component RedisCache, add @ConditionalOnComponent(RedisCache) to Service",
+			},
+			report: "component RedisCache: disabled\n\t- @ConditionalOnProperty(cache.type=redis): cache.type is not set\n" +
+				"component Optional: disabled\n\t- @ConditionalOnProperty(cache.type=redis): cache.type is not set\n",
+		},
+		{
+			name:     "replaced components skip conditions and dependencies",
+			replaced: []string{"RedisCache", "Service"},
+			components: []component{
+				{name: "RedisCache", conditions: property("cache.type", "redis")},
+				{name: "Missing", conditions: property("missing", "")},
+				{name: "Service", dependencies: []string{"Missing"}},
+			},
+			enabled: []string{"RedisCache", "Service"},
+			report: "component RedisCache: enabled\n\t+ replaced: component RedisCache is replaced\n" +
+				"component Missing: disabled\n\t- @ConditionalOnProperty(missing): missing is not set\n" +
+				"component Service: enabled\n\t+ replaced: component Service is replaced\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			e := NewEvaluator(test.values, test.profiles)
+			for _, name := range test.replaced {
+				e.Replace(name)
+			}
+			errors := make(map[string]string)
+			enabled := make([]string, 0)
+			for _, c := range test.components {
+				var conditions []Condition
+				if c.conditions != nil {
+					conditions = c.conditions(e)
+				}
+				if err := e.Enable(c.name, c.dependencies, conditions...); err != nil {
+					errors[c.name] = err.Error()
+				}
+				if e.Enabled(c.name) {
+					enabled = append(enabled, c.name)
+				}
+			}
+			if test.errors == nil {
+				test.errors = map[string]string{}
+			}
+			if !reflect.DeepEqual(errors, test.errors) {
+				t.Errorf("errors %v, want %v", errors, test.errors)
+			}
+			if !reflect.DeepEqual(enabled, test.enabled) {
+				t.Errorf("enabled %v, want %v", enabled, test.enabled)
+			}
+			if report := e.Report.String(); report != test.report {
+				t.Errorf("report\n%s\nwant\n%s", report, test.report)
+			}
+		})
+	}
+}
+
+func TestEvaluatorOneAndCheck(t *testing.T) {
+	e := NewEvaluator(map[string]interface{}{"cache.type": "redis"}, nil)
+	e.Enable("RedisCache", nil, e.Property("cache.type", "redis"))
+	e.Enable("MemoryCache", nil, e.Property("cache.type", "memory"))
+	e.Enable("FileCache", nil, e.Property("cache.type", "file"))
+	e.Enable("Plain", nil)
+	tests := []struct {
+		names []string
+		one   string
+		err   string
+	}{
+		{[]string{"RedisCache", "MemoryCache"}, "RedisCache", ""},
+		{[]string{"MemoryCache", "FileCache"}, "", "none of the components providing Cache is enabled: MemoryCache, FileCache"},
+		{[]string{"RedisCache", "Plain"}, "", "more than one enabled component provides Cache: RedisCache, Plain"},
+	}
+	for _, test := range tests {
+		one, err := e.One("Cache", test.names...)
+		if one != test.one || errorText(err) != test.err {
+			t.Errorf("One(%v) = %q, %v, want %q, %s", test.names, one, err, test.one, test.err)
+		}
+	}
+	checks := map[string]string{
+		"RedisCache":  "",
+		"Plain":       "",
+		"Unknown":     "",
+		"MemoryCache": `component MemoryCache is disabled: @ConditionalOnProperty(cache.type=memory): cache.type is "redis"`,
+	}
+	for name, want := range checks {
+		if err := e.Check(name); errorText(err) != want {
+			t.Errorf("Check(%s) = %v, want %s", name, err, want)
+		}
+	}
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,158 @@
+package config_crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/utils"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	KeyEnv     = "CONFIG_ENCRYPTION_KEY"
+	KeyFileEnv = "CONFIG_ENCRYPTION_KEY_FILE"
+	KeySize    = 32
+
+	prefix = "ENC[AES256_GCM,"
+	suffix = "]"
+)
+
+// IsEncrypted 判断配置值是否为ENC[AES256_GCM,...]格式的密文
+func IsEncrypted(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, prefix) && strings.HasSuffix(value, suffix)
+}
+
+// GenerateKey 生成一个随机的AES-256密钥
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// EncodeKey 将密钥编码为可以写入密钥文件或环境变量的字符串
+func EncodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// ParseKey 解析base64或hex编码的密钥
+func ParseKey(text string) ([]byte, error) {
+	text = strings.TrimSpace(text)
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(text); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("encryption key must be %d bytes encoded as base64 or hex", KeySize)
+}
+
+// LoadKey 从密钥文件读取密钥，path为空时依次读取CONFIG_ENCRYPTION_KEY和CONFIG_ENCRYPTION_KEY_FILE环境变量
+func LoadKey(path string) ([]byte, error) {
+	if utils.IsBlank(path) {
+		if text := os.Getenv(KeyEnv); utils.NotBlank(text) {
+			return ParseKey(text)
+		}
+		path = os.Getenv(KeyFileEnv)
+	}
+	if utils.IsBlank(path) {
+		return nil, fmt.Errorf("no encryption key: set %s or %s", KeyEnv, KeyFileEnv)
+	}
+	by, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKey(string(by))
+}
+
+// Encrypt 使用AES-256-GCM加密明文，返回ENC[AES256_GCM,data:...,iv:...,tag:...]
+func Encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, []byte(plaintext), nil)
+	data, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return prefix +
+		"data:" + base64.StdEncoding.EncodeToString(data) +
+		",iv:" + base64.StdEncoding.EncodeToString(iv) +
+		",tag:" + base64.StdEncoding.EncodeToString(tag) +
+		suffix, nil
+}
+
+// Decrypt 解密Encrypt生成的密文
+func Decrypt(key []byte, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if !IsEncrypted(value) {
+		return "", fmt.Errorf("value is not encrypted")
+	}
+	fields := make(map[string][]byte)
+	for _, item := range strings.Split(value[len(prefix):len(value)-len(suffix)], ",") {
+		index := strings.Index(item, ":")
+		if index == -1 {
+			return "", fmt.Errorf("malformed encrypted value: %q", item)
+		}
+		by, err := base64.StdEncoding.DecodeString(item[index+1:])
+		if err != nil {
+			return "", fmt.Errorf("malformed encrypted value %s: %v", item[:index], err)
+		}
+		fields[item[:index]] = by
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(fields["iv"]) != gcm.NonceSize() || len(fields["tag"]) != gcm.Overhead() {
+		return "", fmt.Errorf("malformed encrypted value: bad iv or tag")
+	}
+	plaintext, err := gcm.Open(nil, fields["iv"], append(fields["data"], fields["tag"]...), nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt value: wrong key or corrupted data")
+	}
+	return string(plaintext), nil
+}
+
+// DecryptValues 原地解密配置集合中所有的密文值，只有存在密文时才会加载密钥
+func DecryptValues(values map[string]string, keyPath string) error {
+	var key []byte
+	for k, v := range values {
+		if !IsEncrypted(v) {
+			continue
+		}
+		if key == nil {
+			loaded, err := LoadKey(keyPath)
+			if err != nil {
+				return err
+			}
+			key = loaded
+		}
+		plaintext, err := Decrypt(key, v)
+		if err != nil {
+			return fmt.Errorf("%s: %v", k, err)
+		}
+		values[k] = plaintext
+	}
+	return nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes", KeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
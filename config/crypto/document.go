@@ -0,0 +1,150 @@
+package config_crypto
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EncryptTag 标记需要加密的明文值，例如 password: !encrypt secret
+const EncryptTag = "!encrypt"
+
+// EncryptDocument 加密yaml中所有带!encrypt标记的值，其余内容和注释保持不变
+func EncryptDocument(data, key []byte) ([]byte, error) {
+	return rewriteDocument(data, func(node *yaml.Node) (string, bool, error) {
+		if node.Tag != EncryptTag {
+			return "", false, nil
+		}
+		value, err := Encrypt(key, node.Value)
+		return value, true, err
+	})
+}
+
+// DecryptDocument 把yaml中的密文还原为带!encrypt标记的明文，方便编辑后再次加密
+func DecryptDocument(data, key []byte) ([]byte, error) {
+	return rewriteDocument(data, func(node *yaml.Node) (string, bool, error) {
+		if !IsEncrypted(node.Value) {
+			return "", false, nil
+		}
+		plaintext, err := Decrypt(key, node.Value)
+		if err != nil {
+			return "", true, err
+		}
+		value, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Tag: EncryptTag, Value: plaintext, Style: yaml.DoubleQuotedStyle})
+		return strings.TrimRight(string(value), "\n"), true, err
+	})
+}
+
+// RotateDocument 使用旧密钥解密、新密钥重新加密yaml中所有的密文
+func RotateDocument(data, oldKey, newKey []byte) ([]byte, error) {
+	return rewriteDocument(data, func(node *yaml.Node) (string, bool, error) {
+		if !IsEncrypted(node.Value) {
+			return "", false, nil
+		}
+		plaintext, err := Decrypt(oldKey, node.Value)
+		if err != nil {
+			return "", true, err
+		}
+		value, err := Encrypt(newKey, plaintext)
+		return value, true, err
+	})
+}
+
+type replacement struct {
+	path         string
+	node         *yaml.Node
+	line, column int
+	comment      string
+	value        string
+}
+
+// rewriteDocument 逐个替换标量值所在的文本片段，不重新序列化整个文档
+func rewriteDocument(data []byte, replace func(node *yaml.Node) (string, bool, error)) ([]byte, error) {
+	replacements := make([]replacement, 0)
+	var walk func(node *yaml.Node, path string, flow bool) error
+	walk = func(node *yaml.Node, path string, flow bool) error {
+		switch node.Kind {
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for i, child := range node.Content {
+				childPath := path
+				if node.Kind == yaml.SequenceNode {
+					childPath = fmt.Sprintf("%s[%d]", path, i)
+				}
+				if err := walk(child, childPath, flow || node.Style&yaml.FlowStyle != 0); err != nil {
+					return err
+				}
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				childPath := node.Content[i].Value
+				if path != "" {
+					childPath = path + "." + childPath
+				}
+				if err := walk(node.Content[i+1], childPath, flow || node.Style&yaml.FlowStyle != 0); err != nil {
+					return err
+				}
+			}
+		case yaml.ScalarNode:
+			value, ok, err := replace(node)
+			if err != nil {
+				return fmt.Errorf("%s (line %d): %v", path, node.Line, err)
+			}
+			if !ok {
+				return nil
+			}
+			if flow || node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+				return fmt.Errorf("%s (line %d): only single line values in block mappings can be rewritten", path, node.Line)
+			}
+			replacements = append(replacements, replacement{path: path, node: node, line: node.Line, column: node.Column, comment: node.LineComment, value: value})
+		}
+		return nil
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document yaml.Node
+		err := decoder.Decode(&document)
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		if err := walk(&document, "", false); err != nil {
+			return nil, err
+		}
+	}
+	lines := strings.Split(string(data), "\n")
+	for _, r := range replacements {
+		line := []rune(lines[r.line-1])
+		start := r.column - 1
+		if start > len(line) {
+			return nil, fmt.Errorf("line %d: value position out of range", r.line)
+		}
+		end := len(line)
+		if r.comment != "" {
+			if index := strings.LastIndex(string(line), r.comment); index != -1 {
+				end = len([]rune(string(line)[:index]))
+			}
+		}
+		value := strings.TrimRight(string(line[start:end]), " \t\r")
+		if !sameScalar(value, r.node) {
+			return nil, fmt.Errorf("%s (line %d): only values written on a single line can be rewritten", r.path, r.line)
+		}
+		rest := string(line[start+len([]rune(value)):])
+		lines[r.line-1] = string(line[:start]) + r.value + rest
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// sameScalar text单独解析时是否得到与node相同的标量，多行的值在一行中只有一部分，解析结果不同
+func sameScalar(text string, node *yaml.Node) bool {
+	var document yaml.Node
+	if err := yaml.Unmarshal([]byte(text), &document); err != nil || len(document.Content) != 1 {
+		return false
+	}
+	scalar := document.Content[0]
+	return scalar.Kind == yaml.ScalarNode && scalar.Tag == node.Tag && scalar.Value == node.Value
+}
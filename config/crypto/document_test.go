@@ -0,0 +1,129 @@
+package config_crypto
+
+import (
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestDocumentRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		secrets []string
+	}{
+		{
+			name:    "plain value",
+			input:   "db:\n  user: bob\n  password: !encrypt secret\n",
+			secrets: []string{"secret"},
+		},
+		{
+			name:    "comments are kept",
+			input:   "# database\ndb:\n  # the password\n  password: !encrypt secret # rotate yearly\n  port: 5432 # default\n",
+			secrets: []string{"secret"},
+		},
+		{
+			name:    "quoted values",
+			input:   "a: !encrypt \"with # hash\"\nb: !encrypt 'it''s'\n",
+			secrets: []string{"with # hash", "it's"},
+		},
+		{
+			name:    "sequence",
+			input:   "tokens:\n  - !encrypt one\n  - plain\n  - !encrypt two\n",
+			secrets: []string{"one", "two"},
+		},
+		{
+			name:    "multiple documents",
+			input:   "password: !encrypt first\n---\nprofiles: [prod]\npassword: !encrypt second # prod\n",
+			secrets: []string{"first", "second"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			oldKey, newKey := testKey(t), testKey(t)
+			encrypted, err := EncryptDocument([]byte(test.input), oldKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rotated, err := RotateDocument(encrypted, oldKey, newKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, document := range []string{string(encrypted), string(rotated)} {
+				if strings.Contains(document, EncryptTag) {
+					t.Fatalf("plaintext left in:\n%s", document)
+				}
+				for _, secret := range test.secrets {
+					if strings.Contains(document, secret) {
+						t.Fatalf("secret %q left in:\n%s", secret, document)
+					}
+				}
+				if strings.Count(document, "ENC[") != len(test.secrets) {
+					t.Fatalf("expected %d encrypted values in:\n%s", len(test.secrets), document)
+				}
+			}
+			if _, err := DecryptDocument(rotated, oldKey); err == nil {
+				t.Fatal("decrypting with the old key should fail after rotate")
+			}
+			decrypted, err := DecryptDocument(rotated, newKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reencrypted, err := EncryptDocument(decrypted, newKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := DecryptDocument(reencrypted, newKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(decrypted) {
+				t.Fatalf("decrypt is not stable:\n%s\n---\n%s", decrypted, again)
+			}
+			inputLines, outputLines := strings.Split(test.input, "\n"), strings.Split(string(decrypted), "\n")
+			if len(inputLines) != len(outputLines) {
+				t.Fatalf("line count changed:\n%s", decrypted)
+			}
+			for i := range inputLines {
+				if !strings.Contains(inputLines[i], EncryptTag) && inputLines[i] != outputLines[i] {
+					t.Fatalf("line %d changed from %q to %q", i+1, inputLines[i], outputLines[i])
+				}
+				if index := strings.Index(inputLines[i], " #"); index != -1 && !strings.HasSuffix(outputLines[i], inputLines[i][index:]) {
+					t.Fatalf("line %d lost its comment: %q", i+1, outputLines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDocumentRejectsMultiLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"plain", "multi: !encrypt first\n    second\n"},
+		{"double quoted", "multi: !encrypt \"first\n    second\"\n"},
+		{"single quoted", "multi: !encrypt 'first\n    second'\n"},
+		{"literal", "multi: !encrypt |\n    first\n    second\n"},
+		{"folded", "multi: !encrypt >\n    first\n    second\n"},
+		{"flow", "multi: {a: !encrypt first}\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output, err := EncryptDocument([]byte(test.input), testKey(t))
+			if err == nil {
+				t.Fatalf("expected an error, got:\n%s", output)
+			}
+			if !strings.Contains(err.Error(), "multi") {
+				t.Fatalf("error should name the key: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package config_generator
+
+import (
+	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/model"
+	"strconv"
+	"strings"
+)
+
+// conditionalSections ApplicationConfig中带有条件注解的配置节字段
+func (g *Generator) conditionalSections() []*config_model.Field {
+	fields := make([]*config_model.Field, 0)
+	for _, field := range g.models["ApplicationConfig"].Fields {
+		if typ, _ := g.nestedType(field.Expr); typ != nil && len(typ.Conditions) > 0 && !field.Inline {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// generateConditions 生成Loader.conditions：加载配置后判断target中配置节的条件，未启用的配置节恢复为零值，
+// 返回判断结果和这些配置节的key，Load跳过它们的加载错误
+func (g *Generator) generateConditions() string {
+	w := &codeWriter{}
+	w.line("")
+	w.open("func (l *Loader) conditions(target *ApplicationConfig, profiles []string) (config_condition.Report, []string) {")
+	sections := g.conditionalSections()
+	if len(sections) == 0 {
+		w.line("return nil, nil")
+		w.close("}")
+		return w.builder.String()
+	}
+	w.line("values := l.register(*target)")
+	w.line("report := make(config_condition.Report, 0)")
+	w.line("disabled := make([]string, 0)")
+	for _, field := range sections {
+		typ, _ := g.nestedType(field.Expr)
+		args := make([]string, 0, len(typ.Conditions))
+		for _, condition := range typ.Conditions {
+			args = append(args, conditionExpr(condition, "config_condition.", "values, ", "profiles, "))
+		}
+		w.open("{")
+		w.line("result := config_condition.Evaluate(%q, %s)", "section "+field.Key, strings.Join(args, ", "))
+		w.line("report = append(report, result)")
+		w.open("if !result.Matched {")
+		w.line("target.%s = ApplicationConfig{}.%s", field.Name, field.Name)
+		w.line("disabled = append(disabled, %q)", field.Key)
+		w.close("}")
+		w.close("}")
+	}
+	w.line("return report, disabled")
+	w.close("}")
+	return w.builder.String()
+}
+
+// conditionExpr 判断条件的表达式，prefix为config_condition包或者Evaluator，values、profiles为Property、Profile的第一个参数
+func conditionExpr(condition *config_model.Condition, prefix, values, profiles string) string {
+	switch condition.Annotation {
+	case config_model.ConditionalOnProperty:
+		key, value := condition.Property()
+		return fmt.Sprintf("%sProperty(%s%q, %q)", prefix, values, key, value)
+	case config_model.Profile:
+		return fmt.Sprintf("%sProfile(%s%q)", prefix, profiles, condition.Argument)
+	}
+	missing := condition.Annotation == config_model.ConditionalOnMissingComponent
+	return fmt.Sprintf("%sComponent(%q, %s)", prefix, condition.Argument, strconv.FormatBool(missing))
+}
+
+// hasConditions 是否有组件带有条件注解，没有时生成的Container不判断条件
+func hasConditions(components []*config_model.Component) bool {
+	for _, component := range components {
+		if len(component.Conditions) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// writeEnable 判断组件的条件并记录结果，依赖的组件没有启用时NewContainer返回错误
+func writeEnable(w *codeWriter, component *config_model.Component, wiring *config_model.Wiring) {
+	dependencies := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, dependency := range wiring.Dependencies(component) {
+		if !seen[dependency.Name] {
+			seen[dependency.Name] = true
+			dependencies = append(dependencies, strconv.Quote(dependency.Name))
+		}
+	}
+	args := []string{strconv.Quote(component.Name), "nil"}
+	if len(dependencies) > 0 {
+		args[1] = "[]string{" + strings.Join(dependencies, ", ") + "}"
+	}
+	for _, condition := range component.Conditions {
+		args = append(args, conditionExpr(condition, "c.conditions.", "", ""))
+	}
+	w.open("if err := c.conditions.Enable(%s); err != nil {", strings.Join(args, ", "))
+	w.line("return nil, err")
+	w.close("}")
+}
+
+// conditionalExecute @AutoExecute注入的带有条件的组件，这些组件都启用时才调用
+func conditionalExecute(execute *config_model.Execute, wiring *config_model.Wiring) []string {
+	providers := make([]*config_model.Provider, 0)
+	if execute.Receiver != nil {
+		providers = append(providers, wiring.ReceiverProvider(execute.Receiver))
+	}
+	for _, param := range execute.Params {
+		if param.Value == "" {
+			providers = append(providers, wiring.Provider(param))
+		}
+	}
+	names := make([]string, 0)
+	for _, provider := range providers {
+		if provider != nil && provider.Component != nil && len(provider.Component.Conditions) > 0 {
+			names = append(names, provider.Component.Name)
+		}
+	}
+	return names
+}
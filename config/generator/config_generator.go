@@ -2,75 +2,31 @@ package config_generator
 
 import (
 	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/model"
+	"github.com/orange0224/go-injector-yaml/config/type"
 	"github.com/orange0224/go-injector-yaml/config/utils"
+	"go/token"
 	"io/ioutil"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
 )
 
 const (
-	Template1 = `
-case "{$type}":
-		target := original.({$type})
-		valueOfObject = reflect.ValueOf(&target)
-		typeOfObject = reflect.TypeOf(&target)
-`
-	Template2 = `
-case "{$type}":
-					valueOfObject.Elem().FieldByName(field.Name).Set(reflect.ValueOf(result.({$type})))
-`
-	Header = `
-func (l *Loader)mergeConfig(loadedConfig map[string]string, prefix, realType string, original interface{}, validator func(str string) bool) interface{} {
-	var valueOfObject reflect.Value
-	var typeOfObject reflect.Type
-
-	switch realType {
-case "ApplicationConfig":
-		target := original.(ApplicationConfig)
-		valueOfObject = reflect.ValueOf(&target)
-		typeOfObject = reflect.TypeOf(&target)
-`
-	Content1 = `
-}
+	ConfigLoaderTemplate = `// Code generated by go-injector-yaml. DO NOT EDIT.
 
-	if typeOfObject.Elem().Kind() == reflect.Struct {
-		for i := 0; i < typeOfObject.Elem().NumField(); i++ {
-			field := typeOfObject.Elem().Field(i)
-			fieldValue := valueOfObject.Elem().FieldByName(field.Name)
-			key := field.Tag[6 : len(field.Tag)-1]
-			if field.Type.Kind() == reflect.Struct {
-				oldPrefix := prefix
-				prefix += string(key) + "."
-				result := l.mergeConfig(loadedConfig, prefix, field.Type.Name(), fieldValue.Interface(), validator)
-				switch field.Type.Name() {
-`
-	Footer = `
-}
-				prefix = oldPrefix
-			} else {
-				if validator(loadedConfig[prefix+string(key)]) {
-					valueOfObject.Elem().FieldByName(field.Name).SetString(loadedConfig[prefix+string(key)])
-				}
-			}
-		}
-	}
-	return valueOfObject.Elem().Interface()
-}
-`
-	ConfigLoaderTemplate = `package config
+package config
 
 import (
+	"context"
 	"fmt"
-	"go-injector-yaml/config/utils"
-	"io/ioutil"
-	"net/http"
+	"github.com/orange0224/go-injector-yaml/config/condition"
+	"github.com/orange0224/go-injector-yaml/config/crypto"
+	"github.com/orange0224/go-injector-yaml/config/source"
+	"github.com/orange0224/go-injector-yaml/config/utils"
 	"os"
-	"reflect"
-	"strings"
-
-	"gopkg.in/yaml.v2"
+	"sync"
+	//@ImportGenerate
 )
 
 type Loader struct {
@@ -78,127 +34,202 @@ type Loader struct {
 	Cloud        bool
 	CloudAddress string
 	ConfigPath   string
+	// MountDir Kubernetes挂载的ConfigMap/Secret目录，每个文件一个key
+	MountDir string
+	KeyFile  string
+	// Profiles 激活的profile，为空时读取CONFIG_PROFILES环境变量
+	Profiles []string
+	// EnvPrefix 环境变量的前缀，例如 APP_ 时 APP_SERVER_PORT 对应 server.port；为空时不读取环境变量，
+	// 避免 SERVER_PORT=tcp://... 这类与配置无关的变量覆盖配置
+	EnvPrefix string
+	// CloudOptions 配置中心的超时、重试、认证、TLS、缓存和返回格式设置，为nil时使用默认设置，URL为空时使用CloudAddress
+	CloudOptions *config_source.Remote
+	// Sources 按顺序加载的配置来源，后面的覆盖前面的；为空时使用 file → dir → cloud → env（设置了EnvPrefix时） → args
+	Sources []config_source.Source
+	// Strict 为true时来源中出现ApplicationConfig没有的key会报错
+	Strict bool
+
+	loadErrors config_source.ErrorList
+	legacyKeys []config_source.LegacyKey
 }
 
 func (l *Loader) configValidator() {
 	if l.Cloud {
-		if utils.IsBlank(l.CloudAddress) {
+		if utils.IsBlank(l.CloudAddress) && (l.CloudOptions == nil || utils.IsBlank(l.CloudOptions.URL)) {
 			panic("cloud config path cannot be empty if cloud is enabled")
 		}
 	}
 }
 func (l *Loader) Begin() {
 	l.configValidator()
-	l.initDefaultConfig()
-	if l.External {
-		if utils.NotBlank(l.ConfigPath) {
-			l.initConfigFromFile()
-		}
-		if l.Cloud {
-			l.initConfigFromCloud()
-		}
-		l.initConfigFromArgs()
-	}
-	config = l.register(applicationConfig)
-	configStr = l.getStringSet(config)
-}
-
-//@DefaultConfigGenerate
-//@AutoExecuteGenerate
-
-func (l *Loader) loadConfigFromFile() (int, []byte, error) {
-	//file, err := os.Open("/home/lxs/GoProject/storage/part9/apiServer/src/application.yaml")
-	file, err := os.Open(l.ConfigPath)
-	if err != nil {
-		return 0, nil, err
+	if err := l.Load(context.Background()); err != nil {
+		panic(err)
 	}
-	defer file.Close()
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return 0, nil, err
+	if report := ConditionReport(); len(report) > 0 {
+		fmt.Print(report)
 	}
-	fileSize := fileInfo.Size()
-	buffer := make([]byte, fileSize)
-	bytes, err := file.Read(buffer)
-	if err != nil {
-		return 0, nil, err
+	if err := l.autoExecute(); err != nil {
+		panic(err)
 	}
-	return bytes, buffer, nil
 }
-func (l *Loader) initConfigFromFile() {
-	_, bytes, err := l.loadConfigFromFile()
+
+// Load 依次从所有来源加载配置，全部成功后才替换当前配置；
+// 类型错误、未知key和不符合validate标签的值以 file:line:col: key: message 的格式返回
+func (l *Loader) Load(ctx context.Context) error {
+	profiles := config_source.ActiveProfiles(l.Profiles)
+	cfg, report, legacyKeys, err := l.build(ctx, profiles)
 	if err != nil {
-		return
+		return err
 	}
-	l.loadConfigFromBytes(bytes)
+	configLock.Lock()
+	applicationConfig = cfg
+	config = l.register(cfg)
+	configStr = l.getStringSet(config)
+	conditionReport, activeProfiles = report, profiles
+	l.legacyKeys = legacyKeys
+	configLock.Unlock()
+	for _, legacy := range legacyKeys {
+		if legacy.Deprecated || legacy.Ignored {
+			fmt.Println(legacy.String())
+		}
+	}
+	return nil
 }
-func (l *Loader) loadConfigFromBytes(bytes []byte) {
-	var fileConfig ApplicationConfig
-	yaml.Unmarshal(bytes, &fileConfig)
-	configMap := l.register(fileConfig)
-	configStringMap := l.getStringSet(configMap)
-	configLocal := l.mergeConfig(configStringMap, "", "ApplicationConfig", applicationConfig, utils.NotBlank)
-	applicationConfig = configLocal.(ApplicationConfig)
+
+// Build 与Load相同地加载配置，但只返回结果，不替换当前配置
+func (l *Loader) Build(ctx context.Context) (ApplicationConfig, error) {
+	cfg, _, _, err := l.build(ctx, config_source.ActiveProfiles(l.Profiles))
+	return cfg, err
 }
-func (l *Loader) initConfigFromCloud() {
-	url := l.CloudAddress
-	fmt.Println("get config from cloud:")
-	response, err := http.Get(url)
-	if err != nil {
-		return
-	}
-	defer response.Body.Close()
-	buffer, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return
+
+// build 从所有来源加载配置到新的ApplicationConfig，profiles用于判断配置节的条件；不读写当前配置
+func (l *Loader) build(ctx context.Context, profiles []string) (ApplicationConfig, config_condition.Report, []config_source.LegacyKey, error) {
+	var cfg ApplicationConfig
+	sources := l.getSources()
+	relaxKeys := l.getKeys()
+	for key := range keyAliases {
+		relaxKeys = append(relaxKeys, key)
 	}
-	l.loadConfigFromBytes(buffer)
-	fmt.Println("load cloud config success!")
-}
-func (l *Loader) initConfigFromArgs() {
-	keyStringSet := make(map[string]string, 0)
-	for _, command := range os.Args {
-		if command[0] == '-' {
-			key := command[1:strings.Index(command, "=")]
-			value := command[strings.Index(command, "=")+1:]
-			keyStringSet[key] = value
+	loaded := make([]map[string]string, 0)
+	legacyKeys := make([]config_source.LegacyKey, 0)
+	for _, source := range sources {
+		values, err := source.Load(ctx)
+		if located, ok := err.(*config_source.Error); ok {
+			return cfg, nil, nil, config_source.Locate(source, located)
+		}
+		if err != nil {
+			return cfg, nil, nil, fmt.Errorf("cannot load config from %s: %v", source.Name(), err)
+		}
+		if err := config_crypto.DecryptValues(values, l.KeyFile); err != nil {
+			return cfg, nil, nil, fmt.Errorf("cannot decrypt config from %s: %v", source.Name(), err)
 		}
+		values, legacy := config_source.RenameKeys(source, config_source.RelaxKeys(values, relaxKeys), keyAliases)
+		loaded = append(loaded, values)
+		legacyKeys = append(legacyKeys, legacy...)
+	}
+	// merger 收集这一次加载的字段错误，同一个Loader可以同时加载
+	merger := &Loader{}
+	merger.initDefaultConfig(&cfg)
+	merger.applyDefaults(&cfg)
+	if len(merger.loadErrors) > 0 {
+		return cfg, nil, nil, fmt.Errorf("invalid default value: %v", merger.loadErrors)
+	}
+	errs := make(config_source.ErrorList, 0)
+	for i, values := range loaded {
+		if l.Strict {
+			errs = append(errs, config_source.UnknownKeys(sources[i], values, configKeys)...)
+		}
+		merger.loadErrors = nil
+		merger.loadConfigFromMap(values, &cfg)
+		for _, err := range merger.loadErrors {
+			errs = append(errs, config_source.Locate(sources[i], err))
+		}
+	}
+	report, disabled := l.conditions(&cfg, profiles)
+	if errs = errs.Skip(disabled); len(errs) > 0 {
+		return cfg, nil, nil, errs
 	}
-	for k, v := range keyStringSet {
-		fmt.Println(k, v)
+	// 所有值都转换成功后才按validate标签校验，错误定位到最后设置该值的来源
+	merger.loadErrors = nil
+	merger.validate(&cfg)
+	for _, err := range merger.loadErrors {
+		errs = append(errs, config_source.LocateLast(sources, loaded, err))
 	}
-	configLocal := l.mergeConfig(keyStringSet, "", "ApplicationConfig", applicationConfig, utils.NotBlank)
-	applicationConfig = configLocal.(ApplicationConfig)
+	if errs = errs.Skip(disabled); len(errs) > 0 {
+		return cfg, nil, nil, errs
+	}
+	return cfg, report, legacyKeys, nil
+}
+
+// LegacyKeys 最近一次成功加载时使用了其他名称或已废弃名称的配置项
+func (l *Loader) LegacyKeys() []config_source.LegacyKey {
+	configLock.RLock()
+	defer configLock.RUnlock()
+	return l.legacyKeys
 }
-func (l *Loader) register(object interface{}) map[string]interface{} {
-	keySet := make(map[string]interface{})
-	typeOfInterface := reflect.TypeOf(object)
-	valueOfInterface := reflect.ValueOf(object)
-	if typeOfInterface.Kind() == reflect.Struct {
-		for i := 0; i < typeOfInterface.NumField(); i++ {
-			field := typeOfInterface.Field(i)
-			key := field.Tag[6 : len(field.Tag)-1]
-			value := valueOfInterface.FieldByName(field.Name)
-			keySet[string(key)] = value.Interface()
-			fieldType := reflect.TypeOf(field)
-			if fieldType.Kind() == reflect.Struct {
-				prefix := string(key) + "."
-				subKeySet := l.register(value.Interface())
-				for k, v := range subKeySet {
-					keySet[prefix+k] = v
-				}
+
+// Watch 监听实现了Watcher的来源，变化时重新加载配置，直到ctx结束
+func (l *Loader) Watch(ctx context.Context) error {
+	return config_source.Watch(ctx, l.getSources(), func() {
+		if err := l.Load(ctx); err != nil {
+			fmt.Println("reload config failed:", err)
+		}
+	})
+}
+
+func (l *Loader) getSources() []config_source.Source {
+	if l.Sources != nil {
+		return l.Sources
+	}
+	sources := make([]config_source.Source, 0)
+	if l.External {
+		if utils.NotBlank(l.ConfigPath) {
+			sources = append(sources, &config_source.File{Path: l.ConfigPath, Profiles: l.Profiles})
+		}
+		if utils.NotBlank(l.MountDir) {
+			sources = append(sources, &config_source.Directory{Path: l.MountDir})
+		}
+		if l.Cloud {
+			remote := l.CloudOptions
+			if remote == nil {
+				remote = &config_source.Remote{}
+			}
+			if utils.IsBlank(remote.URL) {
+				remote.URL = l.CloudAddress
+			}
+			if remote.Profiles == nil {
+				remote.Profiles = l.Profiles
 			}
+			sources = append(sources, remote)
 		}
-	} else {
-		return nil
+		if utils.NotBlank(l.EnvPrefix) {
+			sources = append(sources, &config_source.Env{Prefix: l.EnvPrefix})
+		}
+		sources = append(sources, &config_source.Args{Args: os.Args[1:]})
 	}
-	return keySet
+	l.Sources = sources
+	return sources
+}
+
+func (l *Loader) getKeys() []string {
+	keys := make([]string, 0, len(configKeys))
+	for key := range configKeys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+//@DefaultConfigGenerate
+//@AutoExecuteGenerate
+
+func (l *Loader) loadConfigFromMap(configStringMap map[string]string, target *ApplicationConfig) {
+	l.mergeConfig(configStringMap, target)
 }
 func (l *Loader) getStringSet(keySet map[string]interface{}) map[string]string {
 	stringSet := make(map[string]string)
 	for k, v := range keySet {
-		if reflect.TypeOf(v).Kind() == reflect.String {
-			stringSet[k] = v.(string)
+		if str, ok := v.(string); ok {
+			stringSet[k] = str
 		}
 	}
 	return stringSet
@@ -206,274 +237,258 @@ func (l *Loader) getStringSet(keySet map[string]interface{}) map[string]string {
 
 var config map[string]interface{}
 var configStr map[string]string
+var configLock sync.RWMutex
+var conditionReport config_condition.Report
+var activeProfiles []string
 
 //@MergeConfigGenerate
 
 func GetString(key string) string {
+	configLock.RLock()
+	defer configLock.RUnlock()
 	return configStr[key]
 }
 func GetConfig(key string) interface{} {
+	configLock.RLock()
+	defer configLock.RUnlock()
 	return config[key]
 }
-`
-)
 
-type Generator struct {
-	ConfigDir     string
-	aliasMap      map[string]string
-	fileSeparator string
-	typeAlias     map[string]string
+// ConditionReport 最近一次成功加载时配置节的条件判断结果
+func ConditionReport() config_condition.Report {
+	configLock.RLock()
+	defer configLock.RUnlock()
+	return conditionReport
 }
 
-func (g *Generator) checkConfig() {
-	if utils.IsBlank(g.ConfigDir) {
-		panic("cannot scan empty path")
-	}
+// ActiveProfiles 最近一次成功加载时激活的profile
+func ActiveProfiles() []string {
+	configLock.RLock()
+	defer configLock.RUnlock()
+	return activeProfiles
 }
 
-func (g *Generator) initVariable() {
-	if runtime.GOOS == "windows" {
-		g.fileSeparator = "\\"
-	} else {
-		g.fileSeparator = "/"
-	}
+// ConditionValues cfg中所有配置项的值，用于判断组件的@ConditionalOnProperty
+func ConditionValues(cfg ApplicationConfig) map[string]interface{} {
+	return (&Loader{}).register(cfg)
 }
+`
 
-func (g *Generator) Begin() {
-	g.initVariable()
-	scanFiles := g.getScanFiles(g.ConfigDir)
-	scanTypes, defaultConfig, autoExecute := g.getScanTypes(scanFiles)
-	g.generateFunc(scanTypes)
-	method := g.generateFunc(scanTypes)
-	configs := g.generateConfigs(defaultConfig)
-	executes := g.generateExecute(autoExecute)
-	g.writeResultToFile(method, configs, executes, g.ConfigDir)
-}
+	// ConfigTestingTemplate 生成的config_testing.go，只有它导入config/testing；
+	// 使用 -tags noconfigtesting 构建时不包括测试用的函数
+	ConfigTestingTemplate = `// Code generated by go-injector-yaml. DO NOT EDIT.
 
-func (g *Generator) getScanFiles(configPath string) []string {
-	fileInfoList, err := filepath.Glob(filepath.Join(configPath, "*.go"))
-	if err != nil {
-		return nil
-	}
-	files := make([]string, 0)
-	for _, file := range fileInfoList {
-		filename := file[strings.LastIndex(file, g.fileSeparator)+1:]
-		if filename == "config_loader.go" {
-			continue
-		}
-		files = append(files, file)
-	}
-	return files
-}
+//go:build !noconfigtesting
+// +build !noconfigtesting
 
-func (g *Generator) getFileContentAsStringLines(filePath string) ([]string, error) {
-	var result []string
-	by, err := ioutil.ReadFile(filePath)
+package config
+
+import (
+	"context"
+	"github.com/orange0224/go-injector-yaml/config/source"
+	"github.com/orange0224/go-injector-yaml/config/testing"
+)
+
+// NewTestConfig 只从sources加载配置，不读取文件、环境变量和命令行参数，也不替换当前配置；
+// profiles为激活的profile，不读取CONFIG_PROFILES；出现未知key或加载失败时调用t.Fatalf。
+// 每次调用的结果互不影响，可以在t.Parallel()的测试中使用
+func NewTestConfig(t config_testing.T, profiles []string, sources ...config_source.Source) ApplicationConfig {
+	t.Helper()
+	cfg, _, _, err := (&Loader{Sources: sources, Strict: true}).build(context.Background(), profiles)
 	if err != nil {
-		return nil, err
-	}
-	str := string(by)
-	for _, lineStr := range strings.Split(str, "\n") {
-		lineStr = strings.TrimSpace(lineStr)
-		if lineStr == "" {
-			continue
+		t.Fatalf("cannot load test config: %v", err)
+	}
+	return cfg
+}
+
+// testOverride SetTestConfig设置的配置
+type testOverride struct {
+	cfg     ApplicationConfig
+	values  map[string]interface{}
+	strings map[string]string
+}
+
+// testOverrides 按调用顺序保存还没有恢复的SetTestConfig，最后一个为当前配置；testBaseConfig 第一次调用之前的配置
+var testOverrides []*testOverride
+var testBaseConfig testOverride
+
+// SetTestConfig 测试期间把cfg作为当前配置，GetApplicationConfig、GetString等返回cfg，t.Cleanup时移除cfg，
+// 恢复之前的配置；子测试中可以再次调用，并行的测试中同时调用时当前配置为最后一次调用设置的cfg
+func SetTestConfig(t config_testing.T, cfg ApplicationConfig) {
+	t.Helper()
+	values := (&Loader{}).register(cfg)
+	override := &testOverride{cfg: cfg, values: values, strings: (&Loader{}).getStringSet(values)}
+	configLock.Lock()
+	if len(testOverrides) == 0 {
+		testBaseConfig = testOverride{cfg: applicationConfig, values: config, strings: configStr}
+	}
+	testOverrides = append(testOverrides, override)
+	applicationConfig, config, configStr = override.cfg, override.values, override.strings
+	configLock.Unlock()
+	t.Cleanup(func() {
+		configLock.Lock()
+		defer configLock.Unlock()
+		for i := range testOverrides {
+			if testOverrides[i] == override {
+				testOverrides = append(testOverrides[:i], testOverrides[i+1:]...)
+				break
+			}
 		}
-		result = append(result, lineStr)
-	}
-	return result, nil
+		current := &testBaseConfig
+		if len(testOverrides) > 0 {
+			current = testOverrides[len(testOverrides)-1]
+		}
+		applicationConfig, config, configStr = current.cfg, current.values, current.strings
+	})
 }
+`
+)
 
-func (g *Generator) getStructType(lines []string) []string {
-	types := make([]string, 0)
-	for _, line := range lines {
-		typeIndex := strings.Index(string(line), "type ")
-		structIndex := strings.Index(string(line), "struct ")
-		if typeIndex != -1 && structIndex != -1 && typeIndex < structIndex {
-			types = append(types, strings.TrimSpace(line[typeIndex+len("type "):structIndex]))
-		}
-	}
-	return types
+type Generator struct {
+	ConfigDir string
+	// TagKey 读取配置项名称的标签，为空时使用yaml；必须与TypeScanner.TagKey相同
+	TagKey string
+	// Packages、Include、Exclude、BuildTags 与TypeScanner中的含义相同，相对于ConfigDir的上一级目录（项目根目录）；
+	// ConfigDir本身总会被扫描
+	Packages  []string
+	Include   []string
+	Exclude   []string
+	BuildTags []string
+	// ContainerDir 生成依赖注入代码container.go的目录，为空时不生成；
+	// 该目录是单独的包，组件所在的包需要在Packages中
+	ContainerDir string
+	components   []*config_model.Component
+	methods      []*config_model.LifecycleMethod
+	executes     []*config_model.Execute
+	qualifiers   map[string]string
+	models       map[string]*config_model.Type
+	graph        *config_model.Graph
+	typeAlias    map[string]string
+	// wiring 组件的依赖关系，loaderExecutes、containerExecutes 分别由Loader和Container调用的@AutoExecute
+	wiring            *config_model.Wiring
+	loaderExecutes    []*config_model.Execute
+	containerExecutes []*config_model.Execute
 }
 
-func (g *Generator) getScanTypes(files []string) (types []string, defaultConfig map[string]string, autoExecute []string) {
-	types = make([]string, 0)
-	defaultConfig = make(map[string]string, 0)
-	autoExecute = make([]string, 0)
-	for _, file := range files {
-		content, _ := g.getFileContentAsStringLines(file)
-		ty := g.getStructType(content)
-		config := g.getDefaultConfig(content)
-		execute := g.getAutoExecute(content)
-		indexes := findConfigurationsPosition(content)
-		_, aliasMap := getConfiguration(content, indexes)
-
-		types = append(types, ty...)
-		autoExecute = append(autoExecute, execute...)
-		for k, v := range config {
-			defaultConfig[k] = v
-		}
-		if g.aliasMap == nil {
-			g.aliasMap = make(map[string]string)
-		}
-		for k, v := range aliasMap {
-			g.aliasMap[k] = v
-		}
+func (g *Generator) checkConfig() {
+	if utils.IsBlank(g.ConfigDir) {
+		panic("cannot scan empty path")
 	}
-	return types, defaultConfig, autoExecute
 }
 
-func (g *Generator) getDefaultConfig(lines []string) map[string]string {
-	indexes := make([]int, 0)
-	configs := make(map[string]string)
-	for i := range lines {
-		line := lines[i]
-		if strings.Index(line, "//@DefaultConfig") != -1 {
-			indexes = append(indexes, i)
-		}
+func (g *Generator) Begin() {
+	g.checkConfig()
+	if err := g.check(); err != nil {
+		panic(err)
 	}
-	for _, i := range indexes {
-		line := lines[i+1]
-		split := strings.Split(line, " ")
-		if len(split) >= 3 {
-			configs[split[2]] = split[1]
-		}
+	g.scanModels()
+	imports, method, err := g.generateLoader()
+	if err != nil {
+		panic(err)
 	}
-	return configs
-}
-
-func (g *Generator) getAutoExecute(lines []string) []string {
-	indexes := make([]int, 0)
-	methods := make([]string, 0)
-	for i := range lines {
-		line := lines[i]
-		if strings.Index(line, "//@AutoExecute") != -1 {
-			indexes = append(indexes, i)
-		}
+	if err := g.wire(); err != nil {
+		panic(err)
 	}
-	for _, i := range indexes {
-		line := lines[i+1]
-		split := strings.Split(line, " ")
-		if len(split) >= 2 {
-			methods = append(methods, split[1])
+	configs := g.generateConfigs()
+	executes := g.generateExecute() + g.generateConditions()
+	g.writeResultToFile(imports, method, configs, executes, g.ConfigDir)
+	if utils.NotBlank(g.ContainerDir) {
+		if err := g.writeContainer(); err != nil {
+			panic(err)
 		}
 	}
-	return methods
-}
-
-func (g *Generator) getTemplate1(name string) string {
-	return strings.ReplaceAll(Template1, "{$type}", name)
-}
-
-func (g *Generator) getTemplate2(name string) string {
-	return strings.ReplaceAll(Template2, "{$type}", name)
-}
-
-func (g *Generator) generateFunc(types []string) string {
-	template1 := ""
-	template2 := ""
-	for _, ty := range types {
-		template1 += g.getTemplate1(ty)
-		template2 += g.getTemplate2(ty)
-
-	}
-	return Header + template1 + Content1 + template2 + Footer
 }
 
-func (g *Generator) generateConfigs(config map[string]string) string {
-	header := `
-func (l *Loader) initDefaultConfig() {
-`
-	methods := ""
-	for typ, method := range config {
-		methods += "applicationConfig." + strings.ToUpper(g.aliasMap[typ][0:1]) + g.aliasMap[typ][1:] + "=" + method + "\n"
+// check 生成代码之前执行与TypeScanner.Check相同的检查：重名的类型、冲突的@Alias、循环引用和@Component的依赖
+func (g *Generator) check() error {
+	configDir, err := filepath.Abs(g.ConfigDir)
+	if err != nil {
+		return err
 	}
-	footer := `}
-`
-	return header + methods + footer
-}
-func IsCodeAnnotation(str string) bool {
-	if strings.TrimSpace(str)[:2] == "//" {
-		return true
+	scanner := &config_type.TypeScanner{
+		ConfigDir: filepath.Dir(configDir),
+		TagKey:    g.TagKey,
+		Packages:  append([]string{"./" + filepath.Base(configDir)}, g.Packages...),
+		Include:   g.Include,
+		Exclude:   g.Exclude,
+		BuildTags: g.BuildTags,
 	}
-	return false
-}
-func GetTypeName(line string) string {
-	typeIndex := strings.Index(line, "type ")
-	structIndex := strings.Index(line, "struct ")
-	if typeIndex != -1 && structIndex != -1 && typeIndex < structIndex {
-		return strings.TrimSpace(line[typeIndex+len("type ") : structIndex])
-	}
-	return ""
+	return scanner.Check()
 }
 
-func GetTypeAlias(line, typeName string) string {
-	index := strings.Index(line, "@Alias=")
-	if index == -1 {
-		return strings.ToLower(typeName[0:1]) + typeName[1:]
+// scanModels 解析配置目录和Packages中的结构体，包括TypeScanner生成的ApplicationConfig
+func (g *Generator) scanModels() {
+	configDir, err := filepath.Abs(g.ConfigDir)
+	if err != nil {
+		panic(err)
+	}
+	files, err := config_model.ScanFiles(config_model.ScanOptions{
+		Root:      filepath.Dir(configDir),
+		Patterns:  append([]string{configDir}, g.Packages...),
+		Include:   g.Include,
+		Exclude:   g.Exclude,
+		BuildTags: g.BuildTags,
+	})
+	if err != nil {
+		panic(fmt.Errorf("cannot scan config types: %v", err))
 	}
-	split := strings.Split(line[index+len("@Alias="):], " ")
-	return split[0]
-}
-
-func getConfiguration(fileContent []string, indexes []int) (types [][]string, aliasMap map[string]string) {
-	types = make([][]string, 0)
-	aliasMap = make(map[string]string)
-	for _, index := range indexes {
-		count := 0
-		type_ := make([]string, 0)
-		pos := index + 1
-		typeAnnotation := fileContent[index]
-		typeInfo := fileContent[pos]
-		name := GetTypeName(typeInfo)
-		alias := GetTypeAlias(typeAnnotation, name)
-		aliasMap[name] = alias
-		for ; ; pos++ {
-			leftCount := strings.Count(fileContent[pos], "{")
-			count += leftCount
-
-			rightCount := strings.Count(fileContent[pos], "}")
-			count -= rightCount
-
-			if count == 0 {
-				pos++
-				break
-			}
+	g.models = make(map[string]*config_model.Type)
+	g.components = nil
+	g.methods = nil
+	g.executes = nil
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if filepath.Base(file) == "config_loader.go" {
+			continue
 		}
-		type_ = append(type_, fileContent[index+1:pos]...)
-		types = append(types, type_)
-	}
-	return
-}
-
-//获取Configuration注解出现的位置
-func findConfigurationsPosition(fileContent []string) []int {
-	indexes := make([]int, 0)
-	for i, line := range fileContent {
-		if strings.Index(line, "//@Configuration") != -1 && !IsCodeAnnotation(fileContent[i+1]) {
-			indexes = append(indexes, i)
+		parsed, err := config_model.ParseFile(fset, file, g.TagKey)
+		if err != nil {
+			panic(fmt.Errorf("cannot parse %s: %v", file, err))
 		}
+		for _, typ := range parsed.Types {
+			if _, ok := g.models[typ.Name]; !ok {
+				g.models[typ.Name] = typ
+			}
+		}
+		g.components = append(g.components, parsed.Components...)
+		g.methods = append(g.methods, parsed.Methods...)
+		g.executes = append(g.executes, parsed.Executes...)
 	}
-	return indexes
+	g.graph = config_model.NewGraph(g.models)
 }
 
-func (g *Generator) generateExecute(executes []string) string {
+// generateConfigs 对ApplicationConfig中有@DefaultConfig函数的字段调用该函数设置默认值
+func (g *Generator) generateConfigs() string {
 	header := `
-func (l *Loader) autoExecute() {
+func (l *Loader) initDefaultConfig(target *ApplicationConfig) {
 `
 	methods := ""
-	for _, method := range executes {
-		methods += method + "\n"
+	for _, field := range g.models["ApplicationConfig"].Fields {
+		typ, _ := g.nestedType(field.Expr)
+		if typ == nil || typ.DefaultFunc == "" {
+			continue
+		}
+		function := typ.DefaultFunc
+		if qualifier, ok := g.qualifiers[typ.ImportPath]; ok {
+			function = qualifier + "." + function
+		}
+		methods += "target." + field.Name + "=" + function + "()\n"
 	}
 	footer := `}
 `
 	return header + methods + footer
 }
-
-func (g *Generator) writeResultToFile(method, config, execute, configPath string) {
+func (g *Generator) writeResultToFile(imports, method, config, execute, configPath string) {
 	templateContent := strings.Split(ConfigLoaderTemplate, "\n")
-	methodIndex, configIndex, executeIndex := -1, -1, -1
+	importIndex, methodIndex, configIndex, executeIndex := -1, -1, -1, -1
 	for i := range templateContent {
 		line := templateContent[i]
+		if strings.Index(line, "//@ImportGenerate") != -1 {
+			if importIndex != -1 {
+				panic("error occurred when scan @ImportGenerate:Multiple instances detected")
+			}
+			importIndex = i
+		}
 		mIndex := strings.Index(line, "//@MergeConfigGenerate")
 		cIndex := strings.Index(line, "//@DefaultConfigGenerate")
 		eIndex := strings.Index(line, "//@AutoExecuteGenerate")
@@ -496,7 +511,7 @@ func (g *Generator) writeResultToFile(method, config, execute, configPath string
 			executeIndex = i
 		}
 	}
-	if methodIndex == -1 || configIndex == -1 || executeIndex == -1 {
+	if importIndex == -1 || methodIndex == -1 || configIndex == -1 || executeIndex == -1 {
 		panic(fmt.Errorf("cannot find enough instance:method:%d,config:%d,execute:%d", methodIndex, configIndex, executeIndex))
 	}
 
@@ -507,6 +522,9 @@ func (g *Generator) writeResultToFile(method, config, execute, configPath string
 	writeFile := make([]string, 0)
 	for i, line := range templateContent {
 		writeFile = append(writeFile, line)
+		if i == importIndex && imports != "" {
+			writeFile = append(writeFile, imports)
+		}
 		if i == methodIndex {
 			writeFile = append(writeFile, method)
 		}
@@ -517,6 +535,10 @@ func (g *Generator) writeResultToFile(method, config, execute, configPath string
 			writeFile = append(writeFile, execute)
 		}
 	}
+	if err := ioutil.WriteFile(filepath.Join(configPath, "config_testing.go"), []byte(ConfigTestingTemplate), 0644); err != nil {
+		fmt.Println(err)
+		return
+	}
 	file, err := os.Create(configPath + "/config_loader.go")
 	if err != nil {
 		fmt.Println(err)
@@ -529,3 +551,34 @@ func (g *Generator) writeResultToFile(method, config, execute, configPath string
 		file.Write([]byte("\n"))
 	}
 }
+
+// IsCodeAnnotation 判断一行源码是否为注释
+//
+// Deprecated: 配置类型改为通过go/ast扫描，注解使用 config_model.HasAnnotation 判断
+func IsCodeAnnotation(str string) bool {
+	return strings.HasPrefix(strings.TrimSpace(str), "//")
+}
+
+// GetTypeName 返回 type Xxx struct 一行中的类型名，不是结构体定义时返回空字符串
+//
+// Deprecated: 配置类型改为通过go/ast扫描，类型名使用 config_model.Type 的Name
+func GetTypeName(line string) string {
+	typeIndex := strings.Index(line, "type ")
+	structIndex := strings.Index(line, "struct")
+	if typeIndex != -1 && structIndex != -1 && typeIndex < structIndex {
+		return strings.TrimSpace(line[typeIndex+len("type ") : structIndex])
+	}
+	return ""
+}
+
+// GetTypeAlias 返回注释行中的 @Alias=，没有时返回首字母小写的类型名
+//
+// Deprecated: 配置类型改为通过go/ast扫描，使用 config_model.Annotation 和 config_model.LowerCamel
+func GetTypeAlias(line, typeName string) string {
+	if index := strings.Index(line, "@Alias="); index != -1 {
+		if fields := strings.Fields(line[index+len("@Alias="):]); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	return config_model.LowerCamel(typeName)
+}
@@ -0,0 +1,83 @@
+package config_generator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestBeginChecks Begin在写入config_loader.go和container.go之前执行与TypeScanner.Check相同的检查
+func TestBeginChecks(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		generator Generator
+		message   string
+	}{
+		{
+			name: "alias collision",
+			files: map[string]string{
+				"config/a.go": "package config\n\n//@Configuration @Alias=server\ntype A struct {\n\tHost string\n}\n",
+				"config/b.go": "package config\n\n//@Configuration @Alias=server\ntype B struct {\n\tPort int\n}\n",
+			},
+			message: "config/b.go:4:6: @Alias=server of B collides with @Alias=server of A",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"config/a.go": "package config\n\n//@Configuration\ntype A struct {\n\tB B\n}\n\ntype B struct {\n\tA *A\n}\n",
+			},
+			message: "config/a.go:9:2: recursive config type: A.B -> B.A -> A",
+		},
+		{
+			name: "missing provider",
+			files: map[string]string{
+				"config/a.go":  "package config\n\n//@Configuration\ntype A struct {\n\tHost string\n}\n",
+				"service/s.go": "package service\n\ntype Store interface{}\n\n//@Component\ntype Service struct {\n\t//@Inject\n\tStore Store\n}\n",
+			},
+			generator: Generator{Packages: []string{"./service"}, ContainerDir: "app"},
+			message:   "service/s.go:8:2: Service.Store: no component provides Store",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "generator-check")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			for name, content := range test.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			g := test.generator
+			g.ConfigDir = filepath.Join(dir, "config")
+			if g.ContainerDir != "" {
+				g.ContainerDir = filepath.Join(dir, g.ContainerDir)
+			}
+			var message string
+			func() {
+				defer func() {
+					message = fmt.Sprint(recover())
+				}()
+				g.Begin()
+			}()
+			if !strings.Contains(message, test.message) {
+				t.Errorf("panic %q, want %q", message, test.message)
+			}
+			for _, generated := range []string{"config/config_loader.go", "config/config_testing.go", "app/container.go"} {
+				if _, err := os.Stat(filepath.Join(dir, generated)); err == nil {
+					t.Errorf("%s was generated", generated)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,733 @@
+package config_generator
+
+import (
+	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/model"
+	"github.com/orange0224/go-injector-yaml/config/utils"
+	"go/format"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// containerNames 生成的container.go中已经声明或导入的名称
+var containerNames = map[string]bool{
+	"Container": true, "NewContainer": true, "c": true, "cfg": true, "ctx": true, "err": true, "lifecycle": true,
+	"instance": true, "scope": true, "context": true, "config_condition": true, "fmt": true, "time": true, "config_lifecycle": true,
+	"Options": true, "NewContainerWith": true, "options": true, "name": true, "ok": true, "replaced": true, "profiles": true,
+}
+
+// containerPackage 生成的依赖注入代码所在的包名
+func (g *Generator) containerPackage() string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' {
+			return '_'
+		}
+		return r
+	}, filepath.Base(g.ContainerDir))
+}
+
+// generateContainer 生成Container和NewContainer：每个组件有一个buildXxx方法，singleton组件按依赖顺序
+// 在NewContainer中创建，prototype组件由NewXxx(ctx)每次创建，request组件由Xxx(ctx)在ctx的Scope中获取；
+// 生成的代码在单独的包中，组件可以导入config包而不会循环导入
+func (g *Generator) generateContainer() ([]byte, error) {
+	configPath := config_model.ImportPath(g.ConfigDir)
+	wiring, ordered := g.wiring, g.wiring.Order
+	singletons := make([]*config_model.Component, 0, len(ordered))
+	for _, component := range ordered {
+		if component.Scope == config_model.ScopeSingleton {
+			singletons = append(singletons, component)
+		}
+	}
+	types := []*config_model.Type{{Name: "ApplicationConfig", Package: "config", ImportPath: configPath}}
+	for _, component := range ordered {
+		types = append(types, &config_model.Type{Name: component.TypeName, Package: component.Package, ImportPath: component.ImportPath})
+		for _, injection := range component.Injections() {
+			if resolution := wiring.Resolve(injection); resolution != nil && resolution.Kind != config_model.ResolveOne {
+				if _, typ := injection.TypeRef(); typ.ImportPath != "" {
+					types = append(types, typ)
+				}
+			}
+		}
+	}
+	for _, execute := range g.containerExecutes {
+		types = append(types, &config_model.Type{Name: execute.Function, Package: execute.Package, ImportPath: execute.ImportPath})
+	}
+	qualifiers := config_model.QualifiersReserving(config_model.ImportPath(g.ContainerDir), types, containerNames)
+	conditional := hasConditions(ordered)
+	w := &codeWriter{}
+	w.line("// Code generated by go-injector-yaml. DO NOT EDIT.")
+	w.line("")
+	w.line("package %s", g.containerPackage())
+	w.line("")
+	w.open("import (")
+	w.line(strconv.Quote("context"))
+	if conditional {
+		w.line(strconv.Quote("github.com/orange0224/go-injector-yaml/config/condition"))
+	}
+	w.line(strconv.Quote("github.com/orange0224/go-injector-yaml/config/lifecycle"))
+	if usesTimeout(ordered) {
+		w.line(strconv.Quote("time"))
+	}
+	w.line(strconv.Quote("fmt"))
+	for _, line := range config_model.ImportLines(qualifiers, types) {
+		w.line(line)
+	}
+	w.close(")")
+	w.line("")
+	w.line("// Container 启动时创建的所有singleton组件，Config为创建时使用的配置")
+	w.open("type Container struct {")
+	w.line("Config %s", types[0].QualifiedName(qualifiers))
+	for _, component := range singletons {
+		w.line("%s %s", component.Name, componentType(component, qualifiers))
+	}
+	w.line("")
+	w.line("replaced map[string]interface{}")
+	if conditional {
+		w.line("conditions *config_condition.Evaluator")
+	}
+	w.close("}")
+	w.line("")
+	writeOptions(w, ordered, qualifiers)
+	w.line("// NewContainer 使用加载后的配置按依赖顺序判断组件的条件、创建启用的singleton组件，构造函数返回错误时停止；")
+	w.line("// @AutoExecute在Lifecycle启动所有组件之后调用")
+	w.open("func NewContainer(cfg %s) (*Container, error) {", types[0].QualifiedName(qualifiers))
+	w.line("return NewContainerWith(cfg, Options{})")
+	w.close("}")
+	w.line("")
+	w.line("// NewContainerWith 与NewContainer相同，options可以指定判断条件使用的profile、替换组件")
+	w.open("func NewContainerWith(cfg %s, options Options) (*Container, error) {", types[0].QualifiedName(qualifiers))
+	w.open("if err := options.check(); err != nil {")
+	w.line("return nil, err")
+	w.close("}")
+	w.line("c := &Container{Config: cfg, replaced: options.Replace}")
+	if conditional {
+		config := qualifiers[configPath]
+		w.line("profiles := options.Profiles")
+		w.open("if profiles == nil {")
+		w.line("profiles = %s.ActiveProfiles()", config)
+		w.close("}")
+		w.line("c.conditions = config_condition.NewEvaluator(%s.ConditionValues(cfg), profiles)", config)
+		w.open("for name := range options.Replace {")
+		w.line("c.conditions.Replace(name)")
+		w.close("}")
+	}
+	if len(singletons) > 0 {
+		w.line("var err error")
+	}
+	for _, component := range ordered {
+		if conditional {
+			writeEnable(w, component, wiring)
+		}
+		if component.Scope != config_model.ScopeSingleton {
+			continue
+		}
+		if len(component.Conditions) > 0 {
+			w.open("if c.conditions.Enabled(%q) {", component.Name)
+		}
+		w.open("if replaced, ok := c.replaced[%q]; ok {", component.Name)
+		w.line("c.%s = replaced.(%s)", component.Name, componentType(component, qualifiers))
+		w.indent--
+		w.open("} else if c.%s, err = c.build%s(context.Background()); err != nil {", component.Name, component.Name)
+		w.line("return nil, fmt.Errorf(\"cannot create component %s: %%v\", err)", component.Name)
+		w.close("}")
+		if len(component.Conditions) > 0 {
+			w.close("}")
+		}
+	}
+	w.line("return c, nil")
+	w.close("}")
+	w.line("")
+	for _, component := range ordered {
+		switch component.Scope {
+		case config_model.ScopePrototype:
+			writePrototype(w, component, qualifiers)
+		case config_model.ScopeRequest:
+			writeRequest(w, component, qualifiers)
+		}
+		g.writeBuild(w, component, wiring, qualifiers)
+		writeHook(w, component, qualifiers)
+	}
+	if len(g.containerExecutes) > 0 {
+		w.line("// autoExecute 所有组件启动后按顺序调用@AutoExecute")
+		w.open("func (c *Container) autoExecute(ctx context.Context) error {")
+		if executesUseConfig(g.containerExecutes, wiring) {
+			w.line("cfg := c.Config")
+		}
+		g.writeExecutes(w, g.containerExecutes, wiring, qualifiers, "ctx")
+		w.line("return nil")
+		w.close("}")
+		w.line("")
+	}
+	writeLifecycle(w, singletons, conditional, len(g.containerExecutes) > 0)
+	source, err := format.Source([]byte(w.builder.String()))
+	if err != nil {
+		return nil, fmt.Errorf("cannot format generated container: %v", err)
+	}
+	return source, nil
+}
+
+// wire 检查组件、@PostConstruct、@PreDestroy、@AutoExecute和条件注解，一次返回所有问题；
+// 没有设置ContainerDir时忽略组件，config包之外的@AutoExecute会报错
+func (g *Generator) wire() error {
+	configPath := config_model.ImportPath(g.ConfigDir)
+	checkKey := func(key string) error {
+		_, err := g.configExpr(key)
+		return err
+	}
+	diagnostics := make(config_model.Diagnostics, 0)
+	var containerWiring *config_model.Wiring
+	if utils.NotBlank(g.ContainerDir) {
+		diagnostics = append(diagnostics, config_model.BindLifecycle(g.components, g.methods)...)
+		wiring, wireDiagnostics := config_model.Wire(g.components, g.sections(configPath), checkKey)
+		diagnostics = append(append(diagnostics, wireDiagnostics...), wiring.CheckConditions(g.hasKey)...)
+		containerWiring = wiring
+	}
+	for _, field := range g.conditionalSections() {
+		typ, _ := g.nestedType(field.Expr)
+		diagnostics = append(diagnostics, config_model.CheckConditions("section "+field.Key, typ.Conditions, g.hasKey, nil)...)
+	}
+	configWiring, _ := config_model.Wire(nil, g.sections(configPath), checkKey)
+	loader, container, executeDiagnostics := config_model.PlanExecutes(g.executes, configPath, configWiring, containerWiring, checkKey)
+	g.wiring, g.loaderExecutes, g.containerExecutes = containerWiring, loader, container
+	if diagnostics = append(diagnostics, executeDiagnostics...); len(diagnostics) > 0 {
+		return diagnostics
+	}
+	return nil
+}
+
+// generateExecute 生成Loader.autoExecute，Begin加载配置后按顺序调用config包中的@AutoExecute，
+// 它们收到的是当前配置的副本
+func (g *Generator) generateExecute() string {
+	w := &codeWriter{}
+	w.line("")
+	w.open("func (l *Loader) autoExecute() error {")
+	if len(g.loaderExecutes) > 0 {
+		w.line("cfg := GetApplicationConfig()")
+	}
+	configWiring, _ := config_model.Wire(nil, g.sections(config_model.ImportPath(g.ConfigDir)), func(string) error { return nil })
+	g.writeExecutes(w, g.loaderExecutes, configWiring, nil, "context.Background()")
+	w.line("return nil")
+	w.close("}")
+	return w.builder.String()
+}
+
+// writeExecutes 按顺序调用@AutoExecute，ctx为注入context.Context的表达式，返回错误时以 "@AutoExecute 名称: 错误" 返回
+func (g *Generator) writeExecutes(w *codeWriter, executes []*config_model.Execute, wiring *config_model.Wiring, qualifiers map[string]string, ctx string) {
+	for _, execute := range executes {
+		args := make([]string, 0, len(execute.Params))
+		for _, param := range execute.Params {
+			args = append(args, g.injectionExpr(param, wiring, ctx))
+		}
+		function := execute.Function
+		if execute.Receiver != nil {
+			function = providerExpr(wiring.ReceiverProvider(execute.Receiver)) + "." + function
+		} else if qualifier, ok := qualifiers[execute.ImportPath]; ok {
+			function = qualifier + "." + function
+		}
+		call := function + "(" + strings.Join(args, ", ") + ")"
+		guards := make([]string, 0)
+		if wiring != nil {
+			for _, name := range conditionalExecute(execute, wiring) {
+				guards = append(guards, fmt.Sprintf("c.conditions.Enabled(%q)", name))
+			}
+		}
+		if len(guards) > 0 {
+			w.open("if %s {", strings.Join(guards, " && "))
+		}
+		if !execute.ReturnsError {
+			w.line(call)
+		} else {
+			w.open("if err := %s; err != nil {", call)
+			w.line("return fmt.Errorf(\"@AutoExecute %s: %%v\", err)", execute.Name)
+			w.close("}")
+		}
+		if len(guards) > 0 {
+			w.close("}")
+		}
+	}
+}
+
+// writeContainer 生成 ContainerDir/container.go
+func (g *Generator) writeContainer() error {
+	source, err := g.generateContainer()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(g.ContainerDir, "container.go"), source, 0644)
+}
+
+// sections 返回ApplicationConfig本身和它的每个配置节的提供者，key为带导入路径的类型
+func (g *Generator) sections(configPath string) map[string]*config_model.Provider {
+	sections := map[string]*config_model.Provider{configPath + ".ApplicationConfig": {}}
+	for _, field := range g.models["ApplicationConfig"].Fields {
+		typ, pointer := g.nestedType(field.Expr)
+		if typ == nil {
+			continue
+		}
+		key := typ.ImportPath + "." + typ.Name
+		if pointer {
+			key = "*" + key
+		}
+		sections[key] = &config_model.Provider{Section: field.Name}
+	}
+	return sections
+}
+
+// injectionExpr 注入点的值：@Value对应的配置字段，提供该类型的配置节、singleton组件，或者ctx；
+// Wire和PlanExecutes已经检查过注入点，其他作用域的组件由writeBuild先创建
+func (g *Generator) injectionExpr(injection *config_model.Injection, wiring *config_model.Wiring, ctx string) string {
+	if injection.Value != "" {
+		expr, _ := g.configExpr(injection.Value)
+		return expr
+	}
+	if injection.TypeKey == config_model.ContextKey && wiring.Provider(injection) == nil {
+		return ctx
+	}
+	return providerExpr(wiring.Provider(injection))
+}
+
+func providerExpr(provider *config_model.Provider) string {
+	switch {
+	case provider.Component != nil:
+		return "c." + provider.Component.Name
+	case provider.Section != "":
+		return "cfg." + provider.Section
+	}
+	return "cfg"
+}
+
+// configExpr 把 server.db.maxConn 形式的配置key转换为 cfg.Server.Db.MaxConn，inline字段不出现在key中
+func (g *Generator) configExpr(key string) (string, error) {
+	expr := "cfg"
+	typ := g.models["ApplicationConfig"]
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		if typ == nil {
+			return "", fmt.Errorf("%s is not a struct", strings.Join(parts[:i], "."))
+		}
+		path, field := g.findKey(typ, part)
+		if field == nil {
+			return "", fmt.Errorf("unknown config key %s", strings.Join(parts[:i+1], "."))
+		}
+		expr += path
+		nested, pointer := g.nestedType(field.Expr)
+		if pointer && i < len(parts)-1 {
+			return "", fmt.Errorf("%s is a pointer and may be nil", strings.Join(parts[:i+1], "."))
+		}
+		typ = nested
+	}
+	return expr, nil
+}
+
+// hasKey 配置key是否存在，与configExpr不同，key可以经过指针字段
+func (g *Generator) hasKey(key string) bool {
+	typ := g.models["ApplicationConfig"]
+	for _, part := range strings.Split(key, ".") {
+		if typ == nil {
+			return false
+		}
+		_, field := g.findKey(typ, part)
+		if field == nil {
+			return false
+		}
+		typ, _ = g.nestedType(field.Expr)
+	}
+	return true
+}
+
+// findKey 在typ中查找key对应的字段，包括inline字段中的字段，返回 .Common.Region 形式的访问路径
+func (g *Generator) findKey(typ *config_model.Type, key string) (string, *config_model.Field) {
+	for _, field := range typ.Fields {
+		if field.Inline {
+			if nested, pointer := g.nestedType(field.Expr); nested != nil && !pointer {
+				if path, found := g.findKey(nested, key); found != nil {
+					return "." + field.Name + path, found
+				}
+			}
+			continue
+		}
+		if field.Key == key {
+			return "." + field.Name, field
+		}
+	}
+	return "", nil
+}
+
+func componentType(component *config_model.Component, qualifiers map[string]string) string {
+	typ := &config_model.Type{Name: component.TypeName, ImportPath: component.ImportPath}
+	if component.Pointer {
+		return "*" + typ.QualifiedName(qualifiers)
+	}
+	return typ.QualifiedName(qualifiers)
+}
+
+// usesConfig 组件是否注入了配置值或配置节
+func usesConfig(component *config_model.Component, wiring *config_model.Wiring) bool {
+	for _, injection := range component.Injections() {
+		if injection.Value != "" {
+			return true
+		}
+		if provider := wiring.Provider(injection); provider != nil && provider.Component == nil {
+			return true
+		}
+		if resolution := wiring.Resolve(injection); resolution != nil && resolution.Kind == config_model.ResolveConfig {
+			return true
+		}
+	}
+	return false
+}
+
+// executesUseConfig @AutoExecute的参数或接收者中是否有配置值或配置节
+func executesUseConfig(executes []*config_model.Execute, wiring *config_model.Wiring) bool {
+	for _, execute := range executes {
+		if execute.Receiver != nil && wiring.ReceiverProvider(execute.Receiver).Component == nil {
+			return true
+		}
+		for _, param := range execute.Params {
+			if param.Value != "" {
+				return true
+			}
+			if provider := wiring.Provider(param); provider != nil && provider.Component == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// writeBuild 生成创建组件的buildXxx方法：先创建依赖的prototype和request组件、选择或收集接口的实现，再调用构造函数，
+// 或者创建结构体后逐个设置注入的字段；ctx为request组件所在Scope的ctx，singleton组件收到的是context.Background()
+func (g *Generator) writeBuild(w *codeWriter, component *config_model.Component, wiring *config_model.Wiring, qualifiers map[string]string) {
+	w.open("func (c *Container) build%s(ctx context.Context) (instance %s, err error) {", component.Name, componentType(component, qualifiers))
+	if usesConfig(component, wiring) {
+		w.line("cfg := c.Config")
+	}
+	args := make([]string, 0)
+	for i, injection := range component.Injections() {
+		dependency := "dependency" + strconv.Itoa(i)
+		if resolution := wiring.Resolve(injection); resolution != nil && resolution.Kind != config_model.ResolveOne {
+			g.writeResolution(w, dependency, injection, resolution, qualifiers)
+			args = append(args, dependency)
+			continue
+		}
+		provider := wiring.Provider(injection)
+		if injection.Value != "" || provider == nil || provider.Component == nil || provider.Component.Scope == config_model.ScopeSingleton {
+			args = append(args, g.injectionExpr(injection, wiring, "ctx"))
+			continue
+		}
+		method := provider.Component.Name
+		if provider.Component.Scope == config_model.ScopePrototype {
+			method = "New" + method
+		}
+		w.line("%s, err := c.%s(ctx)", dependency, method)
+		w.open("if err != nil {")
+		w.line("return instance, err")
+		w.close("}")
+		args = append(args, dependency)
+	}
+	if component.Constructor == "" {
+		w.line("instance = &%s{}", (&config_model.Type{Name: component.TypeName, ImportPath: component.ImportPath}).QualifiedName(qualifiers))
+		for i, field := range component.Fields {
+			w.line("instance.%s = %s", field.Name, args[len(component.Params)+i])
+		}
+		w.line("return instance, nil")
+		w.close("}")
+		w.line("")
+		return
+	}
+	function := component.Constructor
+	if qualifier, ok := qualifiers[component.ImportPath]; ok {
+		function = qualifier + "." + function
+	}
+	call := function + "(" + strings.Join(args, ", ") + ")"
+	if component.ReturnsError {
+		w.line("return %s", call)
+	} else {
+		w.line("return %s, nil", call)
+	}
+	w.close("}")
+	w.line("")
+}
+
+// writeResolution 生成选择或收集组件的代码，结果保存在变量dependency中：
+// 切片和map收集所有启用的组件，${key}按配置项的值选择，多个带条件的组件选择唯一启用的组件
+func (g *Generator) writeResolution(w *codeWriter, dependency string, injection *config_model.Injection, resolution *config_model.Resolution, qualifiers map[string]string) {
+	typ := injectionType(injection, qualifiers)
+	switch resolution.Kind {
+	case config_model.ResolveSlice, config_model.ResolveMap:
+		if resolution.Kind == config_model.ResolveSlice {
+			w.line("%s := make(%s, 0, %d)", dependency, typ, len(resolution.Providers))
+		} else {
+			w.line("%s := make(%s, %d)", dependency, typ, len(resolution.Providers))
+		}
+		for _, provider := range resolution.Providers {
+			component := provider.Component
+			if len(component.Conditions) > 0 {
+				w.open("if c.conditions.Enabled(%q) {", component.Name)
+			}
+			if resolution.Kind == config_model.ResolveSlice {
+				w.line("%s = append(%s, c.%s)", dependency, dependency, component.Name)
+			} else {
+				w.line("%s[%q] = c.%s", dependency, component.Qualifier(), component.Name)
+			}
+			if len(component.Conditions) > 0 {
+				w.close("}")
+			}
+		}
+		return
+	case config_model.ResolveConfig:
+		expr, _ := g.configExpr(resolution.Key)
+		names := make([]string, 0, len(resolution.Providers))
+		w.line("var %s %s", dependency, typ)
+		w.open("switch selected := fmt.Sprint(%s); selected {", expr)
+		for _, provider := range resolution.Providers {
+			component := provider.Component
+			names = append(names, component.Qualifier())
+			w.open("case %q:", component.Qualifier())
+			if len(component.Conditions) > 0 {
+				w.open("if err := c.conditions.Check(%q); err != nil {", component.Name)
+				w.line("return instance, err")
+				w.close("}")
+			}
+			w.line("%s = c.%s", dependency, component.Name)
+			w.indent--
+		}
+		w.open("default:")
+		w.line("return instance, fmt.Errorf(\"%s is %%q, use one of: %s\", selected)", resolution.Key, strings.Join(names, ", "))
+		w.indent--
+		w.line("}")
+		return
+	}
+	names := make([]string, 0, len(resolution.Providers))
+	for _, provider := range resolution.Providers {
+		names = append(names, strconv.Quote(provider.Component.Name))
+	}
+	selected := "selected" + strings.TrimPrefix(dependency, "dependency")
+	w.line("var %s %s", dependency, typ)
+	w.line("%s, err := c.conditions.One(%q, %s)", selected, injection.Type, strings.Join(names, ", "))
+	w.open("if err != nil {")
+	w.line("return instance, err")
+	w.close("}")
+	w.open("switch %s {", selected)
+	for _, provider := range resolution.Providers {
+		w.open("case %q:", provider.Component.Name)
+		w.line("%s = c.%s", dependency, provider.Component.Name)
+		w.indent--
+	}
+	w.line("}")
+}
+
+// injectionType 在生成的代码中写出注入点的类型
+func injectionType(injection *config_model.Injection, qualifiers map[string]string) string {
+	prefix, typ := injection.TypeRef()
+	return prefix + typ.QualifiedName(qualifiers)
+}
+
+// writePrototype 生成NewXxx，每次调用创建新的prototype组件并调用它的@PostConstruct或Starter，不会调用@PreDestroy
+func writePrototype(w *codeWriter, component *config_model.Component, qualifiers map[string]string) {
+	w.line("// New%s 创建新的%s（prototype），由调用方负责释放", component.Name, component.Name)
+	w.open("func (c *Container) New%s(ctx context.Context) (%s, error) {", component.Name, componentType(component, qualifiers))
+	writeReplaced(w, component, qualifiers)
+	writeCheck(w, component)
+	w.line("instance, err := c.build%s(ctx)", component.Name)
+	w.open("if err == nil {")
+	w.line("err = config_lifecycle.StartInstance(ctx, instance, %s)", hookExpr(component, "instance"))
+	w.close("}")
+	w.open("if err != nil {")
+	w.line("return nil, fmt.Errorf(\"cannot create component %s: %%v\", err)", component.Name)
+	w.close("}")
+	w.line("return instance, nil")
+	w.close("}")
+	w.line("")
+}
+
+// writeRequest 生成Xxx，在ctx的Scope中获取request组件，第一次获取时创建，Scope结束时停止
+func writeRequest(w *codeWriter, component *config_model.Component, qualifiers map[string]string) {
+	typ := componentType(component, qualifiers)
+	w.line("// %s 返回ctx的Scope中的%s（request），ctx没有Scope时返回config_lifecycle.ErrNoScope", component.Name, component.Name)
+	w.open("func (c *Container) %s(ctx context.Context) (%s, error) {", component.Name, typ)
+	writeReplaced(w, component, qualifiers)
+	writeCheck(w, component)
+	w.open("instance, err := config_lifecycle.ScopeFrom(ctx).Get(ctx, %q, func() (interface{}, config_lifecycle.Hook, error) {", component.Name)
+	w.line("instance, err := c.build%s(ctx)", component.Name)
+	w.line("return instance, %s, err", hookExpr(component, "instance"))
+	w.close("})")
+	w.open("if err != nil {")
+	w.line("return nil, fmt.Errorf(\"cannot create component %s: %%v\", err)", component.Name)
+	w.close("}")
+	w.line("return instance.(%s), nil", typ)
+	w.close("}")
+	w.line("")
+}
+
+// writeReplaced 组件被Options.Replace替换时返回替换的实例
+func writeReplaced(w *codeWriter, component *config_model.Component, qualifiers map[string]string) {
+	w.open("if replaced, ok := c.replaced[%q]; ok {", component.Name)
+	w.line("return replaced.(%s), nil", componentType(component, qualifiers))
+	w.close("}")
+}
+
+// writeOptions 生成Options和它的check：Replace中的名称必须是组件，实例必须能赋值给组件的类型
+func writeOptions(w *codeWriter, components []*config_model.Component, qualifiers map[string]string) {
+	w.line("// Options NewContainerWith的设置，主要用于测试")
+	w.open("type Options struct {")
+	w.line("// Profiles 判断@Profile条件时激活的profile，为nil时使用最近一次加载配置时激活的profile")
+	w.line("Profiles []string")
+	w.line("// Replace 组件名称到替换它的实例，例如测试中的fake；被替换的组件不会创建、启动和停止，")
+	w.line("// 不判断它的条件，注入它的地方都使用这个实例")
+	w.line("Replace map[string]interface{}")
+	w.close("}")
+	w.line("")
+	w.open("func (o Options) check() error {")
+	w.open("for name, instance := range o.Replace {")
+	w.line("ok := false")
+	w.open("switch name {")
+	for _, component := range components {
+		w.open("case %q:", component.Name)
+		w.line("_, ok = instance.(%s)", componentType(component, qualifiers))
+		w.indent--
+	}
+	w.open("default:")
+	w.line("return fmt.Errorf(\"cannot replace unknown component %%s\", name)")
+	w.indent--
+	w.line("}")
+	w.open("if !ok {")
+	w.line("return fmt.Errorf(\"cannot replace component %%s with %%T\", name, instance)")
+	w.close("}")
+	w.close("}")
+	w.line("return nil")
+	w.close("}")
+	w.line("")
+}
+
+// writeCheck 组件带有条件时，没有启用的组件返回说明原因的错误
+func writeCheck(w *codeWriter, component *config_model.Component) {
+	if len(component.Conditions) == 0 {
+		return
+	}
+	w.open("if err := c.conditions.Check(%q); err != nil {", component.Name)
+	w.line("return nil, err")
+	w.close("}")
+}
+
+// hookExpr 组件的Hook表达式，instance为指向组件的指针
+func hookExpr(component *config_model.Component, instance string) string {
+	if component.PostConstruct == nil && component.PreDestroy == nil {
+		return fmt.Sprintf("config_lifecycle.Hook{Name: %q}", component.Name)
+	}
+	return "c.hook" + component.Name + "(" + instance + ")"
+}
+
+// writeHook 生成hookXxx，返回调用组件@PostConstruct、@PreDestroy方法的Hook，没有这些方法时不生成
+func writeHook(w *codeWriter, component *config_model.Component, qualifiers map[string]string) {
+	if component.PostConstruct == nil && component.PreDestroy == nil {
+		return
+	}
+	typ := componentType(component, qualifiers)
+	if !component.Pointer {
+		typ = "*" + typ
+	}
+	w.open("func (c *Container) hook%s(instance %s) config_lifecycle.Hook {", component.Name, typ)
+	w.open("return config_lifecycle.Hook{")
+	w.line("Name: %q,", component.Name)
+	writeHookMethod(w, "Start", component.PostConstruct)
+	writeHookMethod(w, "Stop", component.PreDestroy)
+	w.close("}")
+	w.close("}")
+	w.line("")
+}
+
+// writeLifecycle 生成Lifecycle和Run：按依赖顺序添加每个启用且没有被替换的singleton组件的@PostConstruct、@PreDestroy方法，
+// 没有这些方法时使用组件实现的Starter、Stopper接口；有条件时还生成Conditions，Run启动前输出判断结果
+func writeLifecycle(w *codeWriter, singletons []*config_model.Component, conditional, executes bool) {
+	if executes {
+		w.line("// Lifecycle 按依赖顺序启动singleton组件，全部启动后调用@AutoExecute，按相反顺序停止组件")
+	} else {
+		w.line("// Lifecycle 按依赖顺序启动singleton组件，按相反顺序停止")
+	}
+	w.open("func (c *Container) Lifecycle() *config_lifecycle.Lifecycle {")
+	w.line("lifecycle := &config_lifecycle.Lifecycle{}")
+	for _, component := range singletons {
+		instance := "c." + component.Name
+		if !component.Pointer {
+			instance = "&" + instance
+		}
+		guard := fmt.Sprintf("c.replaced[%q] == nil", component.Name)
+		if len(component.Conditions) > 0 {
+			guard += fmt.Sprintf(" && c.conditions.Enabled(%q)", component.Name)
+		}
+		w.open("if %s {", guard)
+		w.line("lifecycle.Add(%s, %s)", instance, hookExpr(component, instance))
+		w.close("}")
+	}
+	if executes {
+		w.line("lifecycle.Add(nil, config_lifecycle.Hook{Name: \"@AutoExecute\", Start: c.autoExecute})")
+	}
+	w.line("return lifecycle")
+	w.close("}")
+	w.line("")
+	w.line("// Run 启动所有组件，直到ctx结束或者收到SIGINT、SIGTERM，然后停止所有组件")
+	w.open("func (c *Container) Run(ctx context.Context) error {")
+	if conditional {
+		w.line("fmt.Print(c.conditions.Report)")
+	}
+	w.line("return config_lifecycle.Run(ctx, c.Lifecycle())")
+	w.close("}")
+	if conditional {
+		w.line("")
+		w.line("// Conditions 组件条件的判断结果，说明每个带有条件的组件为什么启用或没有启用")
+		w.open("func (c *Container) Conditions() config_condition.Report {")
+		w.line("return c.conditions.Report")
+		w.close("}")
+	}
+}
+
+func writeHookMethod(w *codeWriter, name string, method *config_model.LifecycleMethod) {
+	if method == nil {
+		return
+	}
+	call := "instance." + method.Name + "()"
+	if method.Context {
+		call = "instance." + method.Name + "(ctx)"
+	}
+	w.open("%s: func(ctx context.Context) error {", name)
+	if method.ReturnsError {
+		w.line("return %s", call)
+	} else {
+		w.line(call)
+		w.line("return nil")
+	}
+	w.close("},")
+	if method.Timeout != "" {
+		timeout, _ := time.ParseDuration(method.Timeout)
+		w.line("%sTimeout: %s,", name, durationExpr(timeout))
+	}
+}
+
+func usesTimeout(components []*config_model.Component) bool {
+	for _, component := range components {
+		for _, method := range []*config_model.LifecycleMethod{component.PostConstruct, component.PreDestroy} {
+			if method != nil && method.Timeout != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// durationExpr 把时长写成 5 * time.Second 形式的表达式
+func durationExpr(d time.Duration) string {
+	units := []struct {
+		name  string
+		value time.Duration
+	}{{"time.Hour", time.Hour}, {"time.Minute", time.Minute}, {"time.Second", time.Second}, {"time.Millisecond", time.Millisecond}, {"time.Microsecond", time.Microsecond}}
+	for _, unit := range units {
+		if d%unit.value == 0 {
+			return strconv.FormatInt(int64(d/unit.value), 10) + " * " + unit.name
+		}
+	}
+	return "time.Duration(" + strconv.FormatInt(int64(d), 10) + ")"
+}
@@ -0,0 +1,622 @@
+package config_generator
+
+import (
+	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/model"
+	"github.com/orange0224/go-injector-yaml/config/utils"
+	"go/ast"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const loaderEntries = `
+func (l *Loader) mergeConfig(values map[string]string, target *ApplicationConfig) {
+	l.mergeApplicationConfig(values, "", target)
+}
+
+func (l *Loader) applyDefaults(target *ApplicationConfig) {
+	l.defaultsApplicationConfig("", target)
+}
+
+func (l *Loader) register(object ApplicationConfig) map[string]interface{} {
+	keySet := make(map[string]interface{})
+	l.registerApplicationConfig(keySet, "", object)
+	return keySet
+}
+
+func (l *Loader) validate(target *ApplicationConfig) {
+	l.validateApplicationConfig("", target)
+}
+
+func (l *Loader) fieldError(key string, err error) {
+	l.loadErrors = append(l.loadErrors, &config_source.Error{Key: key, Message: err.Error()})
+}
+`
+
+// scalarParsers 基本类型对应的解析函数，raw为配置中的字符串
+var scalarParsers = map[string]string{
+	"bool":          "utils.ParseBool(raw)",
+	"int":           "utils.ParseInt(raw, 0)",
+	"int8":          "utils.ParseInt(raw, 8)",
+	"int16":         "utils.ParseInt(raw, 16)",
+	"int32":         "utils.ParseInt(raw, 32)",
+	"rune":          "utils.ParseInt(raw, 32)",
+	"int64":         "utils.ParseInt(raw, 64)",
+	"uint":          "utils.ParseUint(raw, 0)",
+	"uint8":         "utils.ParseUint(raw, 8)",
+	"byte":          "utils.ParseUint(raw, 8)",
+	"uint16":        "utils.ParseUint(raw, 16)",
+	"uint32":        "utils.ParseUint(raw, 32)",
+	"uint64":        "utils.ParseUint(raw, 64)",
+	"float32":       "utils.ParseFloat(raw, 32)",
+	"float64":       "utils.ParseFloat(raw, 64)",
+	"time.Duration": "utils.ParseDuration(raw)",
+}
+
+// codeWriter 按缩进输出生成的代码
+type codeWriter struct {
+	builder strings.Builder
+	indent  int
+}
+
+func (w *codeWriter) line(format string, args ...interface{}) {
+	w.builder.WriteString(strings.Repeat("\t", w.indent))
+	w.builder.WriteString(fmt.Sprintf(format, args...))
+	w.builder.WriteString("\n")
+}
+
+func (w *codeWriter) open(format string, args ...interface{}) {
+	w.line(format, args...)
+	w.indent++
+}
+
+func (w *codeWriter) close(text string) {
+	w.indent--
+	w.line(text)
+}
+
+// generateLoader 为ApplicationConfig及其引用的每个结构体生成静态类型的merge、defaults、register和validate方法，
+// 字段类型改变后生成的代码会在编译时报错，而不是运行时通过反射出错；同时返回其他包中的类型需要的import
+func (g *Generator) generateLoader() (string, string, error) {
+	if g.models["ApplicationConfig"] == nil {
+		return "", "", fmt.Errorf("cannot find ApplicationConfig in %s, run TypeScanner first", g.ConfigDir)
+	}
+	types := g.reachableTypes("ApplicationConfig")
+	g.qualifiers = config_model.Qualifiers(config_model.ImportPath(g.ConfigDir), types)
+	imports := ""
+	for _, line := range config_model.ImportLines(g.qualifiers, types) {
+		imports += "\t" + line + "\n"
+	}
+	w := &codeWriter{}
+	w.builder.WriteString(loaderEntries)
+	w.line("")
+	g.writeAliases(w)
+	w.line("")
+	g.writeKeys(w)
+	for _, typ := range types {
+		w.line("")
+		g.writeMerge(w, typ)
+		w.line("")
+		g.writeDefaults(w, typ)
+		w.line("")
+		g.writeRegister(w, typ)
+		w.line("")
+		g.writeValidate(w, typ)
+	}
+	return strings.TrimSuffix(imports, "\n"), w.builder.String(), nil
+}
+
+// reachableTypes 依赖图中从root开始能够到达的所有结构体，按名称排序
+func (g *Generator) reachableTypes(root string) []*config_model.Type {
+	names := g.graph.Reachable(root)
+	types := make([]*config_model.Type, 0, len(names))
+	for _, name := range names {
+		types = append(types, g.models[name])
+	}
+	return types
+}
+
+// writeAliases 生成字段其他名称的完整key到新key的映射，嵌套结构体的其他名称会作用于下面所有的key
+func (g *Generator) writeAliases(w *codeWriter) {
+	aliases := make(map[string]string)
+	g.collectAliases("", g.models["ApplicationConfig"], map[string]bool{}, aliases)
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w.open("var keyAliases = map[string]config_source.KeyAlias{")
+	for _, name := range names {
+		w.line("%s: %s,", strconv.Quote(name), aliases[name])
+	}
+	w.close("}")
+}
+
+func (g *Generator) collectAliases(prefix string, typ *config_model.Type, visiting map[string]bool, aliases map[string]string) {
+	if visiting[typ.Name] {
+		return
+	}
+	visiting[typ.Name] = true
+	defer delete(visiting, typ.Name)
+	for _, field := range typ.Fields {
+		key := prefix + field.Key
+		for _, name := range field.Alternates {
+			aliases[prefix+name] = fmt.Sprintf("{Key: %s}", strconv.Quote(key))
+		}
+		for _, name := range field.DeprecatedKeys {
+			aliases[prefix+name] = fmt.Sprintf("{Key: %s, Deprecated: true}", strconv.Quote(key))
+		}
+		if nested, _ := g.nestedType(field.Expr); nested != nil {
+			if field.Inline {
+				g.collectAliases(prefix, nested, visiting, aliases)
+			} else {
+				g.collectAliases(key+".", nested, visiting, aliases)
+			}
+		}
+	}
+}
+
+// writeKeys 生成所有配置项的key和种类，严格模式据此判断列表和map中的key是否已知；
+// 指针类型的配置节为nil时register不会列出其中的key，所以从类型生成
+func (g *Generator) writeKeys(w *codeWriter) {
+	keys := make(map[string]string)
+	g.collectKeys("", g.models["ApplicationConfig"], map[string]bool{}, keys)
+	names := make([]string, 0, len(keys))
+	for name := range keys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w.open("var configKeys = config_source.Keys{")
+	for _, name := range names {
+		w.line("%s: config_source.%s,", strconv.Quote(name), keys[name])
+	}
+	w.close("}")
+}
+
+func (g *Generator) collectKeys(prefix string, typ *config_model.Type, visiting map[string]bool, keys map[string]string) {
+	if visiting[typ.Name] {
+		return
+	}
+	visiting[typ.Name] = true
+	defer delete(visiting, typ.Name)
+	for _, field := range typ.Fields {
+		key := prefix + field.Key
+		if nested, _ := g.nestedType(field.Expr); nested != nil {
+			if field.Inline {
+				g.collectKeys(prefix, nested, visiting, keys)
+			} else {
+				keys[key] = "KeySection"
+				g.collectKeys(key+".", nested, visiting, keys)
+			}
+			continue
+		}
+		switch expr := field.Expr.(type) {
+		case *ast.ArrayType:
+			if !isBytes(expr) {
+				keys[key] = "KeyList"
+				continue
+			}
+		case *ast.MapType:
+			keys[key] = "KeyMap"
+			continue
+		}
+		keys[key] = "KeyValue"
+	}
+}
+
+// nestedType 字段类型为扫描到的结构体或其指针时返回该结构体
+func (g *Generator) nestedType(expr ast.Expr) (*config_model.Type, bool) {
+	if name, pointer := config_model.StructRef(expr); name != "" {
+		return g.models[name], pointer
+	}
+	return nil, false
+}
+
+func (g *Generator) writeMerge(w *codeWriter, typ *config_model.Type) {
+	w.open("func (l *Loader) merge%s(values map[string]string, prefix string, target *%s) {", typ.Name, typ.QualifiedName(g.qualifiers))
+	for _, field := range typ.Fields {
+		key := "prefix+" + strconv.Quote(field.Key)
+		target := "target." + field.Name
+		if nested, pointer := g.nestedType(field.Expr); nested != nil {
+			prefix := nestedPrefix(field)
+			if pointer {
+				w.open("if utils.HasPrefix(values, %s) {", prefix)
+				w.open("if %s == nil {", target)
+				w.line("%s = &%s{}", target, nested.QualifiedName(g.qualifiers))
+				w.close("}")
+				w.line("l.merge%s(values, %s, %s)", nested.Name, prefix, target)
+				w.close("}")
+			} else {
+				w.line("l.merge%s(values, %s, &%s)", nested.Name, prefix, target)
+			}
+			continue
+		}
+		if field.Inline {
+			panic(fmt.Errorf("%s: %s.%s: inline is only supported for config struct fields", field.Pos, typ.Name, field.Name))
+		}
+		switch expr := field.Expr.(type) {
+		case *ast.ArrayType:
+			if isBytes(expr) {
+				break
+			}
+			element := config_model.ExprString(expr.Elt)
+			if _, ok := scalarParsers[element]; expr.Len != nil || !ok && element != "string" {
+				g.unsupported(w, typ, field)
+				continue
+			}
+			w.open("if items := utils.Items(values, %s); items != nil {", key)
+			w.line("%s = make(%s, len(items))", target, field.Type)
+			w.open("for i, raw := range items {")
+			writeScalar(w, element, fmt.Sprintf("fmt.Sprintf(\"%%s%s[%%d]\", prefix, i)", field.Key), func(value string) {
+				w.line("%s[i] = %s", target, value)
+			})
+			w.close("}")
+			w.close("}")
+			continue
+		case *ast.MapType:
+			element := config_model.ExprString(expr.Value)
+			if _, ok := scalarParsers[element]; config_model.ExprString(expr.Key) != "string" || !ok && element != "string" {
+				g.unsupported(w, typ, field)
+				continue
+			}
+			w.open("if items, err := utils.Entries(values, %s); err != nil {", key)
+			w.line("l.fieldError(%s, err)", key)
+			w.indent--
+			w.open("} else if items != nil {")
+			w.line("%s = make(%s, len(items))", target, field.Type)
+			w.open("for name, raw := range items {")
+			writeScalar(w, element, "prefix+"+strconv.Quote(field.Key+".")+"+name", func(value string) {
+				w.line("%s[name] = %s", target, value)
+			})
+			w.close("}")
+			w.close("}")
+			continue
+		}
+		if !g.canSetScalar(field.Expr) {
+			g.unsupported(w, typ, field)
+			continue
+		}
+		w.open("if raw := values[%s]; utils.NotBlank(raw) {", key)
+		g.writeSetter(w, field.Expr, target, key)
+		w.close("}")
+	}
+	w.close("}")
+}
+
+// writeDefaults 为带有default标签且仍是零值的字段设置默认值
+func (g *Generator) writeDefaults(w *codeWriter, typ *config_model.Type) {
+	w.open("func (l *Loader) defaults%s(prefix string, target *%s) {", typ.Name, typ.QualifiedName(g.qualifiers))
+	for _, field := range typ.Fields {
+		target := "target." + field.Name
+		if nested, pointer := g.nestedType(field.Expr); nested != nil {
+			prefix := nestedPrefix(field)
+			if pointer {
+				w.open("if %s != nil {", target)
+				w.line("l.defaults%s(%s, %s)", nested.Name, prefix, target)
+				w.close("}")
+			} else {
+				w.line("l.defaults%s(%s, &%s)", nested.Name, prefix, target)
+			}
+			continue
+		}
+		def, ok := field.Tag.Lookup("default")
+		if !ok {
+			continue
+		}
+		if !g.canSetScalar(field.Expr) {
+			panic(fmt.Errorf("%s: default tag is not supported for %s.%s of type %s", field.Pos, typ.Name, field.Name, field.Type))
+		}
+		w.open("if %s {", zeroCheck(field.Expr, target))
+		w.line("raw := %s", strconv.Quote(def))
+		g.writeSetter(w, field.Expr, target, "prefix+"+strconv.Quote(field.Key))
+		w.close("}")
+	}
+	w.close("}")
+}
+
+func (g *Generator) writeRegister(w *codeWriter, typ *config_model.Type) {
+	w.open("func (l *Loader) register%s(keySet map[string]interface{}, prefix string, object %s) {", typ.Name, typ.QualifiedName(g.qualifiers))
+	for _, field := range typ.Fields {
+		value := "object." + field.Name
+		if !field.Inline {
+			w.line("keySet[prefix+%s] = %s", strconv.Quote(field.Key), value)
+		}
+		if nested, pointer := g.nestedType(field.Expr); nested != nil {
+			prefix := nestedPrefix(field)
+			if pointer {
+				w.open("if %s != nil {", value)
+				w.line("l.register%s(keySet, %s, *%s)", nested.Name, prefix, value)
+				w.close("}")
+			} else {
+				w.line("l.register%s(keySet, %s, %s)", nested.Name, prefix, value)
+			}
+		}
+	}
+	w.close("}")
+}
+
+// operand validate规则校验的值：kind为string、number、bool或length（列表、map和[]byte按长度校验），
+// set为已配置（非零值）的条件
+type operand struct {
+	kind     string
+	typeName string
+	value    string
+	set      string
+	unset    string
+}
+
+// validateOperand 字段能够校验时返回对应的operand，指针为nil表示没有配置，指向的零值也会被校验
+func (g *Generator) validateOperand(expr ast.Expr, target string) (operand, bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		op, ok := g.validateOperand(star.X, "*"+target)
+		if !ok || op.kind == "length" {
+			return operand{}, false
+		}
+		op.set, op.unset = target+" != nil", target+" == nil"
+		return op, true
+	}
+	switch expr := expr.(type) {
+	case *ast.ArrayType:
+		if expr.Len != nil {
+			return operand{}, false
+		}
+		return operand{kind: "length", value: target, set: "len(" + target + ") > 0", unset: "len(" + target + ") == 0"}, true
+	case *ast.MapType:
+		return operand{kind: "length", value: target, set: "len(" + target + ") > 0", unset: "len(" + target + ") == 0"}, true
+	}
+	typeName := config_model.ExprString(expr)
+	switch typeName {
+	case "string":
+		return operand{kind: "string", value: target, set: target + ` != ""`, unset: target + ` == ""`}, true
+	case "bool":
+		return operand{kind: "bool", value: target, set: target, unset: "!" + target}, true
+	}
+	if _, ok := scalarParsers[typeName]; ok {
+		return operand{kind: "number", typeName: typeName, value: target, set: target + " != 0", unset: target + " == 0"}, true
+	}
+	return operand{}, false
+}
+
+// writeValidate 按validate标签校验加载后的值；除required外，没有配置的零值不校验，与生成的JSON Schema一致
+func (g *Generator) writeValidate(w *codeWriter, typ *config_model.Type) {
+	w.open("func (l *Loader) validate%s(prefix string, target *%s) {", typ.Name, typ.QualifiedName(g.qualifiers))
+	for _, field := range typ.Fields {
+		target := "target." + field.Name
+		key := "prefix+" + strconv.Quote(field.Key)
+		rules := config_model.Rules(field.Tag)
+		if nested, pointer := g.nestedType(field.Expr); nested != nil {
+			prefix := nestedPrefix(field)
+			required := false
+			for _, rule := range rules {
+				if pointer && rule.Name == "required" {
+					required = true
+				} else if rule.Name != "omitempty" {
+					g.skipRule(typ, field, rule)
+				}
+			}
+			if !pointer {
+				w.line("l.validate%s(%s, &%s)", nested.Name, prefix, target)
+				continue
+			}
+			if required {
+				w.open("if %s == nil {", target)
+				w.line("l.fieldError(%s, %s)", key, errorf("is required"))
+				w.indent--
+				w.open("} else {")
+			} else {
+				w.open("if %s != nil {", target)
+			}
+			w.line("l.validate%s(%s, %s)", nested.Name, prefix, target)
+			w.close("}")
+			continue
+		}
+		op, ok := g.validateOperand(field.Expr, target)
+		for _, rule := range rules {
+			if !ok {
+				g.skipRule(typ, field, rule)
+				continue
+			}
+			g.writeRule(w, typ, field, rule, op, key)
+		}
+	}
+	w.close("}")
+}
+
+// comparisons 比较规则不满足时的运算符和错误信息
+var comparisons = map[string]struct {
+	operator string
+	message  string
+}{
+	"min": {"<", "at least %s"},
+	"gte": {"<", "at least %s"},
+	"max": {">", "at most %s"},
+	"lte": {">", "at most %s"},
+	"gt":  {"<=", "greater than %s"},
+	"lt":  {">=", "less than %s"},
+	"len": {"!=", "%s"},
+}
+
+func (g *Generator) writeRule(w *codeWriter, typ *config_model.Type, field *config_model.Field, rule config_model.Rule, op operand, key string) {
+	fail := func(condition, message string) {
+		w.open("if %s {", condition)
+		w.line("l.fieldError(%s, %s)", key, errorf(message))
+		w.close("}")
+	}
+	invalid := func(err error) {
+		panic(fmt.Errorf("%s: validate rule %s=%s of %s.%s: %v", field.Pos, rule.Name, rule.Param, typ.Name, field.Name, err))
+	}
+	comparison, compare := comparisons[rule.Name]
+	switch {
+	case rule.Name == "omitempty":
+	case rule.Name == "required":
+		fail(op.unset, "is required")
+	case compare && op.kind == "number" && rule.Name != "len":
+		literal, err := numberLiteral(op.typeName, rule.Param)
+		if err != nil {
+			invalid(err)
+		}
+		fail(fmt.Sprintf("%s && %s %s %s", op.set, op.value, comparison.operator, literal), "must be "+fmt.Sprintf(comparison.message, rule.Param))
+	case compare && (op.kind == "string" || op.kind == "length"):
+		if _, err := strconv.ParseUint(rule.Param, 10, 32); err != nil {
+			invalid(err)
+		}
+		length := "len(" + op.value + ")"
+		if op.kind == "string" {
+			length = "len([]rune(" + op.value + "))"
+		}
+		fail(fmt.Sprintf("%s && %s %s %s", op.set, length, comparison.operator, rule.Param), "length must be "+fmt.Sprintf(comparison.message, rule.Param))
+	case rule.Name == "oneof" && (op.kind == "string" || op.kind == "number"):
+		options := strings.Fields(rule.Param)
+		if len(options) == 0 {
+			invalid(fmt.Errorf("no values"))
+		}
+		conditions := []string{op.set}
+		for _, option := range options {
+			literal := strconv.Quote(option)
+			if op.kind == "number" {
+				var err error
+				if literal, err = numberLiteral(op.typeName, option); err != nil {
+					invalid(err)
+				}
+			}
+			conditions = append(conditions, op.value+" != "+literal)
+		}
+		fail(strings.Join(conditions, " && "), "must be one of "+strings.Join(options, ", "))
+	case isFormat(rule.Name) && op.kind == "string":
+		w.open("if %s {", op.set)
+		w.open("if err := utils.CheckFormat(%s, %s); err != nil {", strconv.Quote(rule.Name), op.value)
+		w.line("l.fieldError(%s, err)", key)
+		w.close("}")
+		w.close("}")
+	default:
+		g.skipRule(typ, field, rule)
+	}
+}
+
+// skipRule 加载时无法校验的规则只用于生成JSON Schema，生成时给出提示
+func (g *Generator) skipRule(typ *config_model.Type, field *config_model.Field, rule config_model.Rule) {
+	fmt.Printf("%s: validate rule %s of %s.%s is not checked when loading config\n", field.Pos, rule.Name, typ.Name, field.Name)
+}
+
+func isFormat(name string) bool {
+	for _, format := range utils.Formats {
+		if format == name {
+			return true
+		}
+	}
+	return false
+}
+
+// numberLiteral 把规则参数转换为typeName类型的常量，time.Duration的参数为 10s 形式，转换为纳秒
+func numberLiteral(typeName, param string) (string, error) {
+	bitSize := map[string]int{"int8": 8, "uint8": 8, "byte": 8, "int16": 16, "uint16": 16, "int32": 32, "rune": 32, "uint32": 32, "float32": 32}[typeName]
+	if bitSize == 0 {
+		bitSize = 64
+	}
+	switch {
+	case typeName == "time.Duration":
+		d, err := time.ParseDuration(param)
+		return strconv.FormatInt(int64(d), 10), err
+	case strings.HasPrefix(typeName, "float"):
+		f, err := strconv.ParseFloat(param, bitSize)
+		return strconv.FormatFloat(f, 'g', -1, 64), err
+	case strings.HasPrefix(typeName, "uint") || typeName == "byte":
+		u, err := strconv.ParseUint(param, 10, bitSize)
+		return strconv.FormatUint(u, 10), err
+	}
+	i, err := strconv.ParseInt(param, 10, bitSize)
+	return strconv.FormatInt(i, 10), err
+}
+
+// errorf 生成返回固定错误信息的表达式
+func errorf(message string) string {
+	return "fmt.Errorf(" + strconv.Quote(strings.ReplaceAll(message, "%", "%%")) + ")"
+}
+
+// unsupported 无法从字符串赋值的字段只会出现在register中，生成时给出提示
+func (g *Generator) unsupported(w *codeWriter, typ *config_model.Type, field *config_model.Field) {
+	fmt.Printf("%s: %s.%s of type %s cannot be loaded from config, skipped\n", field.Pos, typ.Name, field.Name, field.Type)
+	w.line("// %s: %s is not supported", field.Name, field.Type)
+}
+
+// canSetScalar 字段是否为基本类型、[]byte或基本类型的指针
+func (g *Generator) canSetScalar(expr ast.Expr) bool {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if array, ok := expr.(*ast.ArrayType); ok {
+		return isBytes(array)
+	}
+	name := config_model.ExprString(expr)
+	_, ok := scalarParsers[name]
+	return ok || name == "string"
+}
+
+// writeSetter 把raw转换为字段类型后赋值给target，转换失败时记录key对应的错误
+func (g *Generator) writeSetter(w *codeWriter, expr ast.Expr, target, key string) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		writeScalar(w, config_model.ExprString(star.X), key, func(value string) {
+			w.line("converted := %s", value)
+			w.line("%s = &converted", target)
+		})
+		return
+	}
+	if array, ok := expr.(*ast.ArrayType); ok && isBytes(array) {
+		w.line("%s = []byte(raw)", target)
+		return
+	}
+	writeScalar(w, config_model.ExprString(expr), key, func(value string) {
+		w.line("%s = %s", target, value)
+	})
+}
+
+// writeScalar 解析raw为typeName类型，assign负责输出使用解析结果的语句
+func writeScalar(w *codeWriter, typeName, key string, assign func(value string)) {
+	if typeName == "string" {
+		assign("raw")
+		return
+	}
+	value := "value"
+	switch typeName {
+	case "bool", "int64", "uint64", "float64", "time.Duration":
+	default:
+		value = typeName + "(value)"
+	}
+	w.open("if value, err := %s; err != nil {", scalarParsers[typeName])
+	w.line("l.fieldError(%s, err)", key)
+	w.indent--
+	w.open("} else {")
+	assign(value)
+	w.close("}")
+}
+
+func zeroCheck(expr ast.Expr, target string) string {
+	if _, ok := expr.(*ast.StarExpr); ok {
+		return target + " == nil"
+	}
+	if _, ok := expr.(*ast.ArrayType); ok {
+		return "len(" + target + ") == 0"
+	}
+	switch config_model.ExprString(expr) {
+	case "string":
+		return target + ` == ""`
+	case "bool":
+		return "!" + target
+	}
+	return target + " == 0"
+}
+
+// nestedPrefix 嵌套结构体的key前缀，inline的字段与上层使用相同的前缀
+func nestedPrefix(field *config_model.Field) string {
+	if field.Inline {
+		return "prefix"
+	}
+	return "prefix+" + strconv.Quote(field.Key+".")
+}
+
+func isBytes(array *ast.ArrayType) bool {
+	ident, ok := array.Elt.(*ast.Ident)
+	return ok && array.Len == nil && ident.Name == "byte"
+}
@@ -0,0 +1,366 @@
+package config_generator
+
+import (
+	"github.com/orange0224/go-injector-yaml/config/model"
+	"github.com/orange0224/go-injector-yaml/config/type"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// parseModels 解析测试用的配置类型，field为Sample结构体中的字段声明
+func parseModels(t *testing.T, field string) map[string]*config_model.Type {
+	dir, err := ioutil.TempDir("", "generator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	source := "package config\n\nimport \"time\"\n\nvar _ time.Duration\n\n" +
+		"//@Configuration\ntype Nested struct {\n\tName string `yaml:\"name\" validate:\"required\"`\n}\n\n" +
+		"//@Configuration\ntype Sample struct {\n\t" + field + "\n}\n"
+	path := filepath.Join(dir, "sample.go")
+	if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := config_model.ParseFile(token.NewFileSet(), path, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := make(map[string]*config_model.Type)
+	for _, typ := range file.Types {
+		models[typ.Name] = typ
+	}
+	return models
+}
+
+func TestWriteValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    string
+		contains []string
+		absent   []string
+		panics   bool
+	}{
+		{
+			name:     "required string",
+			field:    "Host string `yaml:\"host\" validate:\"required\"`",
+			contains: []string{`if target.Host == "" {`, `l.fieldError(prefix+"host", fmt.Errorf("is required"))`},
+		},
+		{
+			name:     "number range",
+			field:    "Port int `yaml:\"port\" validate:\"min=1,max=65535\"`",
+			contains: []string{"if target.Port != 0 && target.Port < 1 {", `fmt.Errorf("must be at least 1")`, "target.Port > 65535", `fmt.Errorf("must be at most 65535")`},
+		},
+		{
+			name:     "duration",
+			field:    "Timeout time.Duration `yaml:\"timeout\" validate:\"gte=1s,lt=1m\"`",
+			contains: []string{"target.Timeout < 1000000000", `"must be at least 1s"`, "target.Timeout >= 60000000000", `"must be less than 1m"`},
+		},
+		{
+			name:     "pointer checks zero",
+			field:    "Limit *int `yaml:\"limit\" validate:\"gt=0\"`",
+			contains: []string{"if target.Limit != nil && *target.Limit <= 0 {", `"must be greater than 0"`},
+		},
+		{
+			name:     "string length",
+			field:    "Code string `yaml:\"code\" validate:\"len=3\"`",
+			contains: []string{`target.Code != "" && len([]rune(target.Code)) != 3`, `"length must be 3"`},
+		},
+		{
+			name:     "list length",
+			field:    "Tags []string `yaml:\"tags\" validate:\"required,min=2\"`",
+			contains: []string{"if len(target.Tags) == 0 {", "len(target.Tags) > 0 && len(target.Tags) < 2", `"length must be at least 2"`},
+		},
+		{
+			name:     "oneof string",
+			field:    "Mode string `yaml:\"mode\" validate:\"oneof=debug release\"`",
+			contains: []string{`target.Mode != "" && target.Mode != "debug" && target.Mode != "release"`, `"must be one of debug, release"`},
+		},
+		{
+			name:     "oneof number",
+			field:    "Level uint8 `yaml:\"level\" validate:\"oneof=1 2\"`",
+			contains: []string{"target.Level != 0 && target.Level != 1 && target.Level != 2"},
+		},
+		{
+			name:     "percent in message",
+			field:    "Ratio string `yaml:\"ratio\" validate:\"oneof=50% 100%\"`",
+			contains: []string{`fmt.Errorf("must be one of 50%%, 100%%")`},
+		},
+		{
+			name:     "format",
+			field:    "Mail string `yaml:\"mail\" validate:\"email\"`",
+			contains: []string{`if target.Mail != "" {`, `if err := utils.CheckFormat("email", target.Mail); err != nil {`},
+		},
+		{
+			name:     "required section",
+			field:    "Nested *Nested `yaml:\"nested\" validate:\"required\"`",
+			contains: []string{"if target.Nested == nil {", "} else {", `l.validateNested(prefix+"nested.", target.Nested)`},
+		},
+		{
+			name:     "section",
+			field:    "Nested Nested `yaml:\"nested\"`",
+			contains: []string{`l.validateNested(prefix+"nested.", &target.Nested)`},
+		},
+		{
+			name:   "rules that cannot be checked",
+			field:  "Tags []string `yaml:\"tags\" validate:\"dive,email\"`",
+			absent: []string{"if "},
+		},
+		{
+			name:   "invalid number",
+			field:  "Port int `yaml:\"port\" validate:\"min=abc\"`",
+			panics: true,
+		},
+		{
+			name:   "overflow",
+			field:  "Level uint8 `yaml:\"level\" validate:\"max=300\"`",
+			panics: true,
+		},
+		{
+			name:   "invalid length",
+			field:  "Name string `yaml:\"name\" validate:\"min=-1\"`",
+			panics: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := &Generator{models: parseModels(t, test.field)}
+			w := &codeWriter{}
+			func() {
+				defer func() {
+					if r := recover(); (r != nil) != test.panics {
+						t.Fatalf("panic %v, want panic %v", r, test.panics)
+					}
+				}()
+				g.writeValidate(w, g.models["Sample"])
+			}()
+			code := w.builder.String()
+			for _, text := range test.contains {
+				if !strings.Contains(code, text) {
+					t.Errorf("generated code does not contain %s:\n%s", text, code)
+				}
+			}
+			for _, text := range test.absent {
+				if strings.Contains(code, text) {
+					t.Errorf("generated code contains %s:\n%s", text, code)
+				}
+			}
+		})
+	}
+}
+
+// buildLoader 在临时module中写入files，运行TypeScanner和Generator后编译main.go，返回module目录；
+// main.go用第一个参数作为配置文件调用Loader.Load并输出结果
+func buildLoader(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("builds a generated module")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	root, err := filepath.Abs("../..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := ioutil.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "generated-loader")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	module := map[string]string{
+		"go.mod": "module example\n\ngo 1.16\n\nrequire github.com/orange0224/go-injector-yaml v0.0.0\n\n" +
+			"replace github.com/orange0224/go-injector-yaml => " + root + "\n",
+		"go.sum": string(sum),
+		"main.go": "package main\n\nimport (\n\t\"context\"\n\t\"example/config\"\n\t\"fmt\"\n" +
+			"\t\"github.com/orange0224/go-injector-yaml/config/source\"\n\t\"os\"\n)\n\nfunc main() {\n" +
+			"\tloader := &config.Loader{Sources: []config_source.Source{&config_source.File{Path: os.Args[1]}}}\n" +
+			"\tfmt.Println(loader.Load(context.Background()))\n}\n",
+	}
+	for name, content := range files {
+		module[name] = content
+	}
+	for name, content := range module {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	(&config_type.TypeScanner{ConfigDir: dir}).Begin()
+	(&Generator{ConfigDir: filepath.Join(dir, "config")}).Begin()
+	runGo(t, goTool, dir, "build", "-o", "loader", ".")
+	return dir
+}
+
+// runGo 在dir中执行go命令，失败时调用t.Fatalf，返回输出
+func runGo(t *testing.T, goTool, dir string, args ...string) string {
+	t.Helper()
+	command := exec.Command(goTool, args...)
+	command.Dir = dir
+	command.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOPROXY=off")
+	output, err := command.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return string(output)
+}
+
+// runLoader 用path作为配置文件运行buildLoader编译的程序，返回输出
+func runLoader(t *testing.T, dir, path string) string {
+	t.Helper()
+	output, err := exec.Command(filepath.Join(dir, "loader"), path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, output)
+	}
+	return string(output)
+}
+
+// TestGeneratedLoaderErrors 生成config_loader.go后编译运行，检查各种格式的加载错误给出的位置
+func TestGeneratedLoaderErrors(t *testing.T) {
+	dir := buildLoader(t, map[string]string{
+		"config/server.go": "package config\n\n//@Configuration @Alias=server\ntype ServerConfig struct {\n" +
+			"\tHost string `yaml:\"host\" validate:\"hostname\"`\n\tPort int `yaml:\"port\" validate:\"max=65535\"`\n}\n",
+		"bad.yaml":       "server:\n  port: abc\n",
+		"bad.json":       "{\n  \"server\": {\n    \"port\": 70000\n  }\n}\n",
+		"bad.env":        "# comment\nSERVER_HOST=bad_host\n",
+		"bad.properties": "server.port = x\n",
+		"bad.toml":       "[server]\nport = \"x\"\n",
+		"syntax.json":    "{\n  \"server\": {\n    \"port\" 1\n  }\n}\n",
+	})
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"bad.yaml", `bad.yaml:2:9: server.port: cannot use "abc" as int`},
+		{"bad.json", "bad.json:3:13: server.port: must be at most 65535"},
+		{"bad.env", "bad.env:2:13: server.host: must be a valid hostname"},
+		{"bad.properties", `bad.properties:1:15: server.port: cannot use "x" as int`},
+		{"bad.toml", `bad.toml: server.port: cannot use "x" as int`},
+		{"syntax.json", "syntax.json:3:12: cannot decode json config"},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			output := runLoader(t, dir, filepath.Join(dir, test.file))
+			if want := filepath.Join(dir, test.want); !strings.Contains(output, want) {
+				t.Fatalf("output %q does not contain %q", output, want)
+			}
+		})
+	}
+}
+
+// TestGeneratedLoaderConditions 未启用的配置节不校验，其中的类型错误也不报告
+func TestGeneratedLoaderConditions(t *testing.T) {
+	dir := buildLoader(t, map[string]string{
+		"config/cache.go": "package config\n\n//@Configuration @Alias=cache\n//@ConditionalOnProperty(cache.enabled=true)\ntype Cache struct {\n" +
+			"\tEnabled bool `yaml:\"enabled\"`\n\tURL string `yaml:\"url\" validate:\"required\"`\n\tSize int `yaml:\"size\" validate:\"min=1\"`\n}\n\n" +
+			"//@Configuration @Alias=metrics\n//@Profile(prod)\ntype Metrics struct {\n\tEndpoint string `yaml:\"endpoint\" validate:\"required,url\"`\n}\n",
+		"disabled.yaml":     "cache:\n  enabled: false\n  size: 0\n",
+		"disabled-bad.yaml": "cache:\n  size: abc\nmetrics:\n  endpoint: not a url\n",
+		"enabled.yaml":      "cache:\n  enabled: true\n  size: 0\n",
+		"valid.yaml":        "cache:\n  enabled: true\n  url: redis://cache\n  size: 10\n",
+	})
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"disabled.yaml", "<nil>"},
+		{"disabled-bad.yaml", "<nil>"},
+		{"enabled.yaml", "cache.url: is required"},
+		{"valid.yaml", "<nil>"},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			output := strings.TrimSpace(runLoader(t, dir, filepath.Join(dir, test.file)))
+			if test.want == "<nil>" && output != test.want || !strings.Contains(output, test.want) {
+				t.Fatalf("output %q, want %q", output, test.want)
+			}
+		})
+	}
+}
+
+// TestGeneratedTestHelpers SetTestConfig可以在子测试和并行的测试中调用，Cleanup后恢复之前的配置；
+// 使用 -tags noconfigtesting 构建时不依赖config/testing
+func TestGeneratedTestHelpers(t *testing.T) {
+	dir := buildLoader(t, map[string]string{
+		"config/server.go": "package config\n\n//@Configuration @Alias=server\ntype ServerConfig struct {\n" +
+			"\tPort int `yaml:\"port\"`\n}\n",
+		"config/helpers_test.go": `package config
+
+import (
+	"github.com/orange0224/go-injector-yaml/config/testing"
+	"strconv"
+	"testing"
+)
+
+func portConfig(t *testing.T, port int) ApplicationConfig {
+	return NewTestConfig(t, nil, config_testing.Set("server.port", port))
+}
+
+func checkPort(t *testing.T, want int) {
+	t.Helper()
+	if got := GetApplicationConfig().Server.Port; got != want {
+		t.Fatalf("port %d, want %d", got, want)
+	}
+	if got := GetConfig("server.port"); got != want {
+		t.Fatalf("GetConfig %v, want %d", got, want)
+	}
+}
+
+func TestNested(t *testing.T) {
+	SetTestConfig(t, portConfig(t, 1))
+	t.Run("child", func(t *testing.T) {
+		SetTestConfig(t, portConfig(t, 2))
+		checkPort(t, 2)
+		t.Run("grandchild", func(t *testing.T) {
+			SetTestConfig(t, portConfig(t, 3))
+			checkPort(t, 3)
+		})
+		checkPort(t, 2)
+	})
+	checkPort(t, 1)
+	SetTestConfig(t, portConfig(t, 4))
+	checkPort(t, 4)
+}
+
+func TestParallel(t *testing.T) {
+	SetTestConfig(t, portConfig(t, 5))
+	t.Run("group", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			port := i + 10
+			t.Run(strconv.Itoa(i), func(t *testing.T) {
+				t.Parallel()
+				SetTestConfig(t, portConfig(t, port))
+			})
+		}
+	})
+	checkPort(t, 5)
+}
+`,
+	})
+	goTool, _ := exec.LookPath("go")
+	runGo(t, goTool, dir, "test", "-count=1", "-timeout=1m", "./config")
+	runGo(t, goTool, dir, "build", "-tags", "noconfigtesting", "-o", "loader", ".")
+	deps := runGo(t, goTool, dir, "list", "-deps", "-tags", "noconfigtesting", ".")
+	if strings.Contains(deps, "go-injector-yaml/config/testing") {
+		t.Fatalf("noconfigtesting build depends on config/testing:\n%s", deps)
+	}
+	loader, err := ioutil.ReadFile(filepath.Join(dir, "config", "config_loader.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(loader), "config/testing") {
+		t.Fatal("config_loader.go imports config/testing")
+	}
+}
@@ -0,0 +1,157 @@
+package config_lifecycle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout Run收到信号后停止所有组件的默认时限
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Starter 可选接口，组件在启动时调用Start
+type Starter interface {
+	Start(ctx context.Context) error
+}
+
+// Stopper 可选接口，组件在停止时调用Stop
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+// Hook 一个组件的启动和停止函数，StartTimeout、StopTimeout为它们的时限，0时使用Lifecycle.Timeout；
+// 超过时限时函数收到的ctx被取消，函数必须在ctx取消后尽快返回，Lifecycle会一直等待它返回
+type Hook struct {
+	Name         string
+	Start        func(ctx context.Context) error
+	Stop         func(ctx context.Context) error
+	StartTimeout time.Duration
+	StopTimeout  time.Duration
+}
+
+// Errors 停止组件时的多个错误
+type Errors []error
+
+func (e Errors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Lifecycle 按添加顺序启动组件，按相反顺序停止已经启动的组件
+type Lifecycle struct {
+	// Timeout 每个Hook默认的时限，0表示不限制
+	Timeout time.Duration
+	// ShutdownTimeout Run停止所有组件的时限，0时使用DefaultShutdownTimeout
+	ShutdownTimeout time.Duration
+
+	lock    sync.Mutex
+	hooks   []Hook
+	started int
+}
+
+// Bind 返回补全后的Hook：没有设置的Start、Stop从实现了Starter、Stopper的instance中获取
+func (h Hook) Bind(instance interface{}) Hook {
+	if starter, ok := instance.(Starter); ok && h.Start == nil {
+		h.Start = starter.Start
+	}
+	if stopper, ok := instance.(Stopper); ok && h.Stop == nil {
+		h.Stop = stopper.Stop
+	}
+	return h
+}
+
+// Add 添加组件的Hook，先按Bind补全，Start、Stop都没有时忽略
+func (l *Lifecycle) Add(instance interface{}, hook Hook) {
+	hook = hook.Bind(instance)
+	if hook.Start == nil && hook.Stop == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.hooks = append(l.hooks, hook)
+}
+
+// Start 依次启动所有组件；某个组件失败时按相反顺序停止已经启动的组件并返回错误
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for l.started < len(l.hooks) {
+		hook := l.hooks[l.started]
+		if err := l.call(ctx, hook.Start, hook.StartTimeout); err != nil {
+			err = fmt.Errorf("cannot start %s: %v", hook.Name, err)
+			if stopErr := l.stop(ctx); stopErr != nil {
+				return Errors{err, stopErr}
+			}
+			return err
+		}
+		l.started++
+	}
+	return nil
+}
+
+// Stop 按相反顺序停止已经启动的组件，某个组件失败时继续停止其他组件，最后返回所有错误
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.stop(ctx)
+}
+
+func (l *Lifecycle) stop(ctx context.Context) error {
+	errs := make(Errors, 0)
+	for ; l.started > 0; l.started-- {
+		hook := l.hooks[l.started-1]
+		if err := l.call(ctx, hook.Stop, hook.StopTimeout); err != nil {
+			errs = append(errs, fmt.Errorf("cannot stop %s: %v", hook.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// call 使用带有时限的ctx调用函数并等待它返回；超过时限后返回的错误说明已超时
+func (l *Lifecycle) call(ctx context.Context, function func(ctx context.Context) error, timeout time.Duration) error {
+	if function == nil {
+		return nil
+	}
+	if timeout == 0 {
+		timeout = l.Timeout
+	}
+	if timeout <= 0 {
+		return function(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := function(ctx)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s: %v", timeout, err)
+	}
+	return err
+}
+
+// Run 启动所有组件，直到ctx结束或者收到SIGINT、SIGTERM，然后在ShutdownTimeout内停止所有组件；
+// 用于main函数
+func Run(ctx context.Context, lifecycle *Lifecycle) error {
+	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if err := lifecycle.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	timeout := lifecycle.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), timeout)
+	defer stopCancel()
+	return lifecycle.Stop(stopCtx)
+}
@@ -0,0 +1,223 @@
+package config_lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// recorder 记录Hook的调用顺序
+type recorder struct {
+	events []string
+}
+
+func (r *recorder) hook(name string, startErr, stopErr error) Hook {
+	return Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			r.events = append(r.events, "start "+name)
+			return startErr
+		},
+		Stop: func(ctx context.Context) error {
+			r.events = append(r.events, "stop "+name)
+			return stopErr
+		},
+	}
+}
+
+type starterStopper struct {
+	events *[]string
+}
+
+func (s *starterStopper) Start(ctx context.Context) error {
+	*s.events = append(*s.events, "start instance")
+	return nil
+}
+
+func (s *starterStopper) Stop(ctx context.Context) error {
+	*s.events = append(*s.events, "stop instance")
+	return nil
+}
+
+func TestLifecycle(t *testing.T) {
+	tests := []struct {
+		name     string
+		hooks    func(r *recorder, lifecycle *Lifecycle)
+		startErr string
+		stopErr  string
+		events   []string
+	}{
+		{
+			name: "start in order and stop in reverse order",
+			hooks: func(r *recorder, lifecycle *Lifecycle) {
+				lifecycle.Add(nil, r.hook("a", nil, nil))
+				lifecycle.Add(&starterStopper{events: &r.events}, Hook{Name: "instance"})
+				lifecycle.Add(nil, Hook{Name: "empty"})
+				lifecycle.Add(nil, r.hook("b", nil, nil))
+			},
+			events: []string{"start a", "start instance", "start b", "stop b", "stop instance", "stop a"},
+		},
+		{
+			name: "hook overrides the interfaces",
+			hooks: func(r *recorder, lifecycle *Lifecycle) {
+				hook := r.hook("hook", nil, nil)
+				hook.Stop = nil
+				lifecycle.Add(&starterStopper{events: &r.events}, hook)
+			},
+			events: []string{"start hook", "stop instance"},
+		},
+		{
+			name: "rollback on start failure",
+			hooks: func(r *recorder, lifecycle *Lifecycle) {
+				lifecycle.Add(nil, r.hook("a", nil, nil))
+				lifecycle.Add(nil, r.hook("b", nil, nil))
+				lifecycle.Add(nil, r.hook("c", errors.New("port in use"), nil))
+				lifecycle.Add(nil, r.hook("d", nil, nil))
+			},
+			startErr: "cannot start c: port in use",
+			events:   []string{"start a", "start b", "start c", "stop b", "stop a"},
+		},
+		{
+			name: "rollback errors",
+			hooks: func(r *recorder, lifecycle *Lifecycle) {
+				lifecycle.Add(nil, r.hook("a", nil, errors.New("busy")))
+				lifecycle.Add(nil, r.hook("b", errors.New("port in use"), nil))
+			},
+			startErr: "cannot start b: port in use; cannot stop a: busy",
+			events:   []string{"start a", "start b", "stop a"},
+		},
+		{
+			name: "stop continues after errors",
+			hooks: func(r *recorder, lifecycle *Lifecycle) {
+				lifecycle.Add(nil, r.hook("a", nil, errors.New("closed")))
+				lifecycle.Add(nil, r.hook("b", nil, nil))
+				lifecycle.Add(nil, r.hook("c", nil, errors.New("busy")))
+			},
+			stopErr: "cannot stop c: busy; cannot stop a: closed",
+			events:  []string{"start a", "start b", "start c", "stop c", "stop b", "stop a"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &recorder{}
+			lifecycle := &Lifecycle{}
+			test.hooks(r, lifecycle)
+			err := lifecycle.Start(context.Background())
+			if message := errorText(err); message != test.startErr {
+				t.Fatalf("start error %q, want %q", message, test.startErr)
+			}
+			if err == nil {
+				if message := errorText(lifecycle.Stop(context.Background())); message != test.stopErr {
+					t.Fatalf("stop error %q, want %q", message, test.stopErr)
+				}
+			}
+			if !reflect.DeepEqual(r.events, test.events) {
+				t.Fatalf("events %v, want %v", r.events, test.events)
+			}
+			// 已经停止的组件不会再次停止
+			if err := lifecycle.Stop(context.Background()); err != nil || len(r.events) != len(test.events) {
+				t.Fatalf("second stop: %v, events %v", err, r.events)
+			}
+		})
+	}
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// TestLifecycleStartAdded 再次调用Start时只启动之后添加的组件
+func TestLifecycleStartAdded(t *testing.T) {
+	r := &recorder{}
+	lifecycle := &Lifecycle{}
+	lifecycle.Add(nil, r.hook("a", nil, nil))
+	if err := lifecycle.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	lifecycle.Add(nil, r.hook("b", nil, nil))
+	if err := lifecycle.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := lifecycle.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"start a", "start b", "stop b", "stop a"}; !reflect.DeepEqual(r.events, want) {
+		t.Fatalf("events %v, want %v", r.events, want)
+	}
+}
+
+// TestLifecycleTimeout 超过时限时Hook收到的ctx被取消，Lifecycle等待它返回后回滚
+func TestLifecycleTimeout(t *testing.T) {
+	r := &recorder{}
+	returned := false
+	lifecycle := &Lifecycle{Timeout: time.Hour}
+	lifecycle.Add(nil, r.hook("a", nil, nil))
+	lifecycle.Add(nil, Hook{
+		Name:         "slow",
+		StartTimeout: 20 * time.Millisecond,
+		Start: func(ctx context.Context) error {
+			<-ctx.Done()
+			returned = true
+			return ctx.Err()
+		},
+	})
+	err := lifecycle.Start(context.Background())
+	if want := "cannot start slow: timed out after 20ms: context deadline exceeded"; errorText(err) != want {
+		t.Fatalf("error %q, want %q", errorText(err), want)
+	}
+	if !returned {
+		t.Fatal("Start returned before the hook")
+	}
+	if want := []string{"start a", "stop a"}; !reflect.DeepEqual(r.events, want) {
+		t.Fatalf("events %v, want %v", r.events, want)
+	}
+}
+
+func TestLifecycleTimeoutPerHook(t *testing.T) {
+	deadlines := make(map[string]time.Duration)
+	record := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			if deadline, ok := ctx.Deadline(); ok {
+				deadlines[name] = time.Until(deadline).Round(time.Second)
+			}
+			return nil
+		}
+	}
+	lifecycle := &Lifecycle{Timeout: time.Minute}
+	lifecycle.Add(nil, Hook{Name: "default", Start: record("default start"), Stop: record("default stop")})
+	lifecycle.Add(nil, Hook{Name: "custom", Start: record("custom start"), StartTimeout: time.Hour, Stop: record("custom stop"), StopTimeout: -1})
+	if err := lifecycle.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := lifecycle.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]time.Duration{"default start": time.Minute, "default stop": time.Minute, "custom start": time.Hour}
+	if !reflect.DeepEqual(deadlines, want) {
+		t.Fatalf("deadlines %v, want %v", deadlines, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	r := &recorder{}
+	lifecycle := &Lifecycle{}
+	lifecycle.Add(nil, r.hook("a", nil, nil))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- Run(ctx, lifecycle)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"start a", "stop a"}; !reflect.DeepEqual(r.events, want) {
+		t.Fatalf("events %v, want %v", r.events, want)
+	}
+}
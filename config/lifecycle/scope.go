@@ -0,0 +1,144 @@
+package config_lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNoScope ctx中没有Scope时获取request组件返回的错误
+var ErrNoScope = errors.New("no scope in context, use config_lifecycle.WithScope or RunScope")
+
+// Scope 一次请求或任务中创建的request组件，同一个Scope中每个组件只创建一次，End时按创建的相反顺序停止
+type Scope struct {
+	lock      sync.Mutex
+	instances map[string]*scopedInstance
+	created   []*scopedInstance
+	ended     bool
+}
+
+// scopedInstance Scope中的一个组件，lock保证同时只有一个Get在创建它；创建失败时不保存，下次Get重新创建
+type scopedInstance struct {
+	lock     sync.Mutex
+	created  bool
+	instance interface{}
+	hook     Hook
+}
+
+type scopeKey struct{}
+
+// NewScope 创建新的Scope
+func NewScope() *Scope {
+	return &Scope{instances: make(map[string]*scopedInstance)}
+}
+
+// WithScope 返回带有scope的ctx，生成的Container从中获取request组件
+func WithScope(ctx context.Context, scope *Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFrom 返回ctx中的Scope，没有时返回nil
+func ScopeFrom(ctx context.Context) *Scope {
+	scope, _ := ctx.Value(scopeKey{}).(*Scope)
+	return scope
+}
+
+// RunScope 在新的Scope中调用function，结束后停止其中创建的组件，例如处理一个HTTP请求或者执行一次任务；
+// 停止时不使用ctx，ctx已经取消时也会停止
+func RunScope(ctx context.Context, function func(ctx context.Context) error) (err error) {
+	scope := NewScope()
+	defer func() {
+		if endErr := scope.End(context.Background()); endErr != nil && err == nil {
+			err = endErr
+		}
+	}()
+	return function(WithScope(ctx, scope))
+}
+
+// Get 返回名称为name的组件，第一次获取时调用create创建并执行hook.Start；scope为nil时返回ErrNoScope。
+// create中可以获取其他组件，同一个组件被并发获取时只创建一次；create或Start失败时返回错误，下次获取时重新创建。
+// 创建期间Scope结束时立即停止新创建的组件并返回错误
+func (s *Scope) Get(ctx context.Context, name string, create func() (interface{}, Hook, error)) (interface{}, error) {
+	if s == nil {
+		return nil, ErrNoScope
+	}
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return nil, fmt.Errorf("scope has ended, cannot create %s", name)
+	}
+	entry, ok := s.instances[name]
+	if !ok {
+		entry = &scopedInstance{}
+		s.instances[name] = entry
+	}
+	s.lock.Unlock()
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+	if entry.created {
+		return entry.instance, nil
+	}
+	instance, hook, err := create()
+	if err != nil {
+		return nil, err
+	}
+	hook = hook.Bind(instance)
+	if hook.Start != nil {
+		if err := hook.Start(ctx); err != nil {
+			return nil, err
+		}
+	}
+	s.lock.Lock()
+	ended := s.ended
+	if !ended {
+		entry.instance, entry.hook, entry.created = instance, hook, true
+		s.created = append(s.created, entry)
+	}
+	s.lock.Unlock()
+	if ended {
+		// End已经停止了其他组件，不会再停止这个组件
+		if hook.Stop != nil {
+			if err := hook.Stop(ctx); err != nil {
+				return nil, fmt.Errorf("scope has ended, cannot create %s; cannot stop it: %v", name, err)
+			}
+		}
+		return nil, fmt.Errorf("scope has ended, cannot create %s", name)
+	}
+	return instance, nil
+}
+
+// End 按创建的相反顺序停止Scope中的组件，返回所有错误；之后不能再获取组件
+func (s *Scope) End(ctx context.Context) error {
+	s.lock.Lock()
+	s.ended = true
+	created := s.created
+	s.created = nil
+	s.lock.Unlock()
+	errs := make(Errors, 0)
+	for i := len(created) - 1; i >= 0; i-- {
+		hook := created[i].hook
+		if hook.Stop == nil {
+			continue
+		}
+		if err := hook.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("cannot stop %s: %v", hook.Name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// StartInstance 启动单个prototype组件，没有Start时什么也不做
+func StartInstance(ctx context.Context, instance interface{}, hook Hook) error {
+	hook = hook.Bind(instance)
+	if hook.Start == nil {
+		return nil
+	}
+	if err := hook.Start(ctx); err != nil {
+		return fmt.Errorf("cannot start %s: %v", hook.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package config_lifecycle
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type scopedComponent struct {
+	name   string
+	events *[]string
+	lock   *sync.Mutex
+}
+
+func (c *scopedComponent) Stop(ctx context.Context) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	*c.events = append(*c.events, "stop "+c.name)
+	return nil
+}
+
+func TestScopeGet(t *testing.T) {
+	if _, err := (*Scope)(nil).Get(context.Background(), "a", nil); err != ErrNoScope {
+		t.Fatalf("nil scope: %v", err)
+	}
+	var events []string
+	lock := &sync.Mutex{}
+	creates := 0
+	scope := NewScope()
+	create := func(name string) func() (interface{}, Hook, error) {
+		return func() (interface{}, Hook, error) {
+			creates++
+			return &scopedComponent{name: name, events: &events, lock: lock}, Hook{Name: name}, nil
+		}
+	}
+	a, err := scope.Get(context.Background(), "a", create("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := scope.Get(context.Background(), "a", create("a"))
+	if err != nil || again != a || creates != 1 {
+		t.Fatalf("second Get: %v, same instance %v, creates %d", err, again == a, creates)
+	}
+	if _, err := scope.Get(context.Background(), "b", create("b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := scope.End(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"stop b", "stop a"}; !reflect.DeepEqual(events, want) {
+		t.Fatalf("events %v, want %v", events, want)
+	}
+	if _, err := scope.Get(context.Background(), "a", create("a")); err == nil {
+		t.Fatal("expected an error after End")
+	}
+}
+
+// TestScopeGetRetries create或Start失败时不保存错误，下次Get重新创建
+func TestScopeGetRetries(t *testing.T) {
+	scope := NewScope()
+	failures := []error{errors.New("create failed"), nil, nil}
+	starts := []error{nil, errors.New("start failed"), nil}
+	calls := 0
+	create := func() (interface{}, Hook, error) {
+		call := calls
+		calls++
+		if failures[call] != nil {
+			return nil, Hook{}, failures[call]
+		}
+		return call, Hook{Start: func(ctx context.Context) error { return starts[call] }}, nil
+	}
+	for _, want := range []string{"create failed", "start failed", ""} {
+		instance, err := scope.Get(context.Background(), "a", create)
+		if want != "" {
+			if err == nil || err.Error() != want {
+				t.Fatalf("error %v, want %s", err, want)
+			}
+			continue
+		}
+		if err != nil || instance != 2 {
+			t.Fatalf("instance %v, error %v", instance, err)
+		}
+	}
+}
+
+// TestScopeEndDuringCreate 创建期间Scope结束时，新创建的组件立即停止
+func TestScopeEndDuringCreate(t *testing.T) {
+	scope := NewScope()
+	creating, ended := make(chan struct{}), make(chan struct{})
+	var stopped int32
+	done := make(chan error)
+	go func() {
+		_, err := scope.Get(context.Background(), "a", func() (interface{}, Hook, error) {
+			close(creating)
+			<-ended
+			return nil, Hook{Stop: func(ctx context.Context) error {
+				atomic.AddInt32(&stopped, 1)
+				return nil
+			}}, nil
+		})
+		done <- err
+	}()
+	<-creating
+	if err := scope.End(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	close(ended)
+	if err := <-done; err == nil || err.Error() != "scope has ended, cannot create a" {
+		t.Fatalf("error %v", err)
+	}
+	if atomic.LoadInt32(&stopped) != 1 {
+		t.Fatalf("stopped %d times", stopped)
+	}
+}
+
+// TestScopeGetEndRace 并发获取和结束时，每个启动的组件都恰好停止一次
+func TestScopeGetEndRace(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		scope := NewScope()
+		var started, stopped, creates int32
+		hook := Hook{
+			Start: func(ctx context.Context) error {
+				atomic.AddInt32(&started, 1)
+				return nil
+			},
+			Stop: func(ctx context.Context) error {
+				atomic.AddInt32(&stopped, 1)
+				return nil
+			},
+		}
+		var wg sync.WaitGroup
+		for j := 0; j < 8; j++ {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				scope.Get(context.Background(), name, func() (interface{}, Hook, error) {
+					atomic.AddInt32(&creates, 1)
+					return nil, hook, nil
+				})
+			}(string(rune('a' + j%4)))
+		}
+		if err := scope.End(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		wg.Wait()
+		if started > 4 || creates != started || stopped != started {
+			t.Fatalf("creates %d, started %d, stopped %d", creates, started, stopped)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package config_model
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 组件的作用域，用 @Scope= 注解指定，默认为ScopeSingleton
+const (
+	// ScopeSingleton 启动时创建一次，所有注入点共用
+	ScopeSingleton = "singleton"
+	// ScopePrototype 每个注入点、每次调用Container.NewXxx都创建新的实例
+	ScopePrototype = "prototype"
+	// ScopeRequest 每个绑定到context.Context的Scope中创建一次，Scope结束时停止
+	ScopeRequest = "request"
+)
+
+// Component @Component注解的结构体或构造函数。结构体组件按 &T{} 创建，再注入带有@Inject或@Value的字段；
+// 构造函数组件的所有参数都会被注入，返回 T、*T，也可以再返回一个error
+type Component struct {
+	// Name 组件名称，即生成的Container中的字段名，默认为类型名
+	Name string
+	// TypeName 组件的类型名，构造函数的返回值不是当前包中的具名类型时为空
+	TypeName    string
+	Pointer     bool
+	Constructor string
+	// Scope 作用域，ScopeSingleton、ScopePrototype或ScopeRequest
+	Scope string
+	// ReturnsError 构造函数的第二个返回值为error
+	ReturnsError bool
+	Package      string
+	ImportPath   string
+	Doc          string
+	Pos          token.Position
+	Params       []*Injection
+	Fields       []*Injection
+	// Named @Named("redis") 指定的名称，用于按名称注入和map注入，为空时使用Name
+	Named string
+	// Provides @Provides(Cache) 声明该组件还提供的类型，通常是它实现的接口
+	Provides []*Binding
+	// Conditions 条件注解，全部匹配时才创建该组件
+	Conditions []*Condition
+	// PostConstruct、PreDestroy 组件类型上带有对应注解的方法，由BindLifecycle设置
+	PostConstruct *LifecycleMethod
+	PreDestroy    *LifecycleMethod
+}
+
+// Binding @Provides中的一个类型，TypeKey为空表示无法解析
+type Binding struct {
+	Type    string
+	TypeKey string
+}
+
+// Injection 注入点，即构造函数的参数或结构体组件的字段；Value不为空时注入该配置key的值，否则按类型注入
+type Injection struct {
+	Name string
+	// Type 类型表达式的源码形式，TypeKey 带导入路径的形式，例如 *repo.UserRepo 和 *example/repo.UserRepo
+	Type    string
+	TypeKey string
+	Value   string
+	// Qualifier @Named或@Qualifier指定的组件名称，${cache.type} 形式表示按配置项的值选择
+	Qualifier string
+	// Package 注入点所在的包名
+	Package string
+	Pos     token.Position
+}
+
+// TypeRef 把注入的类型拆成 []、map[string]、* 组成的前缀和具名类型，用于在其他包中写出该类型
+func (i *Injection) TypeRef() (string, *Type) {
+	prefix, key, source := "", i.TypeKey, i.Type
+	for changed := true; changed; {
+		changed = false
+		for _, part := range []string{"[]", "map[string]", "*"} {
+			if strings.HasPrefix(key, part) && strings.HasPrefix(source, part) {
+				prefix, key, source = prefix+part, key[len(part):], source[len(part):]
+				changed = true
+			}
+		}
+	}
+	index := strings.LastIndex(key, ".")
+	if index == -1 {
+		return prefix, &Type{Name: key}
+	}
+	importPath := key[:index]
+	return prefix, &Type{Name: key[index+1:], Package: packageName(importPath), ImportPath: importPath}
+}
+
+// ConfigQualifier Qualifier为 ${key} 形式时返回配置key
+func (i *Injection) ConfigQualifier() (string, bool) {
+	if strings.HasPrefix(i.Qualifier, "${") && strings.HasSuffix(i.Qualifier, "}") {
+		return strings.TrimSpace(i.Qualifier[2 : len(i.Qualifier)-1]), true
+	}
+	return "", false
+}
+
+// LifecycleMethod 带有@PostConstruct或@PreDestroy的方法，可以接收context.Context并返回error；
+// Timeout为 @Timeout= 注解的值，例如 5s
+type LifecycleMethod struct {
+	Annotation   string
+	Receiver     string
+	ImportPath   string
+	Name         string
+	Context      bool
+	ReturnsError bool
+	Timeout      string
+	// Invalid 参数或返回值不符合要求
+	Invalid bool
+	Pos     token.Position
+}
+
+// lifecycleAnnotations 组件启动和停止时调用的方法的注解
+var lifecycleAnnotations = []string{"@PostConstruct", "@PreDestroy"}
+
+// lifecycleMethod 解析带有@PostConstruct或@PreDestroy的方法，不是这样的方法时返回nil
+func lifecycleMethod(fset *token.FileSet, pkgPath string, imports map[string]string, decl *ast.FuncDecl) *LifecycleMethod {
+	if decl.Doc == nil || decl.Recv == nil || len(decl.Recv.List) != 1 {
+		return nil
+	}
+	for _, annotation := range lifecycleAnnotations {
+		if !HasAnnotation(decl.Doc, annotation) {
+			continue
+		}
+		receiver, _ := StructRef(decl.Recv.List[0].Type)
+		method := &LifecycleMethod{
+			Annotation: annotation,
+			Receiver:   receiver,
+			ImportPath: pkgPath,
+			Name:       decl.Name.Name,
+			Timeout:    Annotation(decl.Doc, "@Timeout="),
+			Pos:        fset.Position(decl.Pos()),
+		}
+		params := decl.Type.Params
+		switch params.NumFields() {
+		case 0:
+		case 1:
+			method.Context = TypeKey(params.List[0].Type, pkgPath, imports) == "context.Context"
+			method.Invalid = !method.Context
+		default:
+			method.Invalid = true
+		}
+		if results := decl.Type.Results; results != nil {
+			ident, ok := results.List[0].Type.(*ast.Ident)
+			method.ReturnsError = results.NumFields() == 1 && ok && ident.Name == "error"
+			method.Invalid = method.Invalid || !method.ReturnsError
+		}
+		return method
+	}
+	return nil
+}
+
+// scope 注释中 @Scope= 的值，没有时为ScopeSingleton
+func scope(doc *ast.CommentGroup) string {
+	if value := Annotation(doc, "@Scope="); value != "" {
+		return value
+	}
+	return ScopeSingleton
+}
+
+// Qualifier 按名称注入和map注入时使用的名称
+func (c *Component) Qualifier() string {
+	if c.Named != "" {
+		return c.Named
+	}
+	return c.Name
+}
+
+// Keys 组件本身的类型和@Provides中的类型
+func (c *Component) Keys() []string {
+	keys := []string{c.Key()}
+	seen := map[string]bool{c.Key(): true}
+	for _, binding := range c.Provides {
+		if binding.TypeKey != "" && !seen[binding.TypeKey] {
+			seen[binding.TypeKey] = true
+			keys = append(keys, binding.TypeKey)
+		}
+	}
+	return keys
+}
+
+// Key 组件提供的类型，与Injection.TypeKey的形式相同
+func (c *Component) Key() string {
+	key := c.ImportPath + "." + c.TypeName
+	if c.Pointer {
+		return "*" + key
+	}
+	return key
+}
+
+// Injections 构造函数的参数和结构体组件的字段
+func (c *Component) Injections() []*Injection {
+	return append(append([]*Injection(nil), c.Params...), c.Fields...)
+}
+
+// TypeKey 类型在导入路径为pkgPath的包中的带导入路径形式，imports为文件中导入的包名到导入路径
+func TypeKey(expr ast.Expr, pkgPath string, imports map[string]string) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if predeclared[expr.Name] {
+			return expr.Name
+		}
+		return pkgPath + "." + expr.Name
+	case *ast.SelectorExpr:
+		if pkg, ok := expr.X.(*ast.Ident); ok {
+			if importPath, ok := imports[pkg.Name]; ok {
+				return importPath + "." + expr.Sel.Name
+			}
+		}
+	case *ast.StarExpr:
+		return "*" + TypeKey(expr.X, pkgPath, imports)
+	case *ast.ArrayType:
+		if expr.Len == nil {
+			return "[]" + TypeKey(expr.Elt, pkgPath, imports)
+		}
+		return "[" + ExprString(expr.Len) + "]" + TypeKey(expr.Elt, pkgPath, imports)
+	case *ast.MapType:
+		return "map[" + TypeKey(expr.Key, pkgPath, imports) + "]" + TypeKey(expr.Value, pkgPath, imports)
+	case *ast.ParenExpr:
+		return TypeKey(expr.X, pkgPath, imports)
+	}
+	return ExprString(expr)
+}
+
+var predeclared = map[string]bool{
+	"bool": true, "byte": true, "complex64": true, "complex128": true, "error": true, "float32": true, "float64": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true, "rune": true, "string": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+}
+
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// fileImports 文件中导入的包名到导入路径；没有别名时按导入路径的最后一段推断包名，忽略 /v2 形式的版本后缀
+func fileImports(file *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		if spec.Name != nil {
+			if spec.Name.Name != "_" && spec.Name.Name != "." {
+				imports[spec.Name.Name] = importPath
+			}
+			continue
+		}
+		imports[packageName(importPath)] = importPath
+	}
+	return imports
+}
+
+// packageName 按导入路径的最后一段推断包名，忽略 /v2 形式的版本后缀
+func packageName(importPath string) string {
+	name := path.Base(importPath)
+	if majorVersion.MatchString(name) && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+	return strings.Replace(name, "-", "_", -1)
+}
+
+var (
+	valuePattern    = regexp.MustCompile(`@Value\(\s*(?:(\w+)\s*=\s*)?"([^"]*)"\s*\)`)
+	namedPattern    = regexp.MustCompile(`@(?:Named|Qualifier)\(\s*(?:(\w+)\s*=\s*)?"([^"]*)"\s*\)`)
+	providesPattern = regexp.MustCompile(`@Provides\(([^)]*)\)`)
+)
+
+// valueAnnotations 注释中的 @Value("key") 和 @Value(name="key")，返回名称到配置key，没有名称时为空字符串
+func valueAnnotations(groups ...*ast.CommentGroup) map[string]string {
+	return annotationValues(valuePattern, groups...)
+}
+
+// namedAnnotations 注释中的 @Named("redis")、@Qualifier("redis") 和 @Named(name="redis")，返回名称到组件名称
+func namedAnnotations(groups ...*ast.CommentGroup) map[string]string {
+	return annotationValues(namedPattern, groups...)
+}
+
+func annotationValues(pattern *regexp.Regexp, groups ...*ast.CommentGroup) map[string]string {
+	values := make(map[string]string)
+	for _, doc := range groups {
+		if doc == nil {
+			continue
+		}
+		for _, comment := range doc.List {
+			for _, match := range pattern.FindAllStringSubmatch(comment.Text, -1) {
+				values[match[1]] = match[2]
+			}
+		}
+	}
+	return values
+}
+
+// provides 解析 @Provides(Cache, io.Closer)，类型按文件中的导入解析
+func provides(doc *ast.CommentGroup, pkgPath string, imports map[string]string) []*Binding {
+	bindings := make([]*Binding, 0)
+	if doc == nil {
+		return bindings
+	}
+	for _, comment := range doc.List {
+		for _, match := range providesPattern.FindAllStringSubmatch(comment.Text, -1) {
+			for _, item := range strings.Split(match[1], ",") {
+				binding := &Binding{Type: strings.TrimSpace(item)}
+				if expr, err := parser.ParseExpr(binding.Type); err == nil && binding.Type != "" {
+					binding.TypeKey = TypeKey(expr, pkgPath, imports)
+				}
+				bindings = append(bindings, binding)
+			}
+		}
+	}
+	return bindings
+}
+
+// structComponent 解析带有@Component的结构体，只有带@Inject或@Value的字段会被注入
+func structComponent(fset *token.FileSet, pkg, pkgPath string, imports map[string]string, spec *ast.TypeSpec, structType *ast.StructType, doc *ast.CommentGroup) *Component {
+	component := &Component{
+		Name:       spec.Name.Name,
+		TypeName:   spec.Name.Name,
+		Pointer:    true,
+		Scope:      scope(doc),
+		Named:      namedAnnotations(doc)[""],
+		Provides:   provides(doc, pkgPath, imports),
+		Conditions: conditions(fset, doc),
+		Package:    pkg,
+		ImportPath: pkgPath,
+		Doc:        DocText(doc),
+		Pos:        fset.Position(spec.Pos()),
+	}
+	for _, field := range structType.Fields.List {
+		value := valueAnnotations(field.Doc, field.Comment)[""]
+		qualifier := namedAnnotations(field.Doc, field.Comment)[""]
+		inject := value != "" || qualifier != ""
+		for _, doc := range []*ast.CommentGroup{field.Doc, field.Comment} {
+			inject = inject || doc != nil && HasAnnotation(doc, "@Inject")
+		}
+		if !inject {
+			continue
+		}
+		for _, name := range field.Names {
+			component.Fields = append(component.Fields, &Injection{
+				Name:      name.Name,
+				Type:      ExprString(field.Type),
+				TypeKey:   TypeKey(field.Type, pkgPath, imports),
+				Value:     value,
+				Qualifier: qualifier,
+				Package:   pkg,
+				Pos:       fset.Position(name.Pos()),
+			})
+		}
+	}
+	return component
+}
+
+// params 解析函数的参数，可以在函数注释中用 @Value(name="key") 绑定配置key，用 @Named(name="redis") 指定组件名称
+func params(fset *token.FileSet, pkg, pkgPath string, imports map[string]string, decl *ast.FuncDecl) []*Injection {
+	values, qualifiers := valueAnnotations(decl.Doc), namedAnnotations(decl.Doc)
+	injections := make([]*Injection, 0)
+	for i, param := range decl.Type.Params.List {
+		names := param.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{ast.NewIdent("arg" + strconv.Itoa(i))}
+		}
+		for _, name := range names {
+			injections = append(injections, &Injection{
+				Name:      name.Name,
+				Type:      ExprString(param.Type),
+				TypeKey:   TypeKey(param.Type, pkgPath, imports),
+				Value:     values[name.Name],
+				Qualifier: qualifiers[name.Name],
+				Package:   pkg,
+				Pos:       fset.Position(name.Pos()),
+			})
+		}
+	}
+	return injections
+}
+
+// constructorComponent 解析带有@Component的构造函数，参数可以用 @Value(name="key") 绑定配置key
+func constructorComponent(fset *token.FileSet, pkg, pkgPath string, imports map[string]string, decl *ast.FuncDecl) *Component {
+	component := &Component{
+		Name:        decl.Name.Name,
+		Constructor: decl.Name.Name,
+		Scope:       scope(decl.Doc),
+		Named:       namedAnnotations(decl.Doc)[""],
+		Provides:    provides(decl.Doc, pkgPath, imports),
+		Conditions:  conditions(fset, decl.Doc),
+		Package:     pkg,
+		ImportPath:  pkgPath,
+		Doc:         DocText(decl.Doc),
+		Pos:         fset.Position(decl.Pos()),
+	}
+	if results := decl.Type.Results; decl.Recv == nil && results != nil && len(results.List) <= 2 && results.NumFields() <= 2 {
+		name, pointer := StructRef(results.List[0].Type)
+		if _, ok := results.List[0].Type.(*ast.SelectorExpr); !ok && name != "" && !predeclared[name] && results.NumFields() == len(results.List) {
+			component.TypeName, component.Pointer = name, pointer
+			component.Name = name
+		}
+		if len(results.List) == 2 {
+			if ident, ok := results.List[1].Type.(*ast.Ident); ok && ident.Name == "error" {
+				component.ReturnsError = true
+			} else {
+				component.TypeName = ""
+			}
+		}
+	}
+	component.Params = params(fset, pkg, pkgPath, imports, decl)
+	return component
+}
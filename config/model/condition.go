@@ -0,0 +1,87 @@
+package config_model
+
+import (
+	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/source"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// 条件注解，组件和顶层配置节只在条件都匹配时启用
+const (
+	ConditionalOnProperty         = "@ConditionalOnProperty"
+	Profile                       = "@Profile"
+	ConditionalOnComponent        = "@ConditionalOnComponent"
+	ConditionalOnMissingComponent = "@ConditionalOnMissingComponent"
+)
+
+// Condition 组件或配置节上的条件注解，Argument为括号中的内容，例如 cache.enabled=true、prod、RedisCache
+type Condition struct {
+	Annotation string
+	Argument   string
+	Pos        token.Position
+}
+
+func (c *Condition) String() string {
+	return c.Annotation + "(" + c.Argument + ")"
+}
+
+// Property @ConditionalOnProperty的配置key和期望的值，只写key时value为空
+func (c *Condition) Property() (key, value string) {
+	if index := strings.Index(c.Argument, "="); index != -1 {
+		return strings.TrimSpace(c.Argument[:index]), strings.TrimSpace(c.Argument[index+1:])
+	}
+	return c.Argument, ""
+}
+
+var conditionPattern = regexp.MustCompile(`(@ConditionalOnProperty|@Profile|@ConditionalOnComponent|@ConditionalOnMissingComponent)\(([^)]*)\)`)
+
+// conditions 解析注释中的所有条件注解
+func conditions(fset *token.FileSet, doc *ast.CommentGroup) []*Condition {
+	if doc == nil {
+		return nil
+	}
+	result := make([]*Condition, 0)
+	for _, comment := range doc.List {
+		for _, match := range conditionPattern.FindAllStringSubmatch(comment.Text, -1) {
+			result = append(result, &Condition{Annotation: match[1], Argument: strings.TrimSpace(match[2]), Pos: fset.Position(comment.Pos())})
+		}
+	}
+	return result
+}
+
+// CheckConditions 检查owner上的条件：配置key存在（hasKey）、profile表达式可以解析、引用的组件存在；
+// components为nil时owner是配置节，配置节在创建组件之前判断，不能使用组件条件
+func CheckConditions(owner string, conditions []*Condition, hasKey func(key string) bool, components map[string]*Component) Diagnostics {
+	diagnostics := make(Diagnostics, 0)
+	for _, condition := range conditions {
+		message := ""
+		switch condition.Annotation {
+		case ConditionalOnProperty:
+			if key, _ := condition.Property(); key == "" {
+				message = "missing config key"
+			} else if !hasKey(key) {
+				message = "unknown config key " + key
+			}
+		case Profile:
+			if condition.Argument == "" {
+				message = "missing profile expression"
+			} else if _, err := config_source.MatchProfiles(condition.Argument, nil); err != nil {
+				message = err.Error()
+			}
+		default:
+			switch {
+			case components == nil:
+				message = "config sections can only use " + ConditionalOnProperty + " and " + Profile
+			case components[condition.Argument] == nil:
+				message = "no component named " + condition.Argument
+			}
+		}
+		if message != "" {
+			diagnostics = append(diagnostics, Diagnostic{Pos: condition.Pos, Message: fmt.Sprintf("%s %s: %s", owner, condition, message)})
+		}
+	}
+	return diagnostics
+}
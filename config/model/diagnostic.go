@@ -0,0 +1,36 @@
+package config_model
+
+import (
+	"go/token"
+	"sort"
+	"strings"
+)
+
+// Diagnostic 扫描时发现的问题，Pos为出问题的声明所在位置
+type Diagnostic struct {
+	Pos     token.Position
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return d.Pos.String() + ": " + d.Message
+}
+
+// Diagnostics 扫描时发现的所有问题，按文件和行号排序输出
+type Diagnostics []Diagnostic
+
+func (d Diagnostics) Error() string {
+	sorted := make(Diagnostics, len(d))
+	copy(sorted, d)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Pos.Filename != sorted[j].Pos.Filename {
+			return sorted[i].Pos.Filename < sorted[j].Pos.Filename
+		}
+		return sorted[i].Pos.Line < sorted[j].Pos.Line
+	})
+	lines := make([]string, 0, len(sorted))
+	for _, diagnostic := range sorted {
+		lines = append(lines, diagnostic.String())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,256 @@
+package config_model
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContextKey context.Context参数的TypeKey，@AutoExecute函数收到的是context.Background()
+const ContextKey = "context.Context"
+
+// Execute @AutoExecute函数或方法。方法的Receiver按类型注入，函数的参数与构造函数组件的参数相同；
+// Order越小越先调用，After中的函数调用之后才调用该函数
+type Execute struct {
+	// Name 函数名，方法为 Type.Method
+	Name         string
+	Function     string
+	Receiver     *Injection
+	Package      string
+	ImportPath   string
+	Params       []*Injection
+	ReturnsError bool
+	// Invalid 返回值不是空或error
+	Invalid bool
+	Order   int
+	After   []string
+	Pos     token.Position
+	// Problem @AutoExecute(...) 中无法解析的参数
+	Problem string
+}
+
+var executePattern = regexp.MustCompile(`@AutoExecute(?:\(([^)]*)\))?`)
+
+// autoExecute 解析带有@AutoExecute的函数或方法，注解中可以写 @AutoExecute(order=10, after=InitDb)
+func autoExecute(fset *token.FileSet, pkg, pkgPath string, imports map[string]string, decl *ast.FuncDecl) *Execute {
+	if decl.Doc == nil || !HasAnnotation(decl.Doc, "@AutoExecute") {
+		return nil
+	}
+	execute := &Execute{
+		Name:       decl.Name.Name,
+		Function:   decl.Name.Name,
+		Package:    pkg,
+		ImportPath: pkgPath,
+		Pos:        fset.Position(decl.Pos()),
+	}
+	if decl.Recv != nil && len(decl.Recv.List) == 1 {
+		receiver := decl.Recv.List[0]
+		name, _ := StructRef(receiver.Type)
+		execute.Name = name + "." + decl.Name.Name
+		execute.Receiver = &Injection{Name: "receiver", Type: ExprString(receiver.Type), TypeKey: TypeKey(receiver.Type, pkgPath, imports), Pos: fset.Position(receiver.Pos())}
+	}
+	for _, comment := range decl.Doc.List {
+		match := executePattern.FindStringSubmatch(comment.Text)
+		if match == nil {
+			continue
+		}
+		for _, option := range strings.Split(match[1], ",") {
+			option = strings.TrimSpace(option)
+			if option == "" {
+				continue
+			}
+			index := strings.Index(option, "=")
+			if index == -1 {
+				execute.Problem = fmt.Sprintf("invalid option %q, use order=N or after=Name", option)
+				continue
+			}
+			name, value := strings.TrimSpace(option[:index]), strings.TrimSpace(option[index+1:])
+			switch name {
+			case "order":
+				order, err := strconv.Atoi(value)
+				if err != nil {
+					execute.Problem = fmt.Sprintf("invalid order=%s", value)
+				}
+				execute.Order = order
+			case "after":
+				execute.After = append(execute.After, value)
+			default:
+				execute.Problem = fmt.Sprintf("unknown option %s", name)
+			}
+		}
+		break
+	}
+	execute.Params = params(fset, pkg, pkgPath, imports, decl)
+	if results := decl.Type.Results; results != nil {
+		ident, ok := results.List[0].Type.(*ast.Ident)
+		execute.ReturnsError = results.NumFields() == 1 && ok && ident.Name == "error"
+		execute.Invalid = !execute.ReturnsError
+	}
+	return execute
+}
+
+// receiverProvider 方法接收者的提供者，*T 和 T 都可以匹配，Go会自动取地址或解引用
+func (w *Wiring) receiverProvider(receiver *Injection) (*Provider, string) {
+	keys := []string{receiver.TypeKey, "*" + receiver.TypeKey}
+	if strings.HasPrefix(receiver.TypeKey, "*") {
+		keys[1] = receiver.TypeKey[1:]
+	}
+	for _, key := range keys {
+		providers := w.Providers[key]
+		if len(providers) == 1 {
+			return providers[0], ""
+		}
+		if len(providers) > 1 {
+			return nil, fmt.Sprintf("%s is provided by more than one component", receiver.Type)
+		}
+	}
+	return nil, fmt.Sprintf("no component provides %s", receiver.Type)
+}
+
+// ReceiverProvider 方法接收者的唯一提供者
+func (w *Wiring) ReceiverProvider(receiver *Injection) *Provider {
+	provider, _ := w.receiverProvider(receiver)
+	return provider
+}
+
+// PlanExecutes 检查并排序@AutoExecute。configPath包中的函数在加载配置后由Loader调用，只能注入配置，使用configWiring；
+// 其他包中的函数在创建所有组件后由Container调用，使用containerWiring，为nil时表示没有生成Container
+func PlanExecutes(executes []*Execute, configPath string, configWiring, containerWiring *Wiring, checkKey func(key string) error) (loader, container []*Execute, diagnostics Diagnostics) {
+	diagnostics = make(Diagnostics, 0)
+	for _, execute := range executes {
+		wiring := configWiring
+		if execute.ImportPath != configPath {
+			wiring = containerWiring
+		}
+		message := ""
+		switch {
+		case execute.Problem != "":
+			message = execute.Problem
+		case execute.Invalid:
+			message = "must return nothing or an error"
+		case wiring == nil:
+			message = "functions outside the config package need Generator.ContainerDir"
+		}
+		if message != "" {
+			diagnostics = append(diagnostics, Diagnostic{Pos: execute.Pos, Message: "@AutoExecute " + execute.Name + ": " + message})
+			continue
+		}
+		ok := true
+		if execute.Receiver != nil {
+			provider, message := wiring.receiverProvider(execute.Receiver)
+			if message == "" {
+				message = singletonOnly(provider)
+			}
+			if message != "" {
+				diagnostics = append(diagnostics, Diagnostic{Pos: execute.Receiver.Pos, Message: "@AutoExecute " + execute.Name + ": " + message})
+				ok = false
+			}
+		}
+		for _, param := range execute.Params {
+			if param.TypeKey == ContextKey && param.Value == "" {
+				continue
+			}
+			message := wiring.check(param, checkKey)
+			if resolution := wiring.Resolve(param); message == "" && resolution != nil && resolution.Kind != ResolveOne {
+				message = fmt.Sprintf("cannot inject %s, @AutoExecute parameters need exactly one provider", param.Type)
+			}
+			if message == "" && param.Value == "" {
+				message = singletonOnly(wiring.Provider(param))
+			}
+			if message != "" {
+				diagnostics = append(diagnostics, Diagnostic{Pos: param.Pos, Message: "@AutoExecute " + execute.Name + "." + param.Name + ": " + message})
+				ok = false
+			}
+		}
+		if !ok {
+			continue
+		}
+		if execute.ImportPath == configPath {
+			loader = append(loader, execute)
+		} else {
+			container = append(container, execute)
+		}
+	}
+	loader, loaderDiagnostics := orderExecutes(loader)
+	container, containerDiagnostics := orderExecutes(container)
+	diagnostics = append(append(diagnostics, loaderDiagnostics...), containerDiagnostics...)
+	return loader, container, diagnostics
+}
+
+// singletonOnly @AutoExecute只能注入singleton组件，其他作用域的组件返回错误信息
+func singletonOnly(provider *Provider) string {
+	if provider == nil || provider.Component == nil || provider.Component.Scope == ScopeSingleton {
+		return ""
+	}
+	return fmt.Sprintf("cannot inject %s scoped component %s", provider.Component.Scope, provider.Component.Name)
+}
+
+// orderExecutes 按order和声明位置排序，再保证after中的函数在前；after不存在或形成环时报告
+func orderExecutes(executes []*Execute) ([]*Execute, Diagnostics) {
+	sort.SliceStable(executes, func(i, j int) bool {
+		a, b := executes[i], executes[j]
+		if a.Order != b.Order {
+			return a.Order < b.Order
+		}
+		if a.Pos.Filename != b.Pos.Filename {
+			return a.Pos.Filename < b.Pos.Filename
+		}
+		return a.Pos.Line < b.Pos.Line
+	})
+	diagnostics := make(Diagnostics, 0)
+	byName := make(map[string]*Execute)
+	for _, execute := range executes {
+		if other, ok := byName[execute.Name]; ok {
+			diagnostics = append(diagnostics, Diagnostic{Pos: execute.Pos, Message: fmt.Sprintf("@AutoExecute %s is also declared at %s", execute.Name, other.Pos)})
+			continue
+		}
+		byName[execute.Name] = execute
+	}
+	ordered := make([]*Execute, 0, len(executes))
+	state := make(map[*Execute]int)
+	var visit func(execute *Execute, path []string) bool
+	visit = func(execute *Execute, path []string) bool {
+		switch state[execute] {
+		case 1:
+			start := 0
+			for path[start] != execute.Name {
+				start++
+			}
+			cycle := append(append([]string(nil), path[start:]...), execute.Name)
+			diagnostics = append(diagnostics, Diagnostic{Pos: execute.Pos, Message: "@AutoExecute after cycle: " + strings.Join(cycle, " -> ")})
+			return false
+		case 2:
+			return true
+		case 3:
+			return false
+		}
+		state[execute] = 1
+		ok := true
+		for _, after := range execute.After {
+			dependency, found := byName[after]
+			if !found {
+				diagnostics = append(diagnostics, Diagnostic{Pos: execute.Pos, Message: fmt.Sprintf("@AutoExecute %s: after=%s is not an @AutoExecute called at the same stage", execute.Name, after)})
+				ok = false
+				continue
+			}
+			ok = visit(dependency, append(path, execute.Name)) && ok
+		}
+		if !ok {
+			state[execute] = 3
+			return false
+		}
+		state[execute] = 2
+		ordered = append(ordered, execute)
+		return true
+	}
+	for _, execute := range executes {
+		if byName[execute.Name] == execute {
+			visit(execute, nil)
+		}
+	}
+	return ordered, diagnostics
+}
@@ -0,0 +1,147 @@
+package config_model
+
+import (
+	"go/ast"
+	"sort"
+)
+
+// Dependency 字段对另一个扫描到的结构体的引用，Container为引用所在的容器：
+// 空字符串表示直接引用，其余为 pointer、slice、array、map
+type Dependency struct {
+	From      string
+	To        string
+	Field     *Field
+	Container string
+}
+
+// Graph 结构体之间通过字段类型形成的依赖图
+type Graph struct {
+	Types map[string]*Type
+	Edges map[string][]Dependency
+}
+
+// NewGraph 根据字段的类型表达式建立依赖图，包括指针、切片、数组、map的元素和 pkg.Type 形式的类型
+func NewGraph(types map[string]*Type) *Graph {
+	graph := &Graph{Types: types, Edges: make(map[string][]Dependency)}
+	for name, typ := range types {
+		for _, field := range typ.Fields {
+			for _, ref := range TypeRefs(field.Expr) {
+				to := graph.resolve(ref.Name)
+				if to == "" {
+					continue
+				}
+				graph.Edges[name] = append(graph.Edges[name], Dependency{From: name, To: to, Field: field, Container: ref.Container})
+			}
+		}
+	}
+	return graph
+}
+
+// resolve 先按完整名称查找，pkg.Type 找不到时再按类型名查找
+func (g *Graph) resolve(name string) string {
+	if _, ok := g.Types[name]; ok {
+		return name
+	}
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '.' {
+			if _, ok := g.Types[name[i+1:]]; ok {
+				return name[i+1:]
+			}
+			break
+		}
+	}
+	return ""
+}
+
+// Contains from的某个字段是否引用了to
+func (g *Graph) Contains(from, to string) bool {
+	for _, dependency := range g.Edges[from] {
+		if dependency.To == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Roots 返回names中没有被names中其他类型引用的类型，按名称排序
+func (g *Graph) Roots(names []string) []string {
+	referenced := make(map[string]bool)
+	for _, from := range names {
+		for _, dependency := range g.Edges[from] {
+			if dependency.To != from {
+				referenced[dependency.To] = true
+			}
+		}
+	}
+	roots := make([]string, 0)
+	for _, name := range names {
+		if !referenced[name] {
+			roots = append(roots, name)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// Reachable 从root开始通过字段能够到达的所有类型（包括root），按名称排序
+func (g *Graph) Reachable(root string) []string {
+	visited := map[string]bool{root: true}
+	queue := []string{root}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, dependency := range g.Edges[name] {
+			if !visited[dependency.To] {
+				visited[dependency.To] = true
+				queue = append(queue, dependency.To)
+			}
+		}
+	}
+	names := make([]string, 0, len(visited))
+	for name := range visited {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TypeRef 类型表达式中引用的一个具名类型
+type TypeRef struct {
+	Name      string
+	Container string
+}
+
+// TypeRefs 返回类型表达式中引用的具名类型，例如 map[string][]*DBConfig 返回 DBConfig
+func TypeRefs(expr ast.Expr) []TypeRef {
+	refs := make([]TypeRef, 0)
+	collectRefs(expr, "", &refs)
+	return refs
+}
+
+func collectRefs(expr ast.Expr, container string, refs *[]TypeRef) {
+	outer := func(inner string) string {
+		if container != "" {
+			return container
+		}
+		return inner
+	}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		*refs = append(*refs, TypeRef{Name: expr.Name, Container: container})
+	case *ast.SelectorExpr:
+		*refs = append(*refs, TypeRef{Name: ExprString(expr), Container: container})
+	case *ast.StarExpr:
+		collectRefs(expr.X, outer("pointer"), refs)
+	case *ast.ArrayType:
+		if expr.Len == nil {
+			collectRefs(expr.Elt, outer("slice"), refs)
+		} else {
+			collectRefs(expr.Elt, outer("array"), refs)
+		}
+	case *ast.MapType:
+		collectRefs(expr.Key, outer("map"), refs)
+		collectRefs(expr.Value, outer("map"), refs)
+	case *ast.ParenExpr:
+		collectRefs(expr.X, container, refs)
+	}
+}
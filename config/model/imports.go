@@ -0,0 +1,79 @@
+package config_model
+
+import (
+	"sort"
+	"strconv"
+)
+
+// configPackageNames 生成的config包中已经声明或导入的名称，分配的包名不能与它们相同
+var configPackageNames = map[string]bool{
+	"config": true, "configStr": true, "configLock": true, "applicationConfig": true, "keyAliases": true, "configKeys": true,
+	"context": true, "fmt": true, "os": true, "sync": true, "utils": true, "config_source": true, "config_crypto": true,
+	"config_condition": true, "config_testing": true, "testOverride": true,
+	"testOverrides": true, "testBaseConfig": true,
+}
+
+// Qualifiers 为不在pkgPath中的类型所在的包分配引用时使用的名称，返回导入路径到包名的映射；
+// 包名相同时依次使用 name2、name3
+func Qualifiers(pkgPath string, types []*Type) map[string]string {
+	return QualifiersReserving(pkgPath, types, configPackageNames)
+}
+
+// QualifiersReserving 与Qualifiers相同，分配的包名不会与reserved中已经使用的名称相同
+func QualifiersReserving(pkgPath string, types []*Type, reserved map[string]bool) map[string]string {
+	packages := make(map[string]string)
+	for _, typ := range types {
+		if typ.ImportPath != "" && typ.ImportPath != pkgPath {
+			packages[typ.ImportPath] = typ.Package
+		}
+	}
+	paths := make([]string, 0, len(packages))
+	for importPath := range packages {
+		paths = append(paths, importPath)
+	}
+	sort.Strings(paths)
+	used := make(map[string]bool)
+	for name := range reserved {
+		used[name] = true
+	}
+	qualifiers := make(map[string]string)
+	for _, importPath := range paths {
+		name := packages[importPath]
+		for i := 2; used[name]; i++ {
+			name = packages[importPath] + strconv.Itoa(i)
+		}
+		used[name] = true
+		qualifiers[importPath] = name
+	}
+	return qualifiers
+}
+
+// ImportLines 返回qualifiers中各个包的import语句，分配的名称与包名不同时使用别名
+func ImportLines(qualifiers map[string]string, types []*Type) []string {
+	packages := make(map[string]string)
+	for _, typ := range types {
+		packages[typ.ImportPath] = typ.Package
+	}
+	paths := make([]string, 0, len(qualifiers))
+	for importPath := range qualifiers {
+		paths = append(paths, importPath)
+	}
+	sort.Strings(paths)
+	lines := make([]string, 0, len(paths))
+	for _, importPath := range paths {
+		if qualifiers[importPath] == packages[importPath] {
+			lines = append(lines, strconv.Quote(importPath))
+		} else {
+			lines = append(lines, qualifiers[importPath]+" "+strconv.Quote(importPath))
+		}
+	}
+	return lines
+}
+
+// QualifiedName 在其他包中引用该类型时使用的名称，例如 svc.DBConfig
+func (t *Type) QualifiedName(qualifiers map[string]string) string {
+	if name, ok := qualifiers[t.ImportPath]; ok {
+		return name + "." + t.Name
+	}
+	return t.Name
+}
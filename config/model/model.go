@@ -0,0 +1,422 @@
+package config_model
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DefaultTagKey 默认从yaml标签中读取配置项的名称
+const DefaultTagKey = "yaml"
+
+// Type 扫描到的结构体类型，Configuration表示带有@Configuration注解
+type Type struct {
+	Name          string
+	Alias         string
+	Package       string
+	Doc           string
+	Pos           token.Position
+	Configuration bool
+	// Fields 导出的字段，不包括标签为 "-" 的字段
+	Fields []*Field
+	// Default @DefaultConfig函数返回的字面量中各字段的默认值，key为字段名
+	Default map[string]*Value
+	// DefaultFunc 返回该类型默认值的@DefaultConfig函数名
+	DefaultFunc string
+	// Dir 和 ImportPath 类型所在包的目录和导入路径
+	Dir        string
+	ImportPath string
+	// Conditions @Configuration类型上的条件注解，只对顶层配置节有效
+	Conditions []*Condition
+}
+
+// Field 结构体字段，Type为字段类型表达式的源码形式，例如 []string、*DBConfig
+type Field struct {
+	Name string
+	// Key 配置项名称，字段注释中的 @Alias= 优先，其次是标签，最后是lowerCamel形式的字段名
+	Key  string
+	Type string
+	Expr ast.Expr
+	Tag  reflect.StructTag
+	Doc  string
+	Pos  token.Position
+	// Inline 标签带有inline选项，嵌套结构体的字段直接出现在当前层级
+	Inline bool
+	// Alternates 同样可以使用的其他名称，来自 @AlternateKey= 注解或alternate标签
+	Alternates []string
+	// DeprecatedKeys 仍然可以加载但会给出警告的旧名称，来自 @DeprecatedKey= 注解或deprecated标签
+	DeprecatedKeys []string
+}
+
+// FieldTag 配置标签的名称和选项，例如 yaml:"port,omitempty"
+type FieldTag struct {
+	Name      string
+	OmitEmpty bool
+	Inline    bool
+	// Skip 标签为 "-" 时字段不是配置项
+	Skip bool
+}
+
+// Value @DefaultConfig中的字面量，Literal为基本类型的值，Fields为嵌套结构体的值
+type Value struct {
+	Literal string
+	Fields  map[string]*Value
+}
+
+// File 一个go文件中扫描到的类型和@DefaultConfig函数
+type File struct {
+	Path       string
+	Package    string
+	ImportPath string
+	Types      []*Type
+	Defaults   map[string]*Value
+	// DefaultFuncs 类型名到@DefaultConfig函数名
+	DefaultFuncs map[string]string
+	// Components @Component注解的结构体和构造函数
+	Components []*Component
+	// Methods 带有@PostConstruct或@PreDestroy的方法
+	Methods []*LifecycleMethod
+	// Executes 带有@AutoExecute的函数和方法
+	Executes []*Execute
+}
+
+// ParseFile 使用go/parser解析文件中的结构体类型、注解、文档注释和@DefaultConfig默认值，
+// 配置项名称从tagKey标签中读取，tagKey为空时使用yaml
+func ParseFile(fset *token.FileSet, path, tagKey string) (*File, error) {
+	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	result := &File{
+		Path:         path,
+		Package:      file.Name.Name,
+		ImportPath:   ImportPath(filepath.Dir(path)),
+		Defaults:     make(map[string]*Value),
+		DefaultFuncs: make(map[string]string),
+	}
+	imports := fileImports(file)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			if decl.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range decl.Specs {
+				typeSpec := spec.(*ast.TypeSpec)
+				structType, ok := typeSpec.Type.(*ast.StructType)
+				if !ok {
+					continue
+				}
+				doc := typeSpec.Doc
+				if doc == nil && len(decl.Specs) == 1 {
+					doc = decl.Doc
+				}
+				result.Types = append(result.Types, parseType(fset, file.Name.Name, typeSpec, structType, doc, tagKey))
+				if doc != nil && HasAnnotation(doc, "@Component") {
+					result.Components = append(result.Components, structComponent(fset, file.Name.Name, result.ImportPath, imports, typeSpec, structType, doc))
+				}
+			}
+		case *ast.FuncDecl:
+			if decl.Doc != nil && HasAnnotation(decl.Doc, "@Component") {
+				result.Components = append(result.Components, constructorComponent(fset, file.Name.Name, result.ImportPath, imports, decl))
+			}
+			if method := lifecycleMethod(fset, result.ImportPath, imports, decl); method != nil {
+				result.Methods = append(result.Methods, method)
+			}
+			if execute := autoExecute(fset, file.Name.Name, result.ImportPath, imports, decl); execute != nil {
+				result.Executes = append(result.Executes, execute)
+			}
+			if decl.Doc == nil || !HasAnnotation(decl.Doc, "@DefaultConfig") {
+				continue
+			}
+			typeName, value := defaultValue(decl)
+			if value != nil {
+				result.Defaults[typeName] = value
+			}
+			if typeName != "" {
+				result.DefaultFuncs[typeName] = decl.Name.Name
+			}
+		}
+	}
+	for _, typ := range result.Types {
+		typ.Default = result.Defaults[typ.Name].fields()
+		typ.DefaultFunc = result.DefaultFuncs[typ.Name]
+		typ.Dir = filepath.Dir(path)
+		typ.ImportPath = result.ImportPath
+	}
+	return result, nil
+}
+
+func parseType(fset *token.FileSet, pkg string, spec *ast.TypeSpec, structType *ast.StructType, doc *ast.CommentGroup, tagKey string) *Type {
+	typ := &Type{
+		Name:    spec.Name.Name,
+		Package: pkg,
+		Doc:     DocText(doc),
+		Pos:     fset.Position(spec.Pos()),
+	}
+	if doc != nil && HasAnnotation(doc, "@Configuration") {
+		typ.Configuration = true
+		typ.Alias = Annotation(doc, "@Alias=")
+		typ.Conditions = conditions(fset, doc)
+	}
+	if typ.Alias == "" {
+		typ.Alias = LowerCamel(typ.Name)
+	}
+	for _, field := range structType.Fields.List {
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			if unquoted, err := strconv.Unquote(field.Tag.Value); err == nil {
+				tag = reflect.StructTag(unquoted)
+			}
+		}
+		doc := DocText(field.Doc)
+		if comment := DocText(field.Comment); comment != "" {
+			doc = strings.TrimSpace(doc + "\n" + comment)
+		}
+		fieldTag := ParseTag(tag, tagKey)
+		if fieldTag.Skip {
+			continue
+		}
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{ast.NewIdent(embeddedName(field.Type))}
+		}
+		for _, name := range names {
+			if !ast.IsExported(name.Name) {
+				continue
+			}
+			key := KeyOf(name.Name, tag, tagKey)
+			if alias := fieldAnnotation(field, "@Alias="); alias != "" {
+				key = alias
+			}
+			typ.Fields = append(typ.Fields, &Field{
+				Name:   name.Name,
+				Key:    key,
+				Type:   ExprString(field.Type),
+				Expr:   field.Type,
+				Tag:    tag,
+				Doc:    doc,
+				Pos:    fset.Position(name.Pos()),
+				Inline: fieldTag.Inline,
+
+				Alternates:     keyList(fieldAnnotation(field, "@AlternateKey="), tag.Get("alternate")),
+				DeprecatedKeys: keyList(fieldAnnotation(field, "@DeprecatedKey="), tag.Get("deprecated")),
+			})
+		}
+	}
+	return typ
+}
+
+// ParseTag 使用StructTag.Get读取tagKey标签的名称和omitempty、inline选项，tagKey为空时使用yaml
+func ParseTag(tag reflect.StructTag, tagKey string) FieldTag {
+	if tagKey == "" {
+		tagKey = DefaultTagKey
+	}
+	value := tag.Get(tagKey)
+	if value == "-" {
+		return FieldTag{Skip: true}
+	}
+	parts := strings.Split(value, ",")
+	result := FieldTag{Name: parts[0]}
+	for _, option := range parts[1:] {
+		switch option {
+		case "omitempty":
+			result.OmitEmpty = true
+		case "inline":
+			result.Inline = true
+		}
+	}
+	return result
+}
+
+// KeyOf 字段对应的配置key：tagKey标签的名称部分；没有标签或名称为空时使用lowerCamel形式的字段名，
+// 与@Alias的默认规则相同，例如 MaxConn 对应 maxConn
+func KeyOf(name string, tag reflect.StructTag, tagKey string) string {
+	if key := ParseTag(tag, tagKey).Name; key != "" {
+		return key
+	}
+	return LowerCamel(name)
+}
+
+// fieldAnnotation 字段上方或行尾注释中的注解值
+func fieldAnnotation(field *ast.Field, name string) string {
+	for _, doc := range []*ast.CommentGroup{field.Doc, field.Comment} {
+		if doc == nil {
+			continue
+		}
+		if value := Annotation(doc, name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// keyList 合并逗号分隔的名称列表
+func keyList(lists ...string) []string {
+	var keys []string
+	for _, list := range lists {
+		for _, key := range strings.Split(list, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// LowerCamel 把首字母改为小写，例如 ServerConfig 返回 serverConfig
+func LowerCamel(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[0:1]) + name[1:]
+}
+
+// StructRef 字段类型为具名类型或其指针时返回类型名，pkg.Type 返回Type
+func StructRef(expr ast.Expr) (name string, pointer bool) {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+		pointer = true
+	}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return expr.Name, pointer
+	case *ast.SelectorExpr:
+		return expr.Sel.Name, pointer
+	}
+	return "", false
+}
+
+// embeddedName 嵌入字段的字段名，即去掉指针和包名后的类型名
+func embeddedName(expr ast.Expr) string {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if selector, ok := expr.(*ast.SelectorExpr); ok {
+		return selector.Sel.Name
+	}
+	return ExprString(expr)
+}
+
+// ExprString 返回类型表达式的源码形式
+func ExprString(expr ast.Expr) string {
+	var builder strings.Builder
+	printer.Fprint(&builder, token.NewFileSet(), expr)
+	return builder.String()
+}
+
+// HasAnnotation 判断注释中是否有以annotation开头的注解，例如 //@Configuration
+func HasAnnotation(doc *ast.CommentGroup, annotation string) bool {
+	for _, comment := range doc.List {
+		text := strings.TrimSpace(strings.TrimPrefix(comment.Text, "//"))
+		if strings.HasPrefix(text, annotation) {
+			return true
+		}
+	}
+	return false
+}
+
+// Annotation 返回注释中 name 后面的值，例如 @Alias=server 返回 server
+func Annotation(doc *ast.CommentGroup, name string) string {
+	for _, comment := range doc.List {
+		index := strings.Index(comment.Text, name)
+		if index == -1 {
+			continue
+		}
+		if fields := strings.Fields(comment.Text[index+len(name):]); len(fields) > 0 {
+			return fields[0]
+		}
+		return ""
+	}
+	return ""
+}
+
+// DocText 返回去掉注解行之后的注释文本
+func DocText(doc *ast.CommentGroup) string {
+	if doc == nil {
+		return ""
+	}
+	lines := make([]string, 0)
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "@") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// defaultValue 解析 func GetDefaultXxx() Xxx { return Xxx{...} } 形式的默认值
+func defaultValue(decl *ast.FuncDecl) (string, *Value) {
+	if decl.Type.Results == nil || len(decl.Type.Results.List) != 1 || decl.Body == nil {
+		return "", nil
+	}
+	typeName := ExprString(decl.Type.Results.List[0].Type)
+	for _, stmt := range decl.Body.List {
+		ret, ok := stmt.(*ast.ReturnStmt)
+		if !ok || len(ret.Results) != 1 {
+			continue
+		}
+		if value := literalValue(ret.Results[0]); value != nil {
+			return typeName, value
+		}
+	}
+	return typeName, nil
+}
+
+func literalValue(expr ast.Expr) *Value {
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		if expr.Kind == token.STRING {
+			if unquoted, err := strconv.Unquote(expr.Value); err == nil {
+				return &Value{Literal: unquoted}
+			}
+		}
+		return &Value{Literal: expr.Value}
+	case *ast.Ident:
+		if expr.Name == "true" || expr.Name == "false" {
+			return &Value{Literal: expr.Name}
+		}
+	case *ast.UnaryExpr:
+		inner := literalValue(expr.X)
+		if inner == nil {
+			return nil
+		}
+		switch expr.Op {
+		case token.SUB:
+			return &Value{Literal: "-" + inner.Literal}
+		case token.AND:
+			return inner
+		}
+	case *ast.CompositeLit:
+		value := &Value{Fields: make(map[string]*Value)}
+		for _, element := range expr.Elts {
+			kv, ok := element.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			key, ok := kv.Key.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			if inner := literalValue(kv.Value); inner != nil {
+				value.Fields[key.Name] = inner
+			}
+		}
+		return value
+	}
+	return nil
+}
+
+func (v *Value) fields() map[string]*Value {
+	if v == nil {
+		return nil
+	}
+	return v.Fields
+}
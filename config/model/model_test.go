@@ -0,0 +1,143 @@
+package config_model
+
+import (
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// parseSource 把source写入临时目录后用ParseFile解析
+func parseSource(t *testing.T, source, tagKey string) *File {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "config-model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "types.go")
+	if err := ioutil.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := ParseFile(token.NewFileSet(), path, tagKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag    reflect.StructTag
+		tagKey string
+		want   FieldTag
+	}{
+		{``, "", FieldTag{}},
+		{`yaml:"port"`, "", FieldTag{Name: "port"}},
+		{`yaml:"port,omitempty"`, "", FieldTag{Name: "port", OmitEmpty: true}},
+		{`yaml:",inline"`, "", FieldTag{Inline: true}},
+		{`yaml:",omitempty,inline,flow"`, "", FieldTag{OmitEmpty: true, Inline: true}},
+		{`yaml:"-"`, "", FieldTag{Skip: true}},
+		{`yaml:"-,"`, "", FieldTag{Name: "-"}},
+		{`json:"port" yaml:"listen"`, "", FieldTag{Name: "listen"}},
+		{`json:"port,omitempty" yaml:"listen"`, "json", FieldTag{Name: "port", OmitEmpty: true}},
+		{`json:"-"`, "json", FieldTag{Skip: true}},
+		{`yaml:"-"`, "json", FieldTag{}},
+		{`validate:"required" yaml:"host"`, "", FieldTag{Name: "host"}},
+	}
+	for _, test := range tests {
+		if tag := ParseTag(test.tag, test.tagKey); tag != test.want {
+			t.Errorf("ParseTag(%s, %q) = %+v, want %+v", test.tag, test.tagKey, tag, test.want)
+		}
+	}
+}
+
+func TestKeyOf(t *testing.T) {
+	tests := []struct {
+		name   string
+		tag    reflect.StructTag
+		tagKey string
+		key    string
+	}{
+		{"MaxConn", ``, "", "maxConn"},
+		{"MaxConn", `yaml:"max_conn"`, "", "max_conn"},
+		{"MaxConn", `yaml:",omitempty"`, "", "maxConn"},
+		{"MaxConn", `json:"maxConnections"`, "", "maxConn"},
+		{"MaxConn", `json:"maxConnections"`, "json", "maxConnections"},
+	}
+	for _, test := range tests {
+		if key := KeyOf(test.name, test.tag, test.tagKey); key != test.key {
+			t.Errorf("KeyOf(%s, %s, %q) = %q, want %q", test.name, test.tag, test.tagKey, key, test.key)
+		}
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	const source = "package config\n\n" +
+		"type Common struct {\n\tRegion string\n}\n\n" +
+		"//@Configuration @Alias=server\ntype Server struct {\n" +
+		"\tHost string `yaml:\"host,omitempty\"`\n" +
+		"\tPort int\n" +
+		"\tSecret string `yaml:\"-\"`\n" +
+		"\tinternal string\n" +
+		"\tCommon `yaml:\",inline\"`\n" +
+		"\t//@Alias=maxConnections @DeprecatedKey=maxConn\n" +
+		"\tMaxConn int `yaml:\"max_conn\" alternate:\"max-conn, connections\"`\n" +
+		"\tTimeout int `yaml:\"timeout\" deprecated:\"wait\"` //@AlternateKey=deadline\n" +
+		"\tData string `json:\"payload\"`\n" +
+		"}\n"
+	type field struct {
+		Name           string
+		Key            string
+		Inline         bool
+		Alternates     []string
+		DeprecatedKeys []string
+	}
+	tests := []struct {
+		tagKey string
+		fields []field
+	}{
+		{
+			tagKey: "",
+			fields: []field{
+				{Name: "Host", Key: "host"},
+				{Name: "Port", Key: "port"},
+				{Name: "Common", Key: "common", Inline: true},
+				{Name: "MaxConn", Key: "maxConnections", Alternates: []string{"max-conn", "connections"}, DeprecatedKeys: []string{"maxConn"}},
+				{Name: "Timeout", Key: "timeout", Alternates: []string{"deadline"}, DeprecatedKeys: []string{"wait"}},
+				{Name: "Data", Key: "data"},
+			},
+		},
+		{
+			// 使用json标签时yaml:"-"不生效
+			tagKey: "json",
+			fields: []field{
+				{Name: "Host", Key: "host"},
+				{Name: "Port", Key: "port"},
+				{Name: "Secret", Key: "secret"},
+				{Name: "Common", Key: "common"},
+				{Name: "MaxConn", Key: "maxConnections", Alternates: []string{"max-conn", "connections"}, DeprecatedKeys: []string{"maxConn"}},
+				{Name: "Timeout", Key: "timeout", Alternates: []string{"deadline"}, DeprecatedKeys: []string{"wait"}},
+				{Name: "Data", Key: "payload"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run("tag "+test.tagKey, func(t *testing.T) {
+			file := parseSource(t, source, test.tagKey)
+			server := file.Types[1]
+			if server.Name != "Server" || server.Alias != "server" || !server.Configuration {
+				t.Fatalf("type %s alias %s configuration %v", server.Name, server.Alias, server.Configuration)
+			}
+			fields := make([]field, 0)
+			for _, f := range server.Fields {
+				fields = append(fields, field{f.Name, f.Key, f.Inline, f.Alternates, f.DeprecatedKeys})
+			}
+			if !reflect.DeepEqual(fields, test.fields) {
+				t.Fatalf("fields\n%+v\nwant\n%+v", fields, test.fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package config_model
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ScanOptions 扫描哪些go文件。Patterns为相对于Root的目录或go包模式，例如 ./config、./...、./internal/...；
+// Include和Exclude是相对于Root的路径或文件名的glob，也可以用 dir/... 表示目录下的所有文件
+type ScanOptions struct {
+	Root     string
+	Patterns []string
+	Include  []string
+	Exclude  []string
+	// BuildTags 额外启用的构建标签，文件的构建约束不满足时跳过该文件
+	BuildTags []string
+	// Tests 为true时包括 _test.go 文件
+	Tests bool
+	// Generated 为true时包括带有 Code generated ... DO NOT EDIT. 注释的文件
+	Generated bool
+}
+
+var generatedPattern = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// ScanFiles 按ScanOptions返回需要扫描的go文件，按路径排序
+func ScanFiles(options ScanOptions) ([]string, error) {
+	context := build.Default
+	context.BuildTags = append(append([]string(nil), context.BuildTags...), options.BuildTags...)
+	seen := make(map[string]bool)
+	files := make([]string, 0)
+	for _, pattern := range options.Patterns {
+		dirs, err := patternDirs(options.Root, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, dir := range dirs {
+			infos, err := ioutil.ReadDir(dir)
+			if err != nil {
+				return nil, err
+			}
+			for _, info := range infos {
+				file := filepath.Join(dir, info.Name())
+				if info.IsDir() || !strings.HasSuffix(info.Name(), ".go") || seen[file] {
+					continue
+				}
+				seen[file] = true
+				ok, err := options.match(&context, file)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					files = append(files, file)
+				}
+			}
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+func (o ScanOptions) match(context *build.Context, file string) (bool, error) {
+	name := filepath.Base(file)
+	if strings.HasSuffix(name, "_test.go") && !o.Tests {
+		return false, nil
+	}
+	rel := relativePath(o.Root, file)
+	if len(o.Include) > 0 && !matchAny(o.Include, rel) {
+		return false, nil
+	}
+	if matchAny(o.Exclude, rel) {
+		return false, nil
+	}
+	if ok, err := context.MatchFile(filepath.Dir(file), name); err != nil || !ok {
+		return false, err
+	}
+	if !o.Generated {
+		generated, err := isGenerated(file)
+		if err != nil || generated {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// patternDirs 把 ./... 形式的模式展开为目录；递归时与go命令一样跳过 . 和 _ 开头的目录、testdata、vendor以及其他module
+func patternDirs(root, pattern string) ([]string, error) {
+	recursive := false
+	if pattern == "..." || strings.HasSuffix(pattern, "/...") {
+		recursive = true
+		pattern = strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+	}
+	dir := filepath.FromSlash(pattern)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	if info, err := os.Stat(dir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	if !recursive {
+		return []string{dir}, nil
+	}
+	dirs := make([]string, 0)
+	err := filepath.Walk(dir, func(current string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if current != dir {
+			name := info.Name()
+			if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(current, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		dirs = append(dirs, current)
+		return nil
+	})
+	return dirs, err
+}
+
+func relativePath(root, file string) string {
+	if rel, err := filepath.Rel(root, file); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(file)
+}
+
+// matchAny rel或文件名是否匹配某个glob，dir/... 匹配目录下的所有文件
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+		if strings.HasSuffix(pattern, "/...") && strings.HasPrefix(rel, strings.TrimSuffix(pattern, "...")) {
+			return true
+		}
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isGenerated 文件在package语句之前是否有 // Code generated ... DO NOT EDIT. 注释
+func isGenerated(file string) (bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "package ") {
+			return false, nil
+		}
+		if generatedPattern.MatchString(line) {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// ImportPath 根据上层目录中的go.mod计算目录的导入路径，找不到go.mod时返回空字符串
+func ImportPath(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for current := dir; ; current = filepath.Dir(current) {
+		if module := modulePath(filepath.Join(current, "go.mod")); module != "" {
+			rel, err := filepath.Rel(current, dir)
+			if err != nil || rel == "." {
+				return module
+			}
+			return module + "/" + filepath.ToSlash(rel)
+		}
+		if filepath.Dir(current) == current {
+			return ""
+		}
+	}
+}
+
+func modulePath(goMod string) string {
+	data, err := ioutil.ReadFile(goMod)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "module ") {
+			return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "module ")), `"`)
+		}
+	}
+	return ""
+}
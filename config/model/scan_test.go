@@ -0,0 +1,89 @@
+package config_model
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestScanFiles(t *testing.T) {
+	root := filepath.Join("testdata", "scan")
+	tests := []struct {
+		name    string
+		options ScanOptions
+		files   []string
+	}{
+		{
+			name:    "directory skips tests, generated files and other build constraints",
+			options: ScanOptions{Patterns: []string{"./config"}},
+			files:   []string{"config/local.go", "config/mock_gen.go", "config/server.go"},
+		},
+		{
+			name:    "build tags",
+			options: ScanOptions{Patterns: []string{"./config"}, BuildTags: []string{"integration"}},
+			files:   []string{"config/integration.go", "config/mock_gen.go", "config/server.go"},
+		},
+		{
+			name:    "tests and generated files",
+			options: ScanOptions{Patterns: []string{"./config"}, Tests: true, Generated: true},
+			files:   []string{"config/generated.go", "config/local.go", "config/mock_gen.go", "config/server.go", "config/server_test.go"},
+		},
+		{
+			name:    "exclude file name glob",
+			options: ScanOptions{Patterns: []string{"./config"}, Exclude: []string{"*_gen.go"}},
+			files:   []string{"config/local.go", "config/server.go"},
+		},
+		{
+			name:    "include path glob",
+			options: ScanOptions{Patterns: []string{"./config"}, Include: []string{"config/s*.go"}, Tests: true},
+			files:   []string{"config/server.go", "config/server_test.go"},
+		},
+		{
+			// 与go命令相同，跳过 . 和 _ 开头的目录、testdata、vendor以及其他module
+			name:    "recursive pattern",
+			options: ScanOptions{Patterns: []string{"./..."}, Exclude: []string{"config/..."}},
+			files:   []string{"internal/app/app.go", "tools/tool.go"},
+		},
+		{
+			name:    "recursive pattern below a directory",
+			options: ScanOptions{Patterns: []string{"./internal/..."}},
+			files:   []string{"internal/app/app.go"},
+		},
+		{
+			name:    "exclude directory",
+			options: ScanOptions{Patterns: []string{"./...", "./config"}, Exclude: []string{"./tools/...", "config/mock_gen.go", "local.go"}},
+			files:   []string{"config/server.go", "internal/app/app.go"},
+		},
+		{
+			name:    "explicit directory is not skipped",
+			options: ScanOptions{Patterns: []string{"./nested", "./internal/_skip"}},
+			files:   []string{"internal/_skip/skip.go", "nested/nested.go"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			options := test.options
+			options.Root = root
+			files, err := ScanFiles(options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rel := make([]string, 0, len(files))
+			for _, file := range files {
+				rel = append(rel, relativePath(root, file))
+			}
+			if !reflect.DeepEqual(rel, test.files) {
+				t.Fatalf("files %v, want %v", rel, test.files)
+			}
+		})
+	}
+}
+
+func TestScanFilesErrors(t *testing.T) {
+	root := filepath.Join("testdata", "scan")
+	for _, pattern := range []string{"./missing", "./config/server.go"} {
+		if files, err := ScanFiles(ScanOptions{Root: root, Patterns: []string{pattern}}); err == nil {
+			t.Errorf("pattern %s: expected an error, got %v", pattern, files)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build integration
+// +build integration
+
+package config
+
+type Integration struct {
+	URL string
+}
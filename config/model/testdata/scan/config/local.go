@@ -0,0 +1,8 @@
+//go:build !integration
+// +build !integration
+
+package config
+
+type Local struct {
+	Path string
+}
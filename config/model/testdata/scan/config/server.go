@@ -0,0 +1,5 @@
+package config
+
+type Server struct {
+	Port int
+}
@@ -0,0 +1,5 @@
+package config
+
+import "testing"
+
+func TestServer(t *testing.T) {}
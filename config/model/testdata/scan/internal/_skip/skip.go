@@ -0,0 +1,3 @@
+package skip
+
+type Skip struct{}
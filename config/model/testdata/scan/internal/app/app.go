@@ -0,0 +1,3 @@
+package app
+
+type App struct{}
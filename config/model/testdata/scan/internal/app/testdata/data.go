@@ -0,0 +1,3 @@
+package testdata
+
+type Data struct{}
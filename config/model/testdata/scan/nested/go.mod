@@ -0,0 +1,3 @@
+module example/nested
+
+go 1.16
@@ -0,0 +1,3 @@
+package tools
+
+type Tool struct{}
@@ -0,0 +1,84 @@
+package config_model
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Rule validate标签中的一条校验规则，例如 min=1 的Name为min、Param为1
+type Rule struct {
+	Name  string
+	Param string
+}
+
+// Rules 解析 validate:"required,min=1,max=65535,oneof=debug info" 形式的标签
+func Rules(tag reflect.StructTag) []Rule {
+	rules := make([]Rule, 0)
+	for _, item := range strings.Split(tag.Get("validate"), ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		rule := Rule{Name: item}
+		if index := strings.Index(item, "="); index != -1 {
+			rule = Rule{Name: item[:index], Param: item[index+1:]}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// Required 字段是否带有required规则
+func (f *Field) Required() bool {
+	for _, rule := range Rules(f.Tag) {
+		if rule.Name == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// Enum oneof规则允许的取值
+func (f *Field) Enum() []string {
+	for _, rule := range Rules(f.Tag) {
+		if rule.Name == "oneof" {
+			return strings.Fields(rule.Param)
+		}
+	}
+	return nil
+}
+
+// Deprecated 注释中 Deprecated: 后面的说明，没有废弃时返回空字符串
+func (f *Field) Deprecated() string {
+	for _, line := range strings.Split(f.Doc, "\n") {
+		if strings.HasPrefix(line, "Deprecated:") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "Deprecated:"))
+		}
+	}
+	return ""
+}
+
+// Secret 字段是否为敏感信息：带有 secret:"true" 标签，或字段名包含password、secret、token
+func (f *Field) Secret() bool {
+	if secret, ok := f.Tag.Lookup("secret"); ok {
+		return secret == "true"
+	}
+	name := strings.ToLower(f.Name)
+	for _, word := range []string{"password", "secret", "token"} {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultValue 字段的默认值，@DefaultConfig中的字面量优先，其次是default标签
+func (t *Type) DefaultValue(field *Field) (*Value, bool) {
+	if value, ok := t.Default[field.Name]; ok {
+		return value, true
+	}
+	if value, ok := field.Tag.Lookup("default"); ok {
+		return &Value{Literal: value}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,85 @@
+package config_model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		tag   reflect.StructTag
+		rules []Rule
+	}{
+		{``, []Rule{}},
+		{`validate:"required"`, []Rule{{Name: "required"}}},
+		{`validate:"required, min=1,max=65535"`, []Rule{{Name: "required"}, {Name: "min", Param: "1"}, {Name: "max", Param: "65535"}}},
+		{`validate:"oneof=debug info,,"`, []Rule{{Name: "oneof", Param: "debug info"}}},
+		{`validate:"eq=a=b"`, []Rule{{Name: "eq", Param: "a=b"}}},
+		{`yaml:"port" json:"validate"`, []Rule{}},
+	}
+	for _, test := range tests {
+		if rules := Rules(test.tag); !reflect.DeepEqual(rules, test.rules) {
+			t.Errorf("Rules(%s) = %v, want %v", test.tag, rules, test.rules)
+		}
+	}
+}
+
+func TestFieldRules(t *testing.T) {
+	tests := []struct {
+		tag      reflect.StructTag
+		required bool
+		enum     []string
+	}{
+		{`validate:"required,oneof=debug info"`, true, []string{"debug", "info"}},
+		{`validate:"required_if=Mode debug"`, false, nil},
+		{`validate:"min=1"`, false, nil},
+	}
+	for _, test := range tests {
+		field := &Field{Tag: test.tag}
+		if field.Required() != test.required || !reflect.DeepEqual(field.Enum(), test.enum) {
+			t.Errorf("%s: Required %v, Enum %v, want %v, %v", test.tag, field.Required(), field.Enum(), test.required, test.enum)
+		}
+	}
+}
+
+func TestSecret(t *testing.T) {
+	tests := []struct {
+		name   string
+		tag    reflect.StructTag
+		secret bool
+	}{
+		{"Password", ``, true},
+		{"DBPassword", ``, true},
+		{"ClientSecret", ``, true},
+		{"AccessToken", ``, true},
+		{"Host", ``, false},
+		{"APIKey", `secret:"true"`, true},
+		{"Token", `secret:"false"`, false},
+		{"Host", `secret:"yes"`, false},
+	}
+	for _, test := range tests {
+		field := &Field{Name: test.name, Tag: test.tag}
+		if secret := field.Secret(); secret != test.secret {
+			t.Errorf("%s %s: Secret() = %v, want %v", test.name, test.tag, secret, test.secret)
+		}
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	tests := []struct {
+		doc        string
+		deprecated string
+	}{
+		{"", ""},
+		{"Workers 工作协程数", ""},
+		{"Workers 工作协程数\nDeprecated: use pool.size", "use pool.size"},
+		{"Deprecated:", ""},
+		{"Workers are not Deprecated: here", ""},
+	}
+	for _, test := range tests {
+		field := &Field{Doc: test.doc}
+		if deprecated := field.Deprecated(); deprecated != test.deprecated {
+			t.Errorf("Deprecated() of %q = %q, want %q", test.doc, deprecated, test.deprecated)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package config_model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Provider 提供某个类型的组件或配置节；Section为ApplicationConfig中配置节的字段名，
+// Component和Section都为空时表示ApplicationConfig本身
+type Provider struct {
+	Component *Component
+	Section   string
+}
+
+func (p *Provider) String() string {
+	switch {
+	case p.Component != nil:
+		return fmt.Sprintf("component %s (%s)", p.Component.Name, p.Component.Pos)
+	case p.Section != "":
+		return "config section " + p.Section
+	}
+	return "ApplicationConfig"
+}
+
+// Wiring 校验后的组件依赖关系，Order为按依赖排序的组件，被依赖的在前；
+// @ConditionalOnComponent等条件引用的组件也排在前面
+type Wiring struct {
+	Providers map[string][]*Provider
+	Order     []*Component
+	// Components 组件名称到组件
+	Components map[string]*Component
+}
+
+// 注入点的解析方式
+const (
+	// ResolveOne 唯一的提供者，或者@Named指定名称的组件
+	ResolveOne = "one"
+	// ResolveSlice、ResolveMap 注入 []T、map[string]T 时收集提供T的所有组件，map的key为组件的Qualifier
+	ResolveSlice = "slice"
+	ResolveMap   = "map"
+	// ResolveConfig @Named("${cache.type}") 按配置项的值选择Qualifier相同的组件
+	ResolveConfig = "config"
+	// ResolveCondition 提供同一个类型的组件都带有条件时，选择唯一启用的组件
+	ResolveCondition = "condition"
+)
+
+// Resolution 注入点的提供者，Key为ResolveConfig时选择组件的配置key
+type Resolution struct {
+	Kind      string
+	Providers []*Provider
+	Key       string
+}
+
+// Provider 注入点对应的唯一提供者，没有、有多个或需要在启动时选择时返回nil
+func (w *Wiring) Provider(injection *Injection) *Provider {
+	if resolution := w.Resolve(injection); resolution != nil && resolution.Kind == ResolveOne {
+		return resolution.Providers[0]
+	}
+	return nil
+}
+
+// Resolve 注入点的提供者，@Value注入点或者无法解析时返回nil
+func (w *Wiring) Resolve(injection *Injection) *Resolution {
+	if injection.Value != "" {
+		return nil
+	}
+	resolution, _ := w.resolve(injection)
+	return resolution
+}
+
+func (w *Wiring) resolve(injection *Injection) (*Resolution, string) {
+	providers := w.Providers[injection.TypeKey]
+	if key, ok := injection.ConfigQualifier(); ok {
+		candidates := componentProviders(providers)
+		switch {
+		case key == "":
+			return nil, fmt.Sprintf("@Named(%q) has no config key", injection.Qualifier)
+		case len(candidates) == 0:
+			return nil, fmt.Sprintf("no component provides %s", injection.Type)
+		}
+		return &Resolution{Kind: ResolveConfig, Providers: candidates, Key: key}, duplicateQualifiers(candidates)
+	}
+	if injection.Qualifier != "" {
+		for _, provider := range componentProviders(providers) {
+			if provider.Component.Qualifier() == injection.Qualifier {
+				return &Resolution{Kind: ResolveOne, Providers: []*Provider{provider}}, ""
+			}
+		}
+		return nil, fmt.Sprintf("no component named %q provides %s", injection.Qualifier, injection.Type)
+	}
+	switch len(providers) {
+	case 0:
+		for kind, prefix := range map[string]string{ResolveSlice: "[]", ResolveMap: "map[string]"} {
+			if !strings.HasPrefix(injection.TypeKey, prefix) {
+				continue
+			}
+			if elements := componentProviders(w.Providers[injection.TypeKey[len(prefix):]]); len(elements) > 0 {
+				message := ""
+				if kind == ResolveMap {
+					message = duplicateQualifiers(elements)
+				}
+				return &Resolution{Kind: kind, Providers: elements}, message
+			}
+		}
+		return nil, fmt.Sprintf("no component provides %s", injection.Type)
+	case 1:
+		return &Resolution{Kind: ResolveOne, Providers: providers}, ""
+	}
+	conditional := true
+	items := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		conditional = conditional && provider.Component != nil && len(provider.Component.Conditions) > 0
+		items = append(items, provider.String())
+	}
+	if conditional {
+		return &Resolution{Kind: ResolveCondition, Providers: providers}, ""
+	}
+	return nil, fmt.Sprintf("%s is provided by more than one component: %s; use @Named to choose one", injection.Type, strings.Join(items, ", "))
+}
+
+// componentProviders 提供者中的组件，不包括配置节
+func componentProviders(providers []*Provider) []*Provider {
+	components := make([]*Provider, 0, len(providers))
+	for _, provider := range providers {
+		if provider.Component != nil {
+			components = append(components, provider)
+		}
+	}
+	return components
+}
+
+// duplicateQualifiers 按名称选择或注入map时，名称相同的组件无法区分
+func duplicateQualifiers(providers []*Provider) string {
+	names := make(map[string]*Component)
+	for _, provider := range providers {
+		name := provider.Component.Qualifier()
+		if other, ok := names[name]; ok {
+			return fmt.Sprintf("components %s and %s are both named %q", other.Name, provider.Component.Name, name)
+		}
+		names[name] = provider.Component
+	}
+	return ""
+}
+
+// Wire 在生成代码之前检查组件：构造函数的返回值、@Scope、重名的组件、缺少或有多个提供者的依赖、依赖环，
+// 以及@Value中不存在的配置key（checkKey返回错误）；没有组件提供的context.Context注入request组件所在Scope的ctx，
+// 其他组件收到context.Background()；sections为配置节类型到提供者的映射，
+// 一次返回所有问题
+func Wire(components []*Component, sections map[string]*Provider, checkKey func(key string) error) (*Wiring, Diagnostics) {
+	components = append([]*Component(nil), components...)
+	sort.SliceStable(components, func(i, j int) bool {
+		return components[i].Name < components[j].Name
+	})
+	wiring := &Wiring{Providers: make(map[string][]*Provider), Components: make(map[string]*Component)}
+	for key, provider := range sections {
+		wiring.Providers[key] = append(wiring.Providers[key], provider)
+	}
+	diagnostics := make(Diagnostics, 0)
+	names := wiring.Components
+	valid := make([]*Component, 0, len(components))
+	for _, component := range components {
+		if component.TypeName == "" {
+			diagnostics = append(diagnostics, Diagnostic{Pos: component.Pos, Message: fmt.Sprintf("@Component %s must return T or *T of its own package, optionally followed by error", component.Constructor)})
+			continue
+		}
+		if other, ok := names[component.Name]; ok {
+			diagnostics = append(diagnostics, Diagnostic{Pos: component.Pos, Message: fmt.Sprintf("component %s is also declared at %s", component.Name, other.Pos)})
+			continue
+		}
+		names[component.Name] = component
+		for _, key := range component.Keys() {
+			wiring.Providers[key] = append(wiring.Providers[key], &Provider{Component: component})
+		}
+		for _, binding := range component.Provides {
+			if binding.TypeKey == "" {
+				diagnostics = append(diagnostics, Diagnostic{Pos: component.Pos, Message: fmt.Sprintf("component %s: invalid @Provides(%s)", component.Name, binding.Type)})
+			}
+		}
+		valid = append(valid, component)
+	}
+	for _, component := range valid {
+		if message := checkScope(component); message != "" {
+			diagnostics = append(diagnostics, Diagnostic{Pos: component.Pos, Message: "component " + component.Name + ": " + message})
+		}
+		for _, injection := range component.Injections() {
+			if injection.TypeKey == ContextKey && injection.Value == "" && len(wiring.Providers[ContextKey]) == 0 {
+				continue
+			}
+			message := wiring.check(injection, checkKey)
+			if provider := wiring.Provider(injection); message == "" && provider != nil && provider.Component != nil &&
+				provider.Component.Scope == ScopeRequest && component.Scope != ScopeRequest {
+				message = fmt.Sprintf("%s component %s cannot depend on request scoped %s, which exists only inside a scope", component.Scope, component.Name, provider.Component.Name)
+			}
+			if message != "" {
+				diagnostics = append(diagnostics, Diagnostic{Pos: injection.Pos, Message: component.Name + "." + injection.Name + ": " + message})
+			}
+		}
+	}
+	order, cycles := wiring.order(valid)
+	wiring.Order = order
+	return wiring, append(diagnostics, cycles...)
+}
+
+// checkScope 检查@Scope的值；prototype和request组件由Container的方法返回，必须是指针
+func checkScope(component *Component) string {
+	switch component.Scope {
+	case ScopeSingleton:
+		return ""
+	case ScopePrototype, ScopeRequest:
+		if !component.Pointer {
+			return fmt.Sprintf("%s scoped components must be pointers", component.Scope)
+		}
+		return ""
+	}
+	return fmt.Sprintf("unknown @Scope=%s, use %s, %s or %s", component.Scope, ScopeSingleton, ScopePrototype, ScopeRequest)
+}
+
+// check 检查注入点，返回问题的说明；启动时选择或收集的组件必须是singleton
+func (w *Wiring) check(injection *Injection, checkKey func(key string) error) string {
+	if injection.Value != "" {
+		if err := checkKey(injection.Value); err != nil {
+			return fmt.Sprintf("@Value(%q): %v", injection.Value, err)
+		}
+		return ""
+	}
+	resolution, message := w.resolve(injection)
+	if message != "" {
+		return message
+	}
+	if resolution.Kind == ResolveConfig {
+		if err := checkKey(resolution.Key); err != nil {
+			return fmt.Sprintf("@Named(%q): %v", injection.Qualifier, err)
+		}
+	}
+	if resolution.Kind != ResolveOne {
+		for _, provider := range resolution.Providers {
+			if provider.Component.Scope != ScopeSingleton {
+				return fmt.Sprintf("%s scoped component %s cannot be selected or collected, only singletons can", provider.Component.Scope, provider.Component.Name)
+			}
+		}
+	}
+	return ""
+}
+
+// CheckConditions 检查所有组件上的条件注解，hasKey判断@ConditionalOnProperty的配置key是否存在
+func (w *Wiring) CheckConditions(hasKey func(key string) bool) Diagnostics {
+	diagnostics := make(Diagnostics, 0)
+	for _, component := range w.Order {
+		diagnostics = append(diagnostics, CheckConditions("component "+component.Name, component.Conditions, hasKey, w.Components)...)
+	}
+	return diagnostics
+}
+
+// Dependencies 组件一定会注入的其他组件，不包括启动时选择或收集的组件
+func (w *Wiring) Dependencies(component *Component) []*Component {
+	dependencies := make([]*Component, 0)
+	for _, injection := range component.Injections() {
+		if injection.Value != "" {
+			continue
+		}
+		if provider := w.Provider(injection); provider != nil && provider.Component != nil {
+			dependencies = append(dependencies, provider.Component)
+		}
+	}
+	return dependencies
+}
+
+// dependencies 注入时可能用到的组件和条件引用的组件，它们需要先创建或先判断
+func (w *Wiring) dependencies(component *Component) []*Component {
+	dependencies := make([]*Component, 0)
+	for _, injection := range component.Injections() {
+		if resolution := w.Resolve(injection); resolution != nil {
+			for _, provider := range componentProviders(resolution.Providers) {
+				dependencies = append(dependencies, provider.Component)
+			}
+		}
+	}
+	for _, condition := range component.Conditions {
+		if other := w.Components[condition.Argument]; other != nil && (condition.Annotation == ConditionalOnComponent || condition.Annotation == ConditionalOnMissingComponent) {
+			dependencies = append(dependencies, other)
+		}
+	}
+	return dependencies
+}
+
+// order 深度优先排序组件，每个依赖环报告一次并给出完整路径，环上的组件不会出现在结果中
+func (w *Wiring) order(components []*Component) ([]*Component, Diagnostics) {
+	ordered := make([]*Component, 0, len(components))
+	diagnostics := make(Diagnostics, 0)
+	state := make(map[*Component]int)
+	reported := make(map[string]bool)
+	var visit func(component *Component, path []*Component) bool
+	visit = func(component *Component, path []*Component) bool {
+		switch state[component] {
+		case 1:
+			start := 0
+			for path[start] != component {
+				start++
+			}
+			names := make([]string, 0, len(path)-start+1)
+			for _, item := range path[start:] {
+				names = append(names, item.Name)
+			}
+			key := append([]string(nil), names...)
+			sort.Strings(key)
+			if !reported[strings.Join(key, ",")] {
+				reported[strings.Join(key, ",")] = true
+				diagnostics = append(diagnostics, Diagnostic{Pos: component.Pos, Message: "dependency cycle: " + strings.Join(append(names, component.Name), " -> ")})
+			}
+			return false
+		case 2:
+			return true
+		case 3:
+			return false
+		}
+		state[component] = 1
+		ok := true
+		for _, dependency := range w.dependencies(component) {
+			ok = visit(dependency, append(path, component)) && ok
+		}
+		if !ok {
+			state[component] = 3
+			return false
+		}
+		state[component] = 2
+		ordered = append(ordered, component)
+		return true
+	}
+	for _, component := range components {
+		visit(component, nil)
+	}
+	return ordered, diagnostics
+}
+
+// BindLifecycle 把@PostConstruct和@PreDestroy方法设置到对应的组件上，
+// 返回不在组件上、参数或返回值不符合要求、重复以及@Timeout无法解析的方法
+func BindLifecycle(components []*Component, methods []*LifecycleMethod) Diagnostics {
+	byType := make(map[string]*Component)
+	for _, component := range components {
+		if component.TypeName != "" {
+			byType[component.ImportPath+"."+component.TypeName] = component
+		}
+	}
+	diagnostics := make(Diagnostics, 0)
+	for _, method := range methods {
+		component := byType[method.ImportPath+"."+method.Receiver]
+		if component == nil {
+			diagnostics = append(diagnostics, Diagnostic{Pos: method.Pos, Message: fmt.Sprintf("%s %s.%s: %s is not a @Component", method.Annotation, method.Receiver, method.Name, method.Receiver)})
+			continue
+		}
+		if method.Invalid {
+			diagnostics = append(diagnostics, Diagnostic{Pos: method.Pos, Message: fmt.Sprintf("%s %s.%s must take no arguments or a context.Context and return nothing or an error", method.Annotation, method.Receiver, method.Name)})
+			continue
+		}
+		if method.Timeout != "" {
+			if _, err := time.ParseDuration(method.Timeout); err != nil {
+				diagnostics = append(diagnostics, Diagnostic{Pos: method.Pos, Message: fmt.Sprintf("%s %s.%s: invalid @Timeout=%s", method.Annotation, method.Receiver, method.Name, method.Timeout)})
+				continue
+			}
+		}
+		target := &component.PostConstruct
+		if method.Annotation == "@PreDestroy" {
+			target = &component.PreDestroy
+		}
+		if *target != nil {
+			diagnostics = append(diagnostics, Diagnostic{Pos: method.Pos, Message: fmt.Sprintf("%s %s.%s: %s already has %s %s", method.Annotation, method.Receiver, method.Name, component.Name, method.Annotation, (*target).Name)})
+			continue
+		}
+		*target = method
+	}
+	return diagnostics
+}
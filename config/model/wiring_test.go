@@ -0,0 +1,265 @@
+package config_model
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// wire 解析source中的组件后调用Wire，只接受配置key server.port 和 cache.type
+func wire(t *testing.T, source string) (*Wiring, Diagnostics) {
+	t.Helper()
+	file := parseSource(t, "package app\n\nimport \"context\"\n\nvar _ context.Context\n\n"+source, "")
+	checkKey := func(key string) error {
+		if key != "server.port" && key != "cache.type" {
+			return fmt.Errorf("unknown config key %s", key)
+		}
+		return nil
+	}
+	return Wire(file.Components, nil, checkKey)
+}
+
+// resolutions 每个注入点的解析结果，形式为 kind: 组件名称，按配置项选择时后面加上配置key，无法解析时为空字符串
+func resolutions(wiring *Wiring) map[string]string {
+	result := make(map[string]string)
+	for _, component := range wiring.Order {
+		for _, injection := range component.Injections() {
+			resolution := wiring.Resolve(injection)
+			if resolution == nil {
+				result[component.Name+"."+injection.Name] = ""
+				continue
+			}
+			names := make([]string, 0, len(resolution.Providers))
+			for _, provider := range resolution.Providers {
+				names = append(names, provider.Component.Name)
+			}
+			result[component.Name+"."+injection.Name] = resolution.Kind + ": " + strings.Join(names, ", ")
+			if resolution.Key != "" {
+				result[component.Name+"."+injection.Name] += " by " + resolution.Key
+			}
+		}
+	}
+	return result
+}
+
+func TestWire(t *testing.T) {
+	const store = "type Store interface{}\n\n" +
+		"//@Component @Provides(Store)\ntype Memory struct{}\n\n" +
+		"//@Component @Provides(Store)\ntype Redis struct{}\n\n"
+	tests := []wireTest{
+		{
+			name: "constructor and struct components in dependency order",
+			source: "//@Component\nfunc NewRepo() (*Repo, error) { return &Repo{}, nil }\n\ntype Repo struct{}\n\n" +
+				"//@Component\ntype Service struct {\n\t//@Inject\n\tRepo *Repo\n\t//@Value(\"server.port\")\n\tPort int\n\tplain int\n}\n",
+			order:       []string{"Repo", "Service"},
+			resolutions: map[string]string{"Service.Repo": "one: Repo", "Service.Port": ""},
+		},
+		{
+			name:     "missing provider",
+			source:   "type Repo struct{}\n\n//@Component\nfunc NewService(repo *Repo) *Service { return nil }\n\ntype Service struct{}\n",
+			messages: []string{"Service.repo: no component provides *Repo"},
+			order:    []string{"Service"},
+		},
+		{
+			name:   "ambiguous provider",
+			source: store + "//@Component\nfunc NewService(store Store) *Service { return nil }\n\ntype Service struct{}\n",
+			messages: []string{"Service.store: Store is provided by more than one component: " +
+				"component Memory (%s:10:6), component Redis (%s:13:6); use @Named to choose one"},
+			order: []string{"Memory", "Redis", "Service"},
+		},
+		{
+			name: "cycle",
+			source: "//@Component\ntype A struct {\n\t//@Inject\n\tB *B\n}\n\n" +
+				"//@Component\ntype B struct {\n\t//@Inject\n\tC *C\n}\n\n" +
+				"//@Component\ntype C struct {\n\t//@Inject\n\tA *A\n}\n\n" +
+				"//@Component\ntype D struct {\n\t//@Inject\n\tA *A\n}\n\n" +
+				"//@Component\ntype E struct{}\n",
+			messages: []string{"dependency cycle: A -> B -> C -> A"},
+			order:    []string{"E"},
+		},
+		{
+			name:     "self dependency",
+			source:   "//@Component\nfunc NewA(a *A) *A { return a }\n\ntype A struct{}\n",
+			messages: []string{"dependency cycle: A -> A"},
+			order:    []string{},
+		},
+		{
+			name:        "slice and map injection",
+			source:      store + "//@Component\nfunc NewService(all []Store, byName map[string]Store) *Service { return nil }\n\ntype Service struct{}\n",
+			order:       []string{"Memory", "Redis", "Service"},
+			resolutions: map[string]string{"Service.all": "slice: Memory, Redis", "Service.byName": "map: Memory, Redis"},
+		},
+		{
+			name:     "slice of prototype components",
+			source:   "type Store interface{}\n\n//@Component @Provides(Store) @Scope=prototype\ntype Memory struct{}\n\n//@Component\nfunc NewService(all []Store) *Service { return nil }\n\ntype Service struct{}\n",
+			messages: []string{"Service.all: prototype scoped component Memory cannot be selected or collected, only singletons can"},
+			order:    []string{"Memory", "Service"},
+		},
+		{
+			name:     "slice without providers",
+			source:   "type Store interface{}\n\n//@Component\nfunc NewService(all []Store) *Service { return nil }\n\ntype Service struct{}\n",
+			messages: []string{"Service.all: no component provides []Store"},
+			order:    []string{"Service"},
+		},
+		{
+			name:        "context without provider",
+			source:      "//@Component\nfunc NewService(ctx context.Context) *Service { return nil }\n\ntype Service struct{}\n",
+			order:       []string{"Service"},
+			resolutions: map[string]string{"Service.ctx": ""},
+		},
+		{
+			name: "unknown @Value key and invalid components",
+			source: "//@Component\ntype Service struct {\n\t//@Value(\"server.host\")\n\tHost string\n}\n\n" +
+				"//@Component\nfunc NewName() string { return \"\" }\n\n" +
+				"//@Component @Scope=session\ntype Session struct{}\n\n" +
+				"//@Component @Scope=request\ntype Request struct{}\n\n" +
+				"//@Component\ntype Handler struct {\n\t//@Inject\n\tRequest *Request\n}\n",
+			messages: []string{
+				"@Component NewName must return T or *T of its own package, optionally followed by error",
+				"Handler.Request: singleton component Handler cannot depend on request scoped Request, which exists only inside a scope",
+				"Service.Host: @Value(\"server.host\"): unknown config key server.host",
+				"component Session: unknown @Scope=session, use singleton, prototype or request",
+			},
+			order: []string{"Request", "Handler", "Service", "Session"},
+		},
+	}
+	runWireTests(t, tests)
+}
+
+func TestWireQualifiers(t *testing.T) {
+	const store = "type Store interface{}\n\n" +
+		"//@Component @Named(\"redis\") @Provides(Store)\ntype RedisStore struct{}\n\n" +
+		"//@Component @Provides(Store)\n//@Qualifier(\"memory\")\nfunc NewMemoryStore() *MemoryStore { return nil }\n\ntype MemoryStore struct{}\n\n" +
+		"//@Component @Provides(Store)\ntype FileStore struct{}\n\n"
+	service := func(fields string) string {
+		return store + "//@Component\ntype Service struct {\n" + fields + "}\n"
+	}
+	order := []string{"FileStore", "MemoryStore", "RedisStore", "Service"}
+	tests := []wireTest{
+		{
+			name: "named field, qualifier field and default name",
+			source: service("\t//@Named(\"redis\")\n\tRedis Store\n\t//@Qualifier(\"memory\")\n\tMemory Store\n" +
+				"\t//@Named(\"FileStore\")\n\tFile Store\n\t//@Named(\"redis\")\n\tConcrete *RedisStore\n"),
+			order: order,
+			resolutions: map[string]string{
+				"Service.Redis":    "one: RedisStore",
+				"Service.Memory":   "one: MemoryStore",
+				"Service.File":     "one: FileStore",
+				"Service.Concrete": "one: RedisStore",
+			},
+		},
+		{
+			name: "named constructor parameters",
+			source: store + "//@Component\n//@Named(primary=\"redis\") @Qualifier(fallback=\"memory\")\n" +
+				"func NewService(primary, fallback Store) *Service { return nil }\n\ntype Service struct{}\n",
+			order:       order,
+			resolutions: map[string]string{"Service.primary": "one: RedisStore", "Service.fallback": "one: MemoryStore"},
+		},
+		{
+			name:     "ambiguous without a name",
+			source:   service("\t//@Inject\n\tStore Store\n"),
+			messages: []string{"Service.Store: Store is provided by more than one component: component FileStore (%s:19:6), component MemoryStore (%s:14:1), component RedisStore (%s:10:6); use @Named to choose one"},
+			order:    order,
+		},
+		{
+			name:     "unknown name",
+			source:   service("\t//@Named(\"RedisStore\")\n\tStore Store\n"),
+			messages: []string{"Service.Store: no component named \"RedisStore\" provides Store"},
+			order:    order,
+		},
+		{
+			name:   "slice and map by name",
+			source: service("\t//@Inject\n\tAll []Store\n\t//@Inject\n\tByName map[string]Store\n"),
+			order:  order,
+			resolutions: map[string]string{
+				"Service.All":    "slice: FileStore, MemoryStore, RedisStore",
+				"Service.ByName": "map: FileStore, MemoryStore, RedisStore",
+			},
+		},
+		{
+			name:        "named by config value",
+			source:      service("\t//@Named(\"${cache.type}\")\n\tStore Store\n"),
+			order:       order,
+			resolutions: map[string]string{"Service.Store": "config: FileStore, MemoryStore, RedisStore by cache.type"},
+		},
+		{
+			name:     "named by unknown config key",
+			source:   service("\t//@Named(\"${cache.kind}\")\n\tStore Store\n\t//@Named(\"${ }\")\n\tOther Store\n"),
+			messages: []string{"Service.Store: @Named(\"${cache.kind}\"): unknown config key cache.kind", "Service.Other: @Named(\"${ }\") has no config key"},
+			order:    order,
+		},
+		{
+			name: "duplicate names in a map and a config choice",
+			source: "type Store interface{}\n\n" +
+				"//@Component @Named(\"redis\") @Provides(Store)\ntype RedisStore struct{}\n\n" +
+				"//@Component @Named(\"redis\") @Provides(Store)\ntype RedisCluster struct{}\n\n" +
+				"//@Component\ntype Service struct {\n\t//@Inject\n\tByName map[string]Store\n\t//@Named(\"${cache.type}\")\n\tStore Store\n\t//@Inject\n\tAll []Store\n}\n",
+			messages: []string{
+				"Service.ByName: components RedisCluster and RedisStore are both named \"redis\"",
+				"Service.Store: components RedisCluster and RedisStore are both named \"redis\"",
+			},
+			order:       []string{"RedisCluster", "RedisStore", "Service"},
+			resolutions: map[string]string{"Service.All": "slice: RedisCluster, RedisStore"},
+		},
+		{
+			name: "conditional providers",
+			source: "type Store interface{}\n\n" +
+				"//@Component @Provides(Store) @ConditionalOnProperty(cache.type=redis)\ntype RedisStore struct{}\n\n" +
+				"//@Component @Provides(Store) @ConditionalOnMissingComponent(RedisStore)\ntype MemoryStore struct{}\n\n" +
+				"//@Component\nfunc NewService(store Store) *Service { return nil }\n\ntype Service struct{}\n",
+			order:       []string{"RedisStore", "MemoryStore", "Service"},
+			resolutions: map[string]string{"Service.store": "condition: MemoryStore, RedisStore"},
+		},
+		{
+			name:     "invalid provides",
+			source:   "//@Component @Provides(Store, [)\ntype RedisStore struct{}\n",
+			messages: []string{"component RedisStore: invalid @Provides([)"},
+			order:    []string{"RedisStore"},
+		},
+	}
+	runWireTests(t, tests)
+}
+
+type wireTest struct {
+	name        string
+	source      string
+	messages    []string
+	order       []string
+	resolutions map[string]string
+}
+
+func runWireTests(t *testing.T, tests []wireTest) {
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wiring, diagnostics := wire(t, test.source)
+			messages := make([]string, 0, len(diagnostics))
+			path := ""
+			for _, diagnostic := range diagnostics {
+				messages = append(messages, diagnostic.Message)
+				path = diagnostic.Pos.Filename
+			}
+			for i := range test.messages {
+				test.messages[i] = strings.Replace(test.messages[i], "%s", path, -1)
+			}
+			if test.messages == nil {
+				test.messages = []string{}
+			}
+			if !reflect.DeepEqual(messages, test.messages) {
+				t.Errorf("messages\n%q\nwant\n%q", messages, test.messages)
+			}
+			order := make([]string, 0, len(wiring.Order))
+			for _, component := range wiring.Order {
+				order = append(order, component.Name)
+			}
+			if !reflect.DeepEqual(order, test.order) {
+				t.Errorf("order %v, want %v", order, test.order)
+			}
+			for key, want := range test.resolutions {
+				if got := resolutions(wiring)[key]; got != want {
+					t.Errorf("%s resolved to %q, want %q", key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package config_source
+
+import (
+	"fmt"
+	"sort"
+)
+
+// KeyAlias 配置项的其他名称对应的key；Deprecated为true时表示旧名称，加载时会给出警告
+type KeyAlias struct {
+	Key        string
+	Deprecated bool
+}
+
+// LegacyKey 配置来源中使用了其他名称的配置项；Ignored 表示同一来源中也设置了新名称，旧名称的值没有使用
+type LegacyKey struct {
+	Source      string
+	Pos         Position
+	Key         string
+	Replacement string
+	Deprecated  bool
+	Ignored     bool
+}
+
+// String 返回 file:line:col: config key old is deprecated, use new 形式的提示
+func (k LegacyKey) String() string {
+	where := k.Source
+	if k.Pos.IsValid() {
+		where = k.Pos.String()
+	}
+	if k.Ignored {
+		return fmt.Sprintf("%s: config key %s is ignored because %s is also set", where, k.Key, k.Replacement)
+	}
+	if k.Deprecated {
+		return fmt.Sprintf("%s: config key %s is deprecated, use %s", where, k.Key, k.Replacement)
+	}
+	return fmt.Sprintf("%s: config key %s is an alias of %s", where, k.Key, k.Replacement)
+}
+
+// RenameKeys 把其他名称的key改为配置项的key并返回用到的其他名称，按key排序；
+// aliases的key可以是叶子配置项，也可以是嵌套结构体，此时下面的所有key都会改名。
+// 新旧名称同时出现时使用新名称的值，多个旧名称对应同一个配置项时使用排序在前的，其余的标记为Ignored
+func RenameKeys(source Source, values map[string]string, aliases map[string]KeyAlias) (map[string]string, []LegacyKey) {
+	if len(aliases) == 0 {
+		return values, nil
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make(map[string]string, len(values))
+	legacy := make([]LegacyKey, 0)
+	for _, key := range keys {
+		replacement, deprecated, ok := resolveAlias(key, aliases)
+		if !ok {
+			result[key] = values[key]
+			continue
+		}
+		legacyKey := LegacyKey{Source: source.Name(), Key: key, Replacement: replacement, Deprecated: deprecated}
+		if locator, ok := source.(Locator); ok {
+			legacyKey.Pos, _ = locator.Position(key)
+		}
+		legacy = append(legacy, legacyKey)
+	}
+	for i := range legacy {
+		if _, ok := result[legacy[i].Replacement]; ok {
+			legacy[i].Ignored = true
+			continue
+		}
+		result[legacy[i].Replacement] = values[legacy[i].Key]
+	}
+	return result, legacy
+}
+
+// resolveAlias 依次替换key中的其他名称，直到不再匹配，例如旧的结构体名称下又使用了字段的旧名称
+func resolveAlias(key string, aliases map[string]KeyAlias) (string, bool, bool) {
+	deprecated, found := false, false
+	for i := 0; i <= len(aliases); i++ {
+		name, alias, ok := matchAlias(key, aliases)
+		if !ok {
+			break
+		}
+		key = alias.Key + key[len(name):]
+		deprecated = deprecated || alias.Deprecated
+		found = true
+	}
+	return key, deprecated, found
+}
+
+// matchAlias 查找key本身或它的上层（在 . 或 [ 处截断）对应的其他名称，越长的匹配越优先
+func matchAlias(key string, aliases map[string]KeyAlias) (string, KeyAlias, bool) {
+	for end := len(key); end > 0; end-- {
+		if end < len(key) && key[end] != '.' && key[end] != '[' {
+			continue
+		}
+		if alias, ok := aliases[key[:end]]; ok {
+			return key[:end], alias, true
+		}
+	}
+	return "", KeyAlias{}, false
+}
@@ -0,0 +1,103 @@
+package config_source
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRenameKeys(t *testing.T) {
+	aliases := map[string]KeyAlias{
+		"server.max_conn":  {Key: "server.maxConnections"},
+		"server.maxConn":   {Key: "server.maxConnections", Deprecated: true},
+		"server.database":  {Key: "server.db", Deprecated: true},
+		"server.db.pwd":    {Key: "server.db.password"},
+		"server.endpoints": {Key: "server.urls"},
+	}
+	tests := []struct {
+		name    string
+		content string
+		values  map[string]string
+		legacy  []string
+	}{
+		{
+			name:    "no alias",
+			content: "server:\n  maxConnections: 1\n",
+			values:  map[string]string{"server.maxConnections": "1"},
+			legacy:  []string{},
+		},
+		{
+			name:    "alternate name",
+			content: "server:\n  max_conn: 2\n",
+			values:  map[string]string{"server.maxConnections": "2"},
+			legacy:  []string{"text:app:2:13: config key server.max_conn is an alias of server.maxConnections"},
+		},
+		{
+			name:    "deprecated name",
+			content: "server:\n  maxConn: 3\n",
+			values:  map[string]string{"server.maxConnections": "3"},
+			legacy:  []string{"text:app:2:12: config key server.maxConn is deprecated, use server.maxConnections"},
+		},
+		{
+			name:    "deprecated section and alternate field below it",
+			content: "server:\n  database:\n    url: mysql://db\n    pwd: secret\n",
+			values:  map[string]string{"server.db.url": "mysql://db", "server.db.password": "secret"},
+			legacy: []string{
+				"text:app:4:10: config key server.database.pwd is deprecated, use server.db.password",
+				"text:app:3:10: config key server.database.url is deprecated, use server.db.url",
+			},
+		},
+		{
+			name:    "list elements",
+			content: "server:\n  endpoints: [a, b]\n",
+			values:  map[string]string{"server.urls[0]": "a", "server.urls[1]": "b"},
+			legacy: []string{
+				"text:app:2:15: config key server.endpoints[0] is an alias of server.urls[0]",
+				"text:app:2:18: config key server.endpoints[1] is an alias of server.urls[1]",
+			},
+		},
+		{
+			name:    "old and new name both set",
+			content: "server:\n  maxConnections: 1\n  maxConn: 3\n",
+			values:  map[string]string{"server.maxConnections": "1"},
+			legacy:  []string{"text:app:3:12: config key server.maxConn is ignored because server.maxConnections is also set"},
+		},
+		{
+			name:    "two old names",
+			content: "server:\n  maxConn: 3\n  max_conn: 2\n",
+			values:  map[string]string{"server.maxConnections": "3"},
+			legacy: []string{
+				"text:app:2:12: config key server.maxConn is deprecated, use server.maxConnections",
+				"text:app:3:13: config key server.max_conn is ignored because server.maxConnections is also set",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			source := &Text{ID: "app", Content: test.content}
+			values, err := source.Load(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			values, legacy := RenameKeys(source, values, aliases)
+			if !reflect.DeepEqual(values, test.values) {
+				t.Errorf("values %v, want %v", values, test.values)
+			}
+			messages := make([]string, 0)
+			for _, key := range legacy {
+				messages = append(messages, key.String())
+			}
+			if !reflect.DeepEqual(messages, test.legacy) {
+				t.Errorf("legacy keys\n%v\nwant\n%v", messages, test.legacy)
+			}
+		})
+	}
+}
+
+func TestRenameKeysWithoutAliases(t *testing.T) {
+	values := map[string]string{"server.maxConn": "3"}
+	renamed, legacy := RenameKeys(&Text{}, values, nil)
+	if !reflect.DeepEqual(renamed, values) || legacy != nil {
+		t.Fatalf("values %v, legacy %v", renamed, legacy)
+	}
+}
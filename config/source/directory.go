@@ -0,0 +1,124 @@
+package config_source
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Directory 每个文件一个key的配置目录，例如Kubernetes挂载的ConfigMap和Secret：
+// server.port 文件或 server/port 文件都对应 server.port，文件内容末尾的换行会被去掉；
+// 以 . 开头的文件（包括Kubernetes的 ..data 和时间戳目录）会被跳过
+type Directory struct {
+	Path string
+	// Prefix 加在所有key前面，例如挂载的Secret只对应 db 下的配置时设置为 db
+	Prefix string
+	// Optional 为true时目录不存在不算错误
+	Optional bool
+	// Interval Watch轮询目录变化的间隔，默认2s
+	Interval time.Duration
+}
+
+func (d *Directory) Name() string {
+	return "dir:" + d.Path
+}
+
+func (d *Directory) Load(ctx context.Context) (map[string]string, error) {
+	values := make(map[string]string)
+	if _, err := os.Stat(d.Path); err != nil {
+		if d.Optional && os.IsNotExist(err) {
+			return values, nil
+		}
+		return nil, err
+	}
+	err := d.walk(d.Path, d.Prefix, func(path, key string, info os.FileInfo) error {
+		by, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		values[key] = strings.TrimRight(string(by), "\r\n")
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// Watch 轮询 ..data 链接的指向以及文件的修改时间，Kubernetes原子替换配置时只会触发一次
+func (d *Directory) Watch(ctx context.Context, onChange func()) error {
+	interval := d.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	last := d.signature()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			current := d.signature()
+			if current != last {
+				last = current
+				onChange()
+			}
+		}
+	}
+}
+
+// signature ..data 的指向和所有文件的修改时间；遍历期间 ..data 被替换时重新计算，
+// 避免得到新旧混合的结果导致一次替换触发两次变化
+func (d *Directory) signature() string {
+	data := filepath.Join(d.Path, "..data")
+	for {
+		target, _ := os.Readlink(data)
+		items := []string{"..data->" + target}
+		d.walk(d.Path, d.Prefix, func(path, key string, info os.FileInfo) error {
+			items = append(items, fmt.Sprintf("%s:%d:%d", key, info.ModTime().UnixNano(), info.Size()))
+			return nil
+		})
+		if current, _ := os.Readlink(data); current == target {
+			sort.Strings(items)
+			return strings.Join(items, "\n")
+		}
+	}
+}
+
+// walk 遍历目录并跟随符号链接，子目录名作为key的一级
+func (d *Directory) walk(dir, prefix string, visit func(path, key string, info os.FileInfo) error) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		key := joinKey(prefix, entry.Name())
+		if info.IsDir() {
+			if err := d.walk(path, key, visit); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := visit(path, key, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package config_source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "directory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestDirectoryLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		directory Directory
+		values    map[string]string
+	}{
+		{
+			name:   "files and sub directories",
+			files:  map[string]string{"server.port": "8080\n", "db/url": "mysql://db\r\n", "db/pool/size": "10"},
+			values: map[string]string{"server.port": "8080", "db.url": "mysql://db", "db.pool.size": "10"},
+		},
+		{
+			// 按文件名顺序读取，server目录在 server.port 文件之前，后读取的文件覆盖前面的值
+			name:   "dotted file after directory",
+			files:  map[string]string{"server/port": "1", "server.port": "2"},
+			values: map[string]string{"server.port": "2"},
+		},
+		{
+			name:   "hidden files",
+			files:  map[string]string{".hidden": "x", "..2024/server.port": "1", "server.host": "h"},
+			values: map[string]string{"server.host": "h"},
+		},
+		{
+			name:      "prefix",
+			files:     map[string]string{"password": "secret", "user/name": "root"},
+			directory: Directory{Prefix: "db"},
+			values:    map[string]string{"db.password": "secret", "db.user.name": "root"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			directory := test.directory
+			directory.Path = testDir(t, test.files)
+			values, err := directory.Load(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(values, test.values) {
+				t.Fatalf("values %v, want %v", values, test.values)
+			}
+		})
+	}
+}
+
+func TestDirectoryMissing(t *testing.T) {
+	path := filepath.Join(testDir(t, nil), "missing")
+	if values, err := (&Directory{Path: path, Optional: true}).Load(context.Background()); err != nil || len(values) != 0 {
+		t.Fatalf("optional directory: values %v, error %v", values, err)
+	}
+	if _, err := (&Directory{Path: path}).Load(context.Background()); err == nil {
+		t.Fatal("expected an error for a missing directory")
+	}
+}
+
+// TestDirectoryWatchDataSwap 按Kubernetes的方式挂载：文件链接到 ..data/文件，..data 链接到时间戳目录，
+// 更新时先创建新的时间戳目录，再把指向它的临时链接重命名为 ..data
+func TestDirectoryWatchDataSwap(t *testing.T) {
+	dir := testDir(t, map[string]string{
+		"..2024_01_01/server.port": "8080",
+		"..2024_01_01/server.host": "old",
+	})
+	for _, name := range []string{"server.port", "server.host"} {
+		if err := os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("..2024_01_01", filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	directory := &Directory{Path: dir, Interval: 10 * time.Millisecond}
+	values, err := directory.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"server.port": "8080", "server.host": "old"}; !reflect.DeepEqual(values, want) {
+		t.Fatalf("values %v, want %v", values, want)
+	}
+
+	changes := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- directory.Watch(ctx, func() { changes <- struct{}{} })
+	}()
+	time.Sleep(30 * time.Millisecond)
+	select {
+	case <-changes:
+		t.Fatal("changed before the swap")
+	default:
+	}
+
+	next := filepath.Join(dir, "..2024_01_02")
+	if err := os.Mkdir(next, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"server.port": "9090", "server.host": "new"} {
+		if err := ioutil.WriteFile(filepath.Join(next, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("..2024_01_02", filepath.Join(dir, "..data_tmp")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	os.RemoveAll(filepath.Join(dir, "..2024_01_01"))
+
+	select {
+	case <-changes:
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change after the ..data swap")
+	}
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("expected one change, got %d more", len(changes))
+	}
+	values, err = directory.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"server.port": "9090", "server.host": "new"}; !reflect.DeepEqual(values, want) {
+		t.Fatalf("values after swap %v, want %v", values, want)
+	}
+}
@@ -0,0 +1,241 @@
+package config_source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+	// FormatSpring Spring Cloud Config服务端返回的propertySources格式
+	FormatSpring     = "spring"
+	FormatTOML       = "toml"
+	FormatDotenv     = "dotenv"
+	FormatProperties = "properties"
+)
+
+// Decoder 把配置内容解析成 server.port 形式的key路径到配置值的映射
+type Decoder func(data []byte) (map[string]string, error)
+
+var (
+	decoderLock sync.RWMutex
+	decoders    = make(map[string]Decoder)
+	extensions  = make(map[string]string)
+)
+
+func init() {
+	RegisterDecoder(FormatYAML, decodeYAML, ".yaml", ".yml")
+	RegisterDecoder(FormatJSON, decodeJSON, ".json")
+	RegisterDecoder(FormatSpring, decodeSpring)
+	RegisterDecoder(FormatTOML, decodeTOML, ".toml")
+	RegisterDecoder(FormatDotenv, decodeDotenv, ".env")
+	RegisterDecoder(FormatProperties, decodeProperties, ".properties")
+}
+
+// RegisterDecoder 注册一种配置格式以及对应的文件扩展名，已存在的格式会被覆盖
+func RegisterDecoder(format string, decoder Decoder, exts ...string) {
+	decoderLock.Lock()
+	defer decoderLock.Unlock()
+	decoders[format] = decoder
+	for _, ext := range exts {
+		extensions[strings.ToLower(ext)] = format
+	}
+}
+
+// FormatOf 根据文件扩展名判断配置格式，无法判断时返回空字符串
+func FormatOf(path string) string {
+	decoderLock.RLock()
+	defer decoderLock.RUnlock()
+	ext := strings.ToLower(filepath.Ext(path))
+	if strings.HasPrefix(filepath.Base(path), ".env") {
+		ext = ".env"
+	}
+	return extensions[ext]
+}
+
+// DetectFormat 根据Content-Type判断配置格式，无法判断时返回空字符串
+func DetectFormat(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return FormatJSON
+	case strings.Contains(mediaType, "yaml") || strings.Contains(mediaType, "yml"):
+		return FormatYAML
+	case strings.Contains(mediaType, "toml"):
+		return FormatTOML
+	}
+	return ""
+}
+
+// Decode 按格式解析配置内容，format为空时按yaml解析；
+// yaml和json会自动识别Spring Cloud Config的返回结果。内置格式的语法错误为带行和列的*Error。
+// yaml只合并没有声明profile的文档，结果与环境变量无关，需要按profile选择文档时使用DecodeYAMLDocuments
+func Decode(data []byte, format string) (map[string]string, error) {
+	if format == "" {
+		format = FormatYAML
+	}
+	decoderLock.RLock()
+	decoder, ok := decoders[format]
+	decoderLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+	return decoder(data)
+}
+
+func decodeYAML(data []byte) (map[string]string, error) {
+	return DecodeYAMLDocuments(data, nil)
+}
+
+func decodeJSON(data []byte) (map[string]string, error) {
+	tree, err := jsonTree(data)
+	if err != nil {
+		return nil, err
+	}
+	return treeValues(tree)
+}
+
+func decodeSpring(data []byte) (map[string]string, error) {
+	tree, err := jsonTree(data)
+	if err != nil {
+		return nil, err
+	}
+	return springValues(tree)
+}
+
+func decodeTOML(data []byte) (map[string]string, error) {
+	tree := make(map[string]interface{})
+	if err := toml.Unmarshal(data, &tree); err != nil {
+		if parseErr, ok := err.(toml.ParseError); ok {
+			pos := Position{Line: parseErr.Position.Line}
+			if parseErr.Position.Start > 0 {
+				pos = offsetPosition(data, parseErr.Position.Start)
+			}
+			message := parseErr.Message
+			if message == "" {
+				message = parseErr.Error()
+			}
+			return nil, &Error{Pos: pos, Message: "cannot decode toml config: " + message}
+		}
+		return nil, fmt.Errorf("cannot decode toml config: %v", err)
+	}
+	values := make(map[string]string)
+	Flatten("", tree, values)
+	return values, nil
+}
+
+func jsonTree(data []byte) (interface{}, error) {
+	var tree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		switch err := err.(type) {
+		case *json.SyntaxError:
+			return nil, &Error{Pos: offsetPosition(data, int(err.Offset)-1), Message: "cannot decode json config: " + err.Error()}
+		}
+		if err == io.ErrUnexpectedEOF {
+			return nil, &Error{Pos: offsetPosition(data, len(data)), Message: "cannot decode json config: " + err.Error()}
+		}
+		return nil, fmt.Errorf("cannot decode json config: %v", err)
+	}
+	return tree, nil
+}
+
+func treeValues(tree interface{}) (map[string]string, error) {
+	if isSpringEnvironment(tree) {
+		return springValues(tree)
+	}
+	values := make(map[string]string)
+	Flatten("", tree, values)
+	return values, nil
+}
+
+// Flatten 把嵌套的map/list展开到values中，list元素的key为 name[0]
+func Flatten(prefix string, tree interface{}, values map[string]string) {
+	switch node := tree.(type) {
+	case map[string]interface{}:
+		for k, v := range node {
+			Flatten(joinKey(prefix, k), v, values)
+		}
+	case map[interface{}]interface{}:
+		for k, v := range node {
+			Flatten(joinKey(prefix, fmt.Sprint(k)), v, values)
+		}
+	case []interface{}:
+		for i, v := range node {
+			Flatten(fmt.Sprintf("%s[%d]", prefix, i), v, values)
+		}
+	case []map[string]interface{}:
+		for i, v := range node {
+			Flatten(fmt.Sprintf("%s[%d]", prefix, i), v, values)
+		}
+	case time.Time:
+		if prefix != "" {
+			values[prefix] = node.Format(time.RFC3339Nano)
+		}
+	case nil:
+		if prefix != "" {
+			values[prefix] = ""
+		}
+	default:
+		if prefix != "" {
+			values[prefix] = fmt.Sprint(node)
+		}
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func isSpringEnvironment(tree interface{}) bool {
+	root, ok := tree.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = root["propertySources"].([]interface{})
+	return ok
+}
+
+// springValues 合并Spring的propertySources，列表中靠前的来源优先级更高
+func springValues(tree interface{}) (map[string]string, error) {
+	root, ok := tree.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("spring config: expected an object with propertySources")
+	}
+	sources, ok := root["propertySources"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("spring config: propertySources must be a list")
+	}
+	values := make(map[string]string)
+	for i := len(sources) - 1; i >= 0; i-- {
+		source, ok := sources[i].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("spring config: propertySources[%d] must be an object", i)
+		}
+		properties, ok := source["source"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("spring config: propertySources[%d].source must be an object", i)
+		}
+		for k, v := range properties {
+			Flatten(k, v, values)
+		}
+	}
+	return values, nil
+}
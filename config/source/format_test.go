@@ -0,0 +1,205 @@
+package config_source
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	// 内置的解析器不读取CONFIG_PROFILES
+	defer os.Setenv(ProfilesEnv, os.Getenv(ProfilesEnv))
+	os.Setenv(ProfilesEnv, "prod")
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		values map[string]string
+		err    bool
+	}{
+		{
+			name:   "yaml",
+			format: FormatYAML,
+			data:   "server:\n  port: 8080\n  tags: [a, b]\n",
+			values: map[string]string{"server.port": "8080", "server.tags[0]": "a", "server.tags[1]": "b"},
+		},
+		{
+			name:   "yaml skips profile documents",
+			format: "",
+			data:   "server:\n  port: 8080\n---\nprofiles: [prod]\nserver:\n  port: 9090\n",
+			values: map[string]string{"server.port": "8080"},
+		},
+		{
+			name:   "json",
+			format: FormatJSON,
+			data:   `{"server": {"port": 8080, "tags": ["a", "b"], "debug": true, "name": null}, "rate": 0.5}`,
+			values: map[string]string{"server.port": "8080", "server.tags[0]": "a", "server.tags[1]": "b", "server.debug": "true", "server.name": "", "rate": "0.5"},
+		},
+		{
+			name:   "json keeps large numbers",
+			format: FormatJSON,
+			data:   `{"id": 12345678901234567890}`,
+			values: map[string]string{"id": "12345678901234567890"},
+		},
+		{
+			name:   "json spring environment",
+			format: FormatJSON,
+			data:   `{"name": "app", "propertySources": [{"name": "prod", "source": {"server.port": 9090}}, {"name": "default", "source": {"server.port": 8080, "server.host": "localhost"}}]}`,
+			values: map[string]string{"server.port": "9090", "server.host": "localhost"},
+		},
+		{
+			name:   "json syntax error",
+			format: FormatJSON,
+			data:   `{"server": `,
+			err:    true,
+		},
+		{
+			name:   "spring without propertySources",
+			format: FormatSpring,
+			data:   `{"name": "app"}`,
+			err:    true,
+		},
+		{
+			name:   "toml",
+			format: FormatTOML,
+			data:   "title = \"app\"\n\n[server]\nport = 8080\ntags = [\"a\", \"b\"]\n\n[[server.hosts]]\nname = \"h1\"\n\n[[server.hosts]]\nname = \"h2\"\n",
+			values: map[string]string{"title": "app", "server.port": "8080", "server.tags[0]": "a", "server.tags[1]": "b", "server.hosts[0].name": "h1", "server.hosts[1].name": "h2"},
+		},
+		{
+			name:   "toml datetime",
+			format: FormatTOML,
+			data:   "start = 2020-01-02T03:04:05Z\n",
+			values: map[string]string{"start": "2020-01-02T03:04:05Z"},
+		},
+		{
+			name:   "toml syntax error",
+			format: FormatTOML,
+			data:   "[server\nport = 8080\n",
+			err:    true,
+		},
+		{
+			name:   "dotenv",
+			format: FormatDotenv,
+			data:   "# comment\nSERVER_PORT=8080\nexport SERVER_HOST = localhost # host\nEMPTY=\n",
+			values: map[string]string{"SERVER_PORT": "8080", "SERVER_HOST": "localhost", "EMPTY": ""},
+		},
+		{
+			name:   "dotenv quotes",
+			format: FormatDotenv,
+			data:   "A=\"with # hash\\n\" # comment\nB='single \"quoted\"'\nC=a#b\n",
+			values: map[string]string{"A": "with # hash\n", "B": `single "quoted"`, "C": "a#b"},
+		},
+		{
+			name:   "dotenv missing equals",
+			format: FormatDotenv,
+			data:   "SERVER_PORT\n",
+			err:    true,
+		},
+		{
+			name:   "dotenv unterminated quote",
+			format: FormatDotenv,
+			data:   "A=\"open\n",
+			err:    true,
+		},
+		{
+			name:   "properties",
+			format: FormatProperties,
+			data:   "# comment\n! comment\nserver.port=8080\nserver.host : localhost\nserver.name app\nempty\n",
+			values: map[string]string{"server.port": "8080", "server.host": "localhost", "server.name": "app", "empty": ""},
+		},
+		{
+			name:   "properties continuation and escapes",
+			format: FormatProperties,
+			data:   "list = a,\\\n    b,\\\n    c\nkey\\ with\\=sep = tab\\there\nunicode = \\u4e2d\\u6587\nslash = c:\\\\dir\n",
+			values: map[string]string{"list": "a,b,c", "key with=sep": "tab\there", "unicode": "中文", "slash": `c:\dir`},
+		},
+		{
+			name:   "properties malformed unicode",
+			format: FormatProperties,
+			data:   "a = \\u12\n",
+			err:    true,
+		},
+		{
+			name:   "unsupported format",
+			format: "ini",
+			data:   "a=1",
+			err:    true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := Decode([]byte(test.data), test.format)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", values)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(values, test.values) {
+				t.Fatalf("values %v, want %v", values, test.values)
+			}
+		})
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		path   string
+		format string
+	}{
+		{"application.yaml", FormatYAML},
+		{"config/app.YML", FormatYAML},
+		{"app.json", FormatJSON},
+		{"app.toml", FormatTOML},
+		{".env", FormatDotenv},
+		{"deploy/.env.local", FormatDotenv},
+		{"app.env", FormatDotenv},
+		{"application.properties", FormatProperties},
+		{"app.conf", ""},
+	}
+	for _, test := range tests {
+		if format := FormatOf(test.path); format != test.format {
+			t.Errorf("FormatOf(%q) = %q, want %q", test.path, format, test.format)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		contentType string
+		format      string
+	}{
+		{"application/json", FormatJSON},
+		{"application/json; charset=utf-8", FormatJSON},
+		{"application/vnd.spring-cloud.config-server.v2+json", FormatJSON},
+		{"application/x-yaml", FormatYAML},
+		{"text/yaml", FormatYAML},
+		{"application/toml", FormatTOML},
+		{"text/plain", ""},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if format := DetectFormat(test.contentType); format != test.format {
+			t.Errorf("DetectFormat(%q) = %q, want %q", test.contentType, format, test.format)
+		}
+	}
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	RegisterDecoder("test-upper", func(data []byte) (map[string]string, error) {
+		return map[string]string{"value": string(data)}, nil
+	}, ".TestUpper")
+	if format := FormatOf("app.testupper"); format != "test-upper" {
+		t.Fatalf("FormatOf = %q", format)
+	}
+	values, err := Decode([]byte("x"), "test-upper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["value"] != "x" {
+		t.Fatalf("values %v", values)
+	}
+}
@@ -0,0 +1,141 @@
+package config_source
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/utils"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// positionFinders yaml以外能够给出key位置的格式，位置中只有行和列；toml解析库不提供key的位置，
+// toml和RegisterDecoder注册的其他格式的值错误只带来源名称，语法错误仍然有行和列
+var positionFinders = map[string]func(data []byte) map[string]Position{
+	FormatJSON: jsonPositions,
+	FormatDotenv: func(data []byte) map[string]Position {
+		_, positions, _ := parseDotenv(data)
+		return positions
+	},
+	FormatProperties: func(data []byte) map[string]Position {
+		_, positions, _ := parseProperties(data)
+		return positions
+	},
+}
+
+// offsetPosition 字节偏移offset对应的行和列，都从1开始，列按字符计算
+func offsetPosition(data []byte, offset int) Position {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(data) {
+		offset = len(data)
+	}
+	lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+	return Position{Line: bytes.Count(data[:offset], []byte("\n")) + 1, Column: utf8.RuneCount(data[lineStart:offset]) + 1}
+}
+
+// jsonPositions 按token遍历json，记录每个值开始的位置，key的规则与Flatten相同
+func jsonPositions(data []byte) map[string]Position {
+	positions := make(map[string]Position)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	// next 下一个token开始的偏移，跳过空白和分隔符
+	next := func() int {
+		offset := int(decoder.InputOffset())
+		for offset < len(data) && strings.IndexByte(" \t\r\n:,", data[offset]) != -1 {
+			offset++
+		}
+		return offset
+	}
+	var walk func(prefix string) bool
+	walk = func(prefix string) bool {
+		offset := next()
+		token, err := decoder.Token()
+		if err != nil {
+			return false
+		}
+		switch token {
+		case json.Delim('{'):
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return false
+				}
+				name, _ := key.(string)
+				if !walk(joinKey(prefix, name)) {
+					return false
+				}
+			}
+			_, err = decoder.Token()
+			return err == nil
+		case json.Delim('['):
+			for i := 0; decoder.More(); i++ {
+				if !walk(fmt.Sprintf("%s[%d]", prefix, i)) {
+					return false
+				}
+			}
+			_, err = decoder.Token()
+			return err == nil
+		}
+		if prefix != "" {
+			positions[prefix] = offsetPosition(data, offset)
+		}
+		return true
+	}
+	walk("")
+	return positions
+}
+
+// lookupPosition 查找key的位置：没有直接记录时依次使用环境变量风格的同名key（例如.env中的SERVER_PORT）、
+// 列表或map中最靠前的元素、上一级的key
+func lookupPosition(positions map[string]Position, key string) (Position, bool) {
+	if pos, ok := positions[key]; ok {
+		return pos, true
+	}
+	if len(positions) == 0 {
+		return Position{}, false
+	}
+	envName := utils.EnvName(key)
+	children := make([]Position, 0)
+	for name, pos := range positions {
+		if utils.EnvName(name) == envName && !strings.Contains(name, ".") {
+			return pos, true
+		}
+		if strings.HasPrefix(name, key+"[") || strings.HasPrefix(name, key+".") {
+			children = append(children, pos)
+		}
+	}
+	if len(children) > 0 {
+		sort.Slice(children, func(i, j int) bool {
+			if children[i].Line != children[j].Line {
+				return children[i].Line < children[j].Line
+			}
+			return children[i].Column < children[j].Column
+		})
+		return children[0], true
+	}
+	for parent := parentKey(key); parent != ""; parent = parentKey(parent) {
+		if pos, ok := positions[parent]; ok {
+			return pos, true
+		}
+	}
+	return Position{}, false
+}
+
+// parentKey 去掉key最后的 [i] 或 .name，没有上一级时返回空字符串
+func parentKey(key string) string {
+	if strings.HasSuffix(key, "]") {
+		if index := strings.LastIndex(key, "["); index > 0 {
+			if _, err := strconv.Atoi(key[index+1 : len(key)-1]); err == nil {
+				return key[:index]
+			}
+		}
+	}
+	if index := strings.LastIndex(key, "."); index > 0 {
+		return key[:index]
+	}
+	return ""
+}
@@ -0,0 +1,131 @@
+package config_source
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodePositions(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		// positions key到期望的 line:col
+		positions map[string]string
+	}{
+		{
+			name:      "yaml",
+			format:    FormatYAML,
+			data:      "server:\n  port: 8080\n  tags:\n    - a\n",
+			positions: map[string]string{"server.port": "2:9", "server.tags[0]": "4:7", "server.tags": "4:7"},
+		},
+		{
+			name:      "json",
+			format:    FormatJSON,
+			data:      "{\n  \"server\": {\n    \"port\": 8080,\n    \"name\": \"中文\", \"debug\": true\n  },\n  \"tags\": [\"a\",\n    \"b\"]\n}\n",
+			positions: map[string]string{"server.port": "3:13", "server.name": "4:13", "server.debug": "4:28", "tags[0]": "6:12", "tags[1]": "7:5", "tags": "6:12"},
+		},
+		{
+			// toml只有语法错误带行和列，值没有位置
+			name:      "toml",
+			format:    FormatTOML,
+			data:      "[server]\nport = 8080\n",
+			positions: map[string]string{},
+		},
+		{
+			name:      "dotenv",
+			format:    FormatDotenv,
+			data:      "# comment\nSERVER_PORT=8080\n  export SERVER_HOST = \"localhost\"\n",
+			positions: map[string]string{"SERVER_PORT": "2:13", "server.port": "2:13", "server.host": "3:24"},
+		},
+		{
+			name:      "properties",
+			format:    FormatProperties,
+			data:      "# comment\nserver.port=8080\n  server.host : localhost\nserver.list = a,\\\n  b\nserver.next = \\\n  c\n",
+			positions: map[string]string{"server.port": "2:13", "server.host": "3:17", "server.list": "4:15", "server.next": "6:1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, positions, err := decodeWithProfiles("text:app", []byte(test.data), test.format, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(test.positions) == 0 && len(positions) > 0 {
+				t.Fatalf("unexpected positions %v", positions)
+			}
+			for key, want := range test.positions {
+				pos, ok := lookupPosition(positions, key)
+				if !ok {
+					t.Fatalf("no position for %s", key)
+				}
+				if got := pos.String(); got != "text:app:"+want {
+					t.Errorf("position of %s is %s, want %s", key, got, want)
+				}
+				if pos.Snippet == "" {
+					t.Errorf("position of %s has no snippet", key)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		data     string
+		position string
+		message  string
+	}{
+		{"json", FormatJSON, "{\n  \"port\": 80,\n  \"host\" \"x\"\n}\n", "3:10", "cannot decode json config"},
+		{"json truncated", FormatJSON, "{\n  \"port\": 80,\n", "3:1", "cannot decode json config"},
+		{"toml", FormatTOML, "a = 1\n[server\nport = 1\n", "2", "cannot decode toml config"},
+		{"dotenv", FormatDotenv, "A=1\n  B\n", "2:3", "expected KEY=value"},
+		{"dotenv quote", FormatDotenv, "A=1\nB = \"open\n", "2:5", "unterminated quoted value"},
+		{"properties", FormatProperties, "a = 1\nb = \\u12\n", "2:5", "malformed \\u escape"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := decodeWithProfiles("text:app", []byte(test.data), test.format, nil)
+			located, ok := err.(*Error)
+			if !ok {
+				t.Fatalf("expected *Error, got %v", err)
+			}
+			if !strings.HasPrefix(located.Pos.String(), "text:app:"+test.position) {
+				t.Fatalf("position %s, want %s", located.Pos, test.position)
+			}
+			if !strings.Contains(located.Message, test.message) {
+				t.Fatalf("message %q, want %q", located.Message, test.message)
+			}
+		})
+	}
+}
+
+func TestLookupPosition(t *testing.T) {
+	positions := map[string]Position{
+		"SERVER_PORT":    {Line: 1},
+		"server.tags[1]": {Line: 3},
+		"server.tags[0]": {Line: 2},
+		"server.extra.a": {Line: 4},
+		"list":           {Line: 5},
+	}
+	tests := []struct {
+		key  string
+		line int
+	}{
+		{"server.port", 1},
+		{"server.tags", 2},
+		{"server.tags[1]", 3},
+		{"server.extra", 4},
+		{"list[3]", 5},
+		{"server", 2},
+		{"other", 0},
+	}
+	for _, test := range tests {
+		pos, ok := lookupPosition(positions, test.key)
+		if ok != (test.line > 0) || pos.Line != test.line {
+			t.Errorf("lookupPosition(%q) = %v %v, want line %d", test.key, pos, ok, test.line)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package config_source
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Position 配置值在源文件中的位置，Snippet为所在的行
+type Position struct {
+	File    string
+	Line    int
+	Column  int
+	Snippet string
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String 返回 file:line:col，编辑器可以直接跳转
+func (p Position) String() string {
+	file := p.File
+	if file == "" {
+		file = "<config>"
+	}
+	if p.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d", file, p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d", file, p.Line)
+}
+
+// Locator 可选接口，返回最近一次Load得到的key在源文件中的位置
+type Locator interface {
+	Position(key string) (Position, bool)
+}
+
+// Error 带位置的配置错误，格式为 file:line:col: key: message，下面附带出错的行
+type Error struct {
+	Pos     Position
+	Source  string
+	Key     string
+	Message string
+}
+
+func (e *Error) Error() string {
+	var builder strings.Builder
+	switch {
+	case e.Pos.IsValid():
+		builder.WriteString(e.Pos.String() + ": ")
+	case e.Source != "":
+		builder.WriteString(e.Source + ": ")
+	}
+	if e.Key != "" {
+		builder.WriteString(e.Key + ": ")
+	}
+	builder.WriteString(e.Message)
+	if e.Pos.Snippet != "" {
+		builder.WriteString("\n\t" + strings.ReplaceAll(e.Pos.Snippet, "\t", " "))
+		if e.Pos.Column > 0 {
+			builder.WriteString("\n\t" + strings.Repeat(" ", len([]rune(e.Pos.Snippet[:columnOffset(e.Pos.Snippet, e.Pos.Column)]))) + "^")
+		}
+	}
+	return builder.String()
+}
+
+func columnOffset(line string, column int) int {
+	runes := 0
+	for i := range line {
+		if runes == column-1 {
+			return i
+		}
+		runes++
+	}
+	return len(line)
+}
+
+// ErrorList 多个配置错误，按文件和行号排序后逐个输出
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	sorted := make(ErrorList, len(l))
+	copy(sorted, l)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Pos.File != sorted[j].Pos.File {
+			return sorted[i].Pos.File < sorted[j].Pos.File
+		}
+		if sorted[i].Pos.Line != sorted[j].Pos.Line {
+			return sorted[i].Pos.Line < sorted[j].Pos.Line
+		}
+		return sorted[i].Key < sorted[j].Key
+	})
+	messages := make([]string, 0, len(sorted))
+	for _, err := range sorted {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Skip 去掉key为prefixes之一或在其下的错误，用于跳过未启用的配置节
+func (l ErrorList) Skip(prefixes []string) ErrorList {
+	if len(prefixes) == 0 {
+		return l
+	}
+	errs := make(ErrorList, 0, len(l))
+	for _, err := range l {
+		skipped := false
+		for _, prefix := range prefixes {
+			skipped = skipped || err.Key == prefix || strings.HasPrefix(err.Key, prefix+".")
+		}
+		if !skipped {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Locate 为错误补充来源名称和位置
+func Locate(source Source, err *Error) *Error {
+	err.Source = source.Name()
+	if locator, ok := source.(Locator); ok && !err.Pos.IsValid() {
+		if pos, ok := locator.Position(err.Key); ok {
+			err.Pos = pos
+		}
+	}
+	return err
+}
+
+// KeyKind 配置项的种类，列表和map的元素不在已知的key中，需要按种类判断
+type KeyKind int
+
+const (
+	KeyValue KeyKind = iota
+	KeySection
+	KeyList
+	KeyMap
+)
+
+// Keys 所有已知的配置项，由生成的代码根据配置类型列出
+type Keys map[string]KeyKind
+
+// Known key是已知的配置项，或者是已知列表的元素 name[0]、已知map中的项 name.key
+func (k Keys) Known(key string) bool {
+	if _, ok := k[key]; ok {
+		return true
+	}
+	if strings.HasSuffix(key, "]") {
+		index := strings.LastIndex(key, "[")
+		if index <= 0 {
+			return false
+		}
+		if _, err := strconv.Atoi(key[index+1 : len(key)-1]); err != nil {
+			return false
+		}
+		return k[key[:index]] == KeyList
+	}
+	for index := strings.LastIndex(key, "."); index > 0; index = strings.LastIndex(key[:index], ".") {
+		if kind, ok := k[key[:index]]; ok {
+			return kind == KeyMap
+		}
+	}
+	return false
+}
+
+// LocateLast 在最后一个设置了err.Key的来源中定位错误，列表和map的元素也算设置了该key；
+// 都没有设置时值来自默认值，err不带位置
+func LocateLast(sources []Source, values []map[string]string, err *Error) *Error {
+	for i := len(values) - 1; i >= 0; i-- {
+		if _, ok := values[i][err.Key]; ok || hasChild(values[i], err.Key) {
+			return Locate(sources[i], err)
+		}
+	}
+	return err
+}
+
+func hasChild(values map[string]string, key string) bool {
+	for name := range values {
+		if strings.HasPrefix(name, key+"[") || strings.HasPrefix(name, key+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// UnknownKeys 返回values中不属于keys的配置项；命令行参数和环境变量中常有与配置无关的项，不做检查
+func UnknownKeys(source Source, values map[string]string, keys Keys) ErrorList {
+	switch source.(type) {
+	case *Args, *Env:
+		return nil
+	}
+	errs := make(ErrorList, 0)
+	for key := range values {
+		if !keys.Known(key) {
+			errs = append(errs, Locate(source, &Error{Key: key, Message: "unknown config key"}))
+		}
+	}
+	return errs
+}
+
+var yamlLinePattern = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// yamlSyntaxError 把 yaml: line 3: ... 形式的语法错误转换为带位置的Error
+func yamlSyntaxError(file string, lines []string, err error) error {
+	match := yamlLinePattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return &Error{Pos: Position{File: file}, Message: err.Error()}
+	}
+	line, _ := strconv.Atoi(match[1])
+	return &Error{Pos: position(file, lines, line, 0), Message: match[2]}
+}
+
+func position(file string, lines []string, line, column int) Position {
+	pos := Position{File: file, Line: line, Column: column}
+	if line > 0 && line <= len(lines) {
+		pos.Snippet = strings.TrimRight(lines[line-1], "\r")
+	}
+	return pos
+}
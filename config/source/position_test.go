@@ -0,0 +1,131 @@
+package config_source
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+var testKeys = Keys{
+	"server":         KeySection,
+	"server.port":    KeyValue,
+	"server.tags":    KeyList,
+	"server.extra":   KeyMap,
+	"server.db":      KeySection,
+	"server.db.user": KeyValue,
+}
+
+func TestKeysKnown(t *testing.T) {
+	tests := []struct {
+		key   string
+		known bool
+	}{
+		{"server", true},
+		{"server.port", true},
+		{"server.db.user", true},
+		{"server.tags", true},
+		{"server.tags[0]", true},
+		{"server.tags[12]", true},
+		{"server.tags[x]", false},
+		{"server.tags.x", false},
+		{"server.port[0]", false},
+		{"server.extra.k", true},
+		{"server.extra.a.b", true},
+		{"server.extra[0]", false},
+		{"server.db.password", false},
+		{"server.unknown", false},
+		{"[0]", false},
+		{"other", false},
+	}
+	for _, test := range tests {
+		if known := testKeys.Known(test.key); known != test.known {
+			t.Errorf("Known(%q) = %v, want %v", test.key, known, test.known)
+		}
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  Source
+		values  map[string]string
+		unknown []string
+	}{
+		{
+			name:   "collections",
+			source: &Memory{},
+			values: map[string]string{"server.tags[0]": "a", "server.tags[1]": "b", "server.extra.k": "v", "server.port": "80"},
+		},
+		{
+			name:    "unknown",
+			source:  &Memory{},
+			values:  map[string]string{"server.prot": "80", "server.tags.k": "v", "server.extra[0]": "v"},
+			unknown: []string{"server.extra[0]", "server.prot", "server.tags.k"},
+		},
+		{
+			name:   "args are not checked",
+			source: &Args{},
+			values: map[string]string{"v": "1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			unknown := make([]string, 0)
+			for _, err := range UnknownKeys(test.source, test.values, testKeys) {
+				unknown = append(unknown, err.Key)
+			}
+			sort.Strings(unknown)
+			if len(unknown) != len(test.unknown) {
+				t.Fatalf("unknown keys %v, want %v", unknown, test.unknown)
+			}
+			for i := range unknown {
+				if unknown[i] != test.unknown[i] {
+					t.Fatalf("unknown keys %v, want %v", unknown, test.unknown)
+				}
+			}
+		})
+	}
+}
+
+func TestLocateLast(t *testing.T) {
+	dir, err := ioutil.TempDir("", "locate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	contents := []string{"server:\n  port: 1\n  tags: [a]\n", "server:\n  host: h\n  port: 2\n"}
+	sources := make([]Source, len(contents))
+	values := make([]map[string]string, len(contents))
+	for i, content := range contents {
+		path := filepath.Join(dir, []string{"base.yaml", "override.yaml"}[i])
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sources[i] = &File{Path: path, Profiles: []string{"default"}}
+		if values[i], err = sources[i].Load(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		key      string
+		position string
+	}{
+		{"server.port", "override.yaml:3:9"},
+		{"server.host", "override.yaml:2:9"},
+		{"server.tags", "base.yaml:3:10"},
+		{"server.mode", ""},
+	}
+	for _, test := range tests {
+		err := LocateLast(sources, values, &Error{Key: test.key, Message: "invalid"})
+		position := ""
+		if err.Pos.IsValid() {
+			position = filepath.Base(err.Pos.String())
+		}
+		if position != test.position {
+			t.Errorf("%s located at %q, want %q", test.key, position, test.position)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package config_source
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/utils"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfilesEnv 未指定激活的profile时从该环境变量读取，多个profile用逗号分隔
+const ProfilesEnv = "CONFIG_PROFILES"
+
+// activationKeys 文档中用于声明激活条件的key，这些key本身不会作为配置值
+var activationKeys = []string{"profiles", "on-profile", "spring.config.activate.on-profile"}
+
+// ActiveProfiles 返回profiles中非空的部分，profiles为空时读取CONFIG_PROFILES环境变量
+func ActiveProfiles(profiles []string) []string {
+	if len(profiles) == 0 {
+		profiles = strings.Split(os.Getenv(ProfilesEnv), ",")
+	}
+	active := make([]string, 0, len(profiles))
+	for _, profile := range profiles {
+		if profile = strings.TrimSpace(profile); profile != "" {
+			active = append(active, profile)
+		}
+	}
+	return active
+}
+
+// DecodeYAMLDocuments 解析 --- 分隔的所有yaml文档，按顺序合并激活的文档；
+// 文档可以用 profiles: [prod] 或 on-profile: prod 声明激活条件，没有声明的文档总是激活
+func DecodeYAMLDocuments(data []byte, profiles []string) (map[string]string, error) {
+	values, _, err := decodeYAMLNodes("", data, profiles)
+	return values, err
+}
+
+// decodeYAMLNodes 与DecodeYAMLDocuments相同，同时返回每个key在文件中的位置
+func decodeYAMLNodes(file string, data []byte, profiles []string) (map[string]string, map[string]Position, error) {
+	values := make(map[string]string)
+	positions := make(map[string]Position)
+	lines := strings.Split(string(data), "\n")
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, nil, yamlSyntaxError(file, lines, err)
+		}
+		var tree interface{}
+		if err := document.Decode(&tree); err != nil {
+			return nil, nil, yamlSyntaxError(file, lines, err)
+		}
+		documentValues := make(map[string]string)
+		documentPositions := make(map[string]Position)
+		if isSpringEnvironment(tree) {
+			springDocument, err := springValues(tree)
+			if err != nil {
+				return nil, nil, err
+			}
+			documentValues = springDocument
+		} else {
+			flattenNode("", &document, func(key string, node *yaml.Node) {
+				documentValues[key] = nodeValue(node)
+				documentPositions[key] = position(file, lines, node.Line, node.Column)
+			})
+		}
+		active, err := documentActive(documentValues, profiles)
+		if err != nil {
+			return nil, nil, &Error{Pos: position(file, lines, document.Line, document.Column), Message: err.Error()}
+		}
+		if !active {
+			continue
+		}
+		for k, v := range documentValues {
+			values[k] = v
+			if pos, ok := documentPositions[k]; ok {
+				positions[k] = pos
+			} else {
+				delete(positions, k)
+			}
+		}
+	}
+	return values, positions, nil
+}
+
+// flattenNode 与Flatten规则相同，展开yaml节点并保留节点位置，支持锚点和 <<: *base 合并
+func flattenNode(prefix string, node *yaml.Node, visit func(key string, node *yaml.Node)) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			flattenNode(prefix, child, visit)
+		}
+	case yaml.AliasNode:
+		flattenNode(prefix, node.Alias, visit)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Tag == "!!merge" {
+				if value.Kind == yaml.SequenceNode {
+					for j := len(value.Content) - 1; j >= 0; j-- {
+						flattenNode(prefix, value.Content[j], visit)
+					}
+				} else {
+					flattenNode(prefix, value, visit)
+				}
+				continue
+			}
+			flattenNode(joinKey(prefix, key.Value), value, visit)
+		}
+	case yaml.SequenceNode:
+		if prefix == "" {
+			return
+		}
+		for i, child := range node.Content {
+			flattenNode(fmt.Sprintf("%s[%d]", prefix, i), child, visit)
+		}
+	case yaml.ScalarNode:
+		if prefix != "" {
+			visit(prefix, node)
+		}
+	}
+}
+
+func nodeValue(node *yaml.Node) string {
+	if node.Tag == "!!null" {
+		return ""
+	}
+	return node.Value
+}
+
+// documentActive 判断文档的激活条件并从values中移除条件本身
+func documentActive(values map[string]string, profiles []string) (bool, error) {
+	conditions := make([]string, 0)
+	for key := range values {
+		for _, activationKey := range activationKeys {
+			if key == activationKey || strings.HasPrefix(key, activationKey+"[") {
+				conditions = append(conditions, values[key])
+				delete(values, key)
+			}
+		}
+	}
+	if len(conditions) == 0 {
+		return true, nil
+	}
+	for _, condition := range conditions {
+		matched, err := MatchProfiles(condition, profiles)
+		if err != nil || matched {
+			return matched, err
+		}
+	}
+	return false, nil
+}
+
+// MatchProfiles 判断profile表达式是否匹配激活的profile，支持 prod、!prod、prod & cloud、prod | dev 和逗号分隔的列表
+func MatchProfiles(expression string, profiles []string) (bool, error) {
+	active := make(map[string]bool, len(profiles))
+	for _, profile := range profiles {
+		active[profile] = true
+	}
+	for _, alternative := range strings.FieldsFunc(expression, func(r rune) bool { return r == '|' || r == ',' }) {
+		matched := true
+		for _, term := range strings.Split(alternative, "&") {
+			term = strings.TrimSpace(term)
+			negate := strings.HasPrefix(term, "!")
+			term = strings.TrimSpace(strings.TrimPrefix(term, "!"))
+			if utils.IsBlank(term) {
+				return false, fmt.Errorf("malformed profile expression %q", expression)
+			}
+			if active[term] == negate {
+				matched = false
+			}
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// decodeWithProfiles yaml按多文档和激活的profiles解析，其他格式直接解析；同时返回每个key的位置，
+// 语法错误为带 file:line:col 的*Error
+func decodeWithProfiles(file string, data []byte, format string, profiles []string) (map[string]string, map[string]Position, error) {
+	if format == "" || format == FormatYAML {
+		return decodeYAMLNodes(file, data, profiles)
+	}
+	lines := strings.Split(string(data), "\n")
+	values, err := Decode(data, format)
+	if located, ok := err.(*Error); ok {
+		located.Pos = position(file, lines, located.Pos.Line, located.Pos.Column)
+		return nil, nil, located
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	positions := make(map[string]Position)
+	if find, ok := positionFinders[format]; ok {
+		for key, pos := range find(data) {
+			positions[key] = position(file, lines, pos.Line, pos.Column)
+		}
+	}
+	return values, positions, nil
+}
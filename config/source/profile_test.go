@@ -0,0 +1,142 @@
+package config_source
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeYAMLDocuments(t *testing.T) {
+	const documents = "server:\n  port: 8080\n  host: localhost\n" +
+		"---\nprofiles: [prod]\nserver:\n  port: 9090\n" +
+		"---\non-profile: prod & cloud\nserver:\n  host: cloud\n" +
+		"---\nspring.config.activate.on-profile: dev\nserver:\n  port: 7070\n" +
+		"---\nprofiles: \"!prod\"\nserver:\n  debug: true\n"
+	tests := []struct {
+		name     string
+		data     string
+		profiles []string
+		values   map[string]string
+		err      bool
+	}{
+		{
+			name:   "only documents without condition and negations",
+			data:   documents,
+			values: map[string]string{"server.port": "8080", "server.host": "localhost", "server.debug": "true"},
+		},
+		{
+			name:     "profiles list",
+			data:     documents,
+			profiles: []string{"prod"},
+			values:   map[string]string{"server.port": "9090", "server.host": "localhost"},
+		},
+		{
+			name:     "and expression",
+			data:     documents,
+			profiles: []string{"cloud", "prod"},
+			values:   map[string]string{"server.port": "9090", "server.host": "cloud"},
+		},
+		{
+			name:     "spring key",
+			data:     documents,
+			profiles: []string{"dev"},
+			values:   map[string]string{"server.port": "7070", "server.host": "localhost", "server.debug": "true"},
+		},
+		{
+			name:     "later documents override earlier ones",
+			data:     "a: 1\nb: 1\n---\nprofiles: [x]\na: 2\n---\nprofiles: [y]\na: 3\nb: 3\n",
+			profiles: []string{"y", "x"},
+			values:   map[string]string{"a": "3", "b": "3"},
+		},
+		{
+			name:     "or and comma",
+			data:     "a: 0\n---\nprofiles: dev | test\na: 1\n---\nprofiles: stage, prod\nb: 2\n",
+			profiles: []string{"test", "prod"},
+			values:   map[string]string{"a": "1", "b": "2"},
+		},
+		{
+			name:     "list of conditions matches any",
+			data:     "a: 0\n---\nprofiles:\n  - dev\n  - test\na: 1\n",
+			profiles: []string{"test"},
+			values:   map[string]string{"a": "1"},
+		},
+		{
+			name:   "merge keys",
+			data:   "base: &base\n  port: 1\n  host: h\nserver:\n  <<: *base\n  port: 2\n",
+			values: map[string]string{"base.port": "1", "base.host": "h", "server.port": "2", "server.host": "h"},
+		},
+		{
+			name: "malformed expression",
+			data: "a: 0\n---\nprofiles: prod &\na: 1\n",
+			err:  true,
+		},
+		{
+			name: "syntax error",
+			data: "a: [1\n",
+			err:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := DecodeYAMLDocuments([]byte(test.data), test.profiles)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", values)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(values, test.values) {
+				t.Fatalf("values %v, want %v", values, test.values)
+			}
+		})
+	}
+}
+
+func TestMatchProfiles(t *testing.T) {
+	tests := []struct {
+		expression string
+		profiles   []string
+		matched    bool
+		err        bool
+	}{
+		{"prod", []string{"prod"}, true, false},
+		{"prod", nil, false, false},
+		{"!prod", nil, true, false},
+		{"! prod", []string{"prod"}, false, false},
+		{"prod & cloud", []string{"prod"}, false, false},
+		{"prod & cloud", []string{"cloud", "prod"}, true, false},
+		{"prod & !cloud", []string{"prod"}, true, false},
+		{"dev | test", []string{"test"}, true, false},
+		{"dev, test", []string{"dev"}, true, false},
+		{"dev | prod & cloud", []string{"prod"}, false, false},
+		{"prod &", []string{"prod"}, false, true},
+		{"!", nil, false, true},
+	}
+	for _, test := range tests {
+		matched, err := MatchProfiles(test.expression, test.profiles)
+		if (err != nil) != test.err || matched != test.matched {
+			t.Errorf("MatchProfiles(%q, %v) = %v, %v, want %v, error %v", test.expression, test.profiles, matched, err, test.matched, test.err)
+		}
+	}
+}
+
+func TestActiveProfiles(t *testing.T) {
+	defer os.Setenv(ProfilesEnv, os.Getenv(ProfilesEnv))
+	os.Setenv(ProfilesEnv, " prod, ,cloud ")
+	tests := []struct {
+		name     string
+		profiles []string
+		want     []string
+	}{
+		{"from environment", nil, []string{"prod", "cloud"}},
+		{"given", []string{"dev", " "}, []string{"dev"}},
+	}
+	for _, test := range tests {
+		if active := ActiveProfiles(test.profiles); !reflect.DeepEqual(active, test.want) {
+			t.Errorf("%s: ActiveProfiles = %v, want %v", test.name, active, test.want)
+		}
+	}
+}
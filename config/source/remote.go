@@ -0,0 +1,303 @@
+package config_source
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/utils"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTimeout = 10 * time.Second
+	DefaultRetries = 3
+	DefaultBackoff = 500 * time.Millisecond
+)
+
+// Remote 从配置中心拉取配置，支持超时、重试、认证头、TLS和ETag缓存
+type Remote struct {
+	URL string
+	// Format 配置格式：yaml、json或spring，为空时根据Content-Type和内容自动判断
+	Format string
+	// Profiles 激活的profile，用于选择yaml中的文档，为空时读取CONFIG_PROFILES
+	Profiles []string
+	// Timeout 单次请求的超时时间，默认10s
+	Timeout time.Duration
+	// Retries 失败后的重试次数，默认3次，小于0表示不重试
+	Retries int
+	// Backoff 第一次重试前的等待时间，之后每次翻倍
+	Backoff time.Duration
+
+	BearerToken string
+	Username    string
+	Password    string
+	Headers     map[string]string
+
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+
+	// CacheFile 保存最后一次成功拉取并且能够解析的配置，ETag和Content-Type分别保存在CacheFile.etag、CacheFile.type中；
+	// 返回304、配置中心无法连接或返回5xx时使用这份缓存，并按缓存的Content-Type判断格式，4xx错误直接返回
+	CacheFile string
+
+	// Client 不为空时直接使用，便于测试时替换
+	Client *http.Client
+
+	clientOnce sync.Once
+	httpClient *http.Client
+	clientErr  error
+}
+
+// StatusError 配置中心返回了非2xx状态码
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("GET %s: unexpected status %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (r *Remote) Name() string {
+	return "cloud:" + r.URL
+}
+
+// Load 拉取配置并按Format解析，返回 server.port 形式的key路径
+func (r *Remote) Load(ctx context.Context) (map[string]string, error) {
+	_, values, err := r.load(ctx)
+	return values, err
+}
+
+// Fetch 拉取配置内容并检查能否解析；配置中心不可用时返回缓存内容，没有缓存则返回错误
+func (r *Remote) Fetch(ctx context.Context) ([]byte, error) {
+	body, _, err := r.load(ctx)
+	return body, err
+}
+
+// load 新拉取的内容解析成功后才写入缓存，避免不完整或错误的内容覆盖上一份可用的缓存
+func (r *Remote) load(ctx context.Context) ([]byte, map[string]string, error) {
+	result, err := r.fetch(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	format := r.Format
+	if utils.IsBlank(format) {
+		format = DetectFormat(result.contentType)
+	}
+	values, _, err := decodeWithProfiles(r.Name(), result.body, format, ActiveProfiles(r.Profiles))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !result.cached {
+		if err := r.writeCache(result.body, result.etag, result.contentType); err != nil {
+			fmt.Printf("cannot write cloud config cache %s: %v\n", r.CacheFile, err)
+		}
+	}
+	return result.body, values, nil
+}
+
+// fetched 拉取的内容，cached为true时来自缓存
+type fetched struct {
+	body        []byte
+	etag        string
+	contentType string
+	cached      bool
+}
+
+// fetch 所有重试都失败时，无法连接或5xx错误使用缓存内容，4xx错误和ctx结束直接返回
+func (r *Remote) fetch(ctx context.Context) (*fetched, error) {
+	result, err := r.fetchWithRetry(ctx)
+	if err == nil {
+		return result, nil
+	}
+	if statusErr, ok := err.(*StatusError); (ok && statusErr.StatusCode < 500) || ctx.Err() != nil {
+		return nil, err
+	}
+	cached, cacheErr := r.readCache()
+	if cacheErr != nil {
+		return nil, err
+	}
+	fmt.Printf("cloud config unavailable (%v), use cached copy %s\n", err, r.CacheFile)
+	return cached, nil
+}
+
+func (r *Remote) fetchWithRetry(ctx context.Context) (*fetched, error) {
+	client, err := r.client()
+	if err != nil {
+		return nil, err
+	}
+	retries := r.Retries
+	if retries == 0 {
+		retries = DefaultRetries
+	} else if retries < 0 {
+		retries = 0
+	}
+	backoff := r.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		result, retry, err := r.fetchOnce(ctx, client)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+		if !retry {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+func (r *Remote) fetchOnce(ctx context.Context, client *http.Client) (result *fetched, retry bool, err error) {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	r.setHeaders(request)
+	cached, cacheErr := r.readCache()
+	if cacheErr == nil && cached.etag != "" {
+		request.Header.Set("If-None-Match", cached.etag)
+	}
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, true, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotModified && cacheErr == nil {
+		return cached, false, nil
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		ioutil.ReadAll(response.Body)
+		statusErr := &StatusError{URL: r.URL, StatusCode: response.StatusCode}
+		return nil, response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests, statusErr
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, true, err
+	}
+	return &fetched{body: body, etag: response.Header.Get("ETag"), contentType: response.Header.Get("Content-Type")}, false, nil
+}
+
+func (r *Remote) setHeaders(request *http.Request) {
+	if utils.NotBlank(r.BearerToken) {
+		request.Header.Set("Authorization", "Bearer "+r.BearerToken)
+	} else if utils.NotBlank(r.Username) {
+		request.SetBasicAuth(r.Username, r.Password)
+	}
+	for k, v := range r.Headers {
+		request.Header.Set(k, v)
+	}
+}
+
+// client 只创建一次，Load和Watch可以同时调用
+func (r *Remote) client() (*http.Client, error) {
+	if r.Client != nil {
+		return r.Client, nil
+	}
+	r.clientOnce.Do(func() {
+		r.httpClient, r.clientErr = r.newClient()
+	})
+	return r.httpClient, r.clientErr
+}
+
+func (r *Remote) newClient() (*http.Client, error) {
+	if utils.IsBlank(r.CAFile) && utils.IsBlank(r.CertFile) && !r.InsecureSkipVerify {
+		return http.DefaultClient, nil
+	}
+	tlsConfig := &tls.Config{InsecureSkipVerify: r.InsecureSkipVerify}
+	if utils.NotBlank(r.CAFile) {
+		pem, err := ioutil.ReadFile(r.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", r.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if utils.NotBlank(r.CertFile) {
+		cert, err := tls.LoadX509KeyPair(r.CertFile, r.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
+
+func (r *Remote) readCache() (*fetched, error) {
+	if utils.IsBlank(r.CacheFile) {
+		return nil, os.ErrNotExist
+	}
+	body, err := ioutil.ReadFile(r.CacheFile)
+	if err != nil {
+		return nil, err
+	}
+	return &fetched{body: body, etag: readCacheMeta(r.CacheFile + ".etag"), contentType: readCacheMeta(r.CacheFile + ".type"), cached: true}, nil
+}
+
+func readCacheMeta(path string) string {
+	by, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(by))
+}
+
+// writeCache 先写临时文件再重命名，避免进程中断时留下不完整的缓存
+func (r *Remote) writeCache(body []byte, etag, contentType string) error {
+	if utils.IsBlank(r.CacheFile) {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.CacheFile), 0755); err != nil {
+		return err
+	}
+	tmp := r.CacheFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, body, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, r.CacheFile); err != nil {
+		return err
+	}
+	if err := writeCacheMeta(r.CacheFile+".type", contentType); err != nil {
+		return err
+	}
+	return writeCacheMeta(r.CacheFile+".etag", etag)
+}
+
+// writeCacheMeta value为空时删除旧文件，避免与新的缓存内容不匹配
+func writeCacheMeta(path, value string) error {
+	if value == "" {
+		os.Remove(path)
+		return nil
+	}
+	return ioutil.WriteFile(path, []byte(value), 0600)
+}
@@ -0,0 +1,347 @@
+package config_source
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testCacheFile(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "remote-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "cloud", "config.cache")
+}
+
+func TestRemoteCachedFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		// second 第二次请求时配置中心的行为：304或不可用
+		second int
+	}{
+		{"toml not modified", "application/toml", "[server]\nport = 8080\n", http.StatusNotModified},
+		{"toml unavailable", "application/toml", "[server]\nport = 8080\n", http.StatusServiceUnavailable},
+		{"json not modified", "application/json", "{\"server\": {\"port\": 8080}}", http.StatusNotModified},
+		{"json unavailable", "application/json; charset=utf-8", "{\"server\": {\"port\": 8080}}", http.StatusServiceUnavailable},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests > 1 {
+					if test.second == http.StatusNotModified && r.Header.Get("If-None-Match") != `"v1"` {
+						t.Errorf("If-None-Match = %q", r.Header.Get("If-None-Match"))
+					}
+					w.WriteHeader(test.second)
+					return
+				}
+				w.Header().Set("Content-Type", test.contentType)
+				w.Header().Set("ETag", `"v1"`)
+				w.Write([]byte(test.body))
+			}))
+			defer server.Close()
+			remote := &Remote{URL: server.URL, CacheFile: testCacheFile(t), Retries: -1, Backoff: time.Millisecond}
+			for i := 0; i < 2; i++ {
+				values, err := remote.Load(context.Background())
+				if err != nil {
+					t.Fatalf("load %d: %v", i+1, err)
+				}
+				if values["server.port"] != "8080" {
+					t.Fatalf("load %d: values %v", i+1, values)
+				}
+			}
+			if requests != 2 {
+				t.Fatalf("expected 2 requests, got %d", requests)
+			}
+		})
+	}
+}
+
+func TestRemoteRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		retries  int
+		requests int
+		status   int
+	}{
+		{"success", []int{200}, 0, 1, 0},
+		{"retry server errors", []int{500, 502, 200}, 3, 3, 0},
+		{"retry too many requests", []int{429, 200}, 3, 2, 0},
+		{"give up after retries", []int{503, 503, 503}, 2, 3, 503},
+		{"no retry", []int{500, 200}, -1, 1, 500},
+		{"client error is not retried", []int{404, 200}, 3, 1, 404},
+		{"unauthorized is not retried", []int{401, 200}, 3, 1, 401},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var times []time.Time
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				times = append(times, time.Now())
+				status := test.statuses[len(times)-1]
+				w.WriteHeader(status)
+				if status == 200 {
+					w.Write([]byte("server:\n  port: 8080\n"))
+				}
+			}))
+			defer server.Close()
+			backoff := 10 * time.Millisecond
+			remote := &Remote{URL: server.URL, Retries: test.retries, Backoff: backoff}
+			body, err := remote.Fetch(context.Background())
+			if len(times) != test.requests {
+				t.Fatalf("expected %d requests, got %d", test.requests, len(times))
+			}
+			for i := 1; i < len(times); i++ {
+				if wait := times[i].Sub(times[i-1]); wait < backoff<<uint(i-1) {
+					t.Fatalf("retry %d after %v, want at least %v", i, wait, backoff<<uint(i-1))
+				}
+			}
+			if test.status == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(body) != "server:\n  port: 8080\n" {
+					t.Fatalf("body %q", body)
+				}
+				return
+			}
+			statusErr, ok := err.(*StatusError)
+			if !ok || statusErr.StatusCode != test.status {
+				t.Fatalf("expected status %d, got %v", test.status, err)
+			}
+		})
+	}
+}
+
+func TestRemoteRetryStopsWithContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	remote := &Remote{URL: server.URL, Retries: 5, Backoff: time.Second}
+	start := time.Now()
+	if _, err := remote.Fetch(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+		t.Fatalf("retry did not stop with the context, took %v", elapsed)
+	}
+}
+
+func TestRemoteCache(t *testing.T) {
+	tests := []struct {
+		name string
+		// handler 第二次请求的处理，为nil时关闭配置中心
+		handler http.HandlerFunc
+		cached  bool
+		port    string
+		etag    string
+		// err 第二次拉取应当返回错误，即使有缓存
+		err bool
+	}{
+		{
+			name: "not modified",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("If-None-Match") != `"v1"` {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				w.WriteHeader(http.StatusNotModified)
+			},
+			cached: true,
+			port:   "8080",
+			etag:   `"v1"`,
+		},
+		{
+			name: "changed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"v2"`)
+				w.Write([]byte("server:\n  port: 9090\n"))
+			},
+			cached: true,
+			port:   "9090",
+			etag:   `"v2"`,
+		},
+		{
+			name: "changed without etag",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("server:\n  port: 9090\n"))
+			},
+			cached: true,
+			port:   "9090",
+		},
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			cached: true,
+			port:   "8080",
+			etag:   `"v1"`,
+		},
+		{
+			name: "unauthorized",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+			},
+			cached: true,
+			port:   "8080",
+			etag:   `"v1"`,
+			err:    true,
+		},
+		{
+			name: "invalid body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("ETag", `"v2"`)
+				w.Write([]byte("server:\n  port: [9090\n"))
+			},
+			cached: true,
+			port:   "8080",
+			etag:   `"v1"`,
+			err:    true,
+		},
+		{
+			name:   "unreachable",
+			cached: true,
+			port:   "8080",
+			etag:   `"v1"`,
+		},
+		{
+			name:   "unreachable without cache",
+			cached: false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests > 1 {
+					test.handler(w, r)
+					return
+				}
+				w.Header().Set("ETag", `"v1"`)
+				w.Write([]byte("server:\n  port: 8080\n"))
+			}))
+			defer server.Close()
+			remote := &Remote{URL: server.URL, Retries: -1}
+			if test.cached {
+				remote.CacheFile = testCacheFile(t)
+			}
+			if _, err := remote.Fetch(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if test.handler == nil {
+				server.Close()
+			}
+			body, err := remote.Fetch(context.Background())
+			want := "server:\n  port: " + test.port + "\n"
+			switch {
+			case !test.cached || test.err:
+				if err == nil {
+					t.Fatalf("expected an error, got %q", body)
+				}
+				if !test.cached {
+					return
+				}
+			case err != nil:
+				t.Fatal(err)
+			case string(body) != want:
+				t.Fatalf("body %q, want %q", body, want)
+			}
+			if cached, _ := ioutil.ReadFile(remote.CacheFile); string(cached) != want {
+				t.Fatalf("cached body %q, want %q", cached, want)
+			}
+			etag, _ := ioutil.ReadFile(remote.CacheFile + ".etag")
+			if string(etag) != test.etag {
+				t.Fatalf("cached etag %q, want %q", etag, test.etag)
+			}
+		})
+	}
+}
+
+func TestRemoteHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		remote *Remote
+		want   map[string]string
+	}{
+		{
+			name:   "bearer",
+			remote: &Remote{BearerToken: "token"},
+			want:   map[string]string{"Authorization": "Bearer token"},
+		},
+		{
+			name:   "basic",
+			remote: &Remote{Username: "user", Password: "pass"},
+			want:   map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+		},
+		{
+			name:   "bearer before basic",
+			remote: &Remote{BearerToken: "token", Username: "user", Password: "pass"},
+			want:   map[string]string{"Authorization": "Bearer token"},
+		},
+		{
+			name:   "custom headers",
+			remote: &Remote{BearerToken: "token", Headers: map[string]string{"X-Config-Label": "main", "Authorization": "Token custom"}},
+			want:   map[string]string{"X-Config-Label": "main", "Authorization": "Token custom"},
+		},
+		{
+			name:   "none",
+			remote: &Remote{},
+			want:   map[string]string{"Authorization": ""},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var header http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header
+				w.Write([]byte("{}"))
+			}))
+			defer server.Close()
+			remote := test.remote
+			remote.URL = server.URL
+			if _, err := remote.Fetch(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range test.want {
+				if header.Get(k) != v {
+					t.Fatalf("%s = %q, want %q", k, header.Get(k), v)
+				}
+			}
+		})
+	}
+}
+
+func TestRemoteConcurrentLoad(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("server:\n  port: 8080\n"))
+	}))
+	defer server.Close()
+	remote := &Remote{URL: server.URL, InsecureSkipVerify: true}
+	errs := make(chan error, 4)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := remote.Load(context.Background())
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
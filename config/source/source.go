@@ -0,0 +1,264 @@
+package config_source
+
+import (
+	"context"
+	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/utils"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWatchInterval 轮询文件变化的默认间隔
+const DefaultWatchInterval = 2 * time.Second
+
+// Source 配置来源，Load返回 server.port 形式的key路径到配置值的映射
+type Source interface {
+	Name() string
+	Load(ctx context.Context) (map[string]string, error)
+}
+
+// Watcher 可选接口，来源内容变化时调用onChange，直到ctx结束
+type Watcher interface {
+	Watch(ctx context.Context, onChange func()) error
+}
+
+// File 本地配置文件
+type File struct {
+	Path string
+	// Format 为空时根据扩展名判断，无法判断时按yaml解析
+	Format string
+	// Profiles 激活的profile，用于选择yaml中的文档，为空时读取CONFIG_PROFILES
+	Profiles []string
+	// Optional 为true时文件不存在不算错误
+	Optional bool
+	// Interval Watch轮询文件变化的间隔，默认2s
+	Interval time.Duration
+
+	lock      sync.Mutex
+	positions map[string]Position
+}
+
+func (f *File) Name() string {
+	return "file:" + f.Path
+}
+
+func (f *File) Load(ctx context.Context) (map[string]string, error) {
+	data, err := ioutil.ReadFile(f.Path)
+	if err != nil {
+		if f.Optional && os.IsNotExist(err) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+	format := f.Format
+	if utils.IsBlank(format) {
+		format = FormatOf(f.Path)
+	}
+	values, positions, err := decodeWithProfiles(f.Path, data, format, ActiveProfiles(f.Profiles))
+	if err != nil {
+		return nil, err
+	}
+	f.lock.Lock()
+	f.positions = positions
+	f.lock.Unlock()
+	return values, nil
+}
+
+func (f *File) Position(key string) (Position, bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return lookupPosition(f.positions, key)
+}
+
+// Watch 轮询文件的修改时间和大小
+func (f *File) Watch(ctx context.Context, onChange func()) error {
+	interval := f.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	last := f.stat()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			current := f.stat()
+			if current != last {
+				last = current
+				onChange()
+			}
+		}
+	}
+}
+
+func (f *File) stat() string {
+	info, err := os.Stat(f.Path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// Args 命令行参数，格式为 -server.port=8080 或 --server.port=8080
+type Args struct {
+	Args []string
+}
+
+func (a *Args) Name() string {
+	return "args"
+}
+
+func (a *Args) Load(ctx context.Context) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, arg := range a.Args {
+		index := strings.Index(arg, "=")
+		if !strings.HasPrefix(arg, "-") || index == -1 {
+			continue
+		}
+		key := strings.TrimLeft(arg[:index], "-")
+		if utils.NotBlank(key) {
+			values[key] = arg[index+1:]
+		}
+	}
+	return values, nil
+}
+
+// Env 环境变量，SERVER_DB_MAXCONN 这类名称由RelaxKeys映射到 server.db.maxConn；
+// Prefix不为空时只读取带该前缀的变量，并在映射前去掉前缀
+type Env struct {
+	Prefix string
+}
+
+func (e *Env) Name() string {
+	return "env"
+}
+
+func (e *Env) Load(ctx context.Context) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, item := range os.Environ() {
+		index := strings.Index(item, "=")
+		if index <= 0 || !strings.HasPrefix(item[:index], e.Prefix) {
+			continue
+		}
+		values[item[len(e.Prefix):index]] = item[index+1:]
+	}
+	return values, nil
+}
+
+// Memory 内存中的配置，适合测试或由代码计算出的配置
+type Memory struct {
+	ID     string
+	Values map[string]string
+}
+
+func (m *Memory) Name() string {
+	if utils.IsBlank(m.ID) {
+		return "memory"
+	}
+	return "memory:" + m.ID
+}
+
+func (m *Memory) Load(ctx context.Context) (map[string]string, error) {
+	values := make(map[string]string, len(m.Values))
+	for k, v := range m.Values {
+		values[k] = v
+	}
+	return values, nil
+}
+
+// Text 内存中的配置内容，与File相同按Format解析，错误中的位置为 ID:line:col
+type Text struct {
+	ID      string
+	Content string
+	// Format 为空时按yaml解析
+	Format string
+	// Profiles 激活的profile，用于选择yaml中的文档；为空时只使用没有声明profile的文档，不读取CONFIG_PROFILES
+	Profiles []string
+
+	lock      sync.Mutex
+	positions map[string]Position
+}
+
+func (t *Text) Name() string {
+	if utils.IsBlank(t.ID) {
+		return "text"
+	}
+	return "text:" + t.ID
+}
+
+func (t *Text) Load(ctx context.Context) (map[string]string, error) {
+	values, positions, err := decodeWithProfiles(t.Name(), []byte(t.Content), t.Format, t.Profiles)
+	if err != nil {
+		return nil, err
+	}
+	t.lock.Lock()
+	t.positions = positions
+	t.lock.Unlock()
+	return values, nil
+}
+
+func (t *Text) Position(key string) (Position, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return lookupPosition(t.positions, key)
+}
+
+// RelaxKeys 把 SERVER_DB_MAXCONN 这类环境变量风格的key映射到已知的 server.db.maxConn，
+// 已知的key和无法匹配的key保持不变
+func RelaxKeys(values map[string]string, keys []string) map[string]string {
+	known := make(map[string]string, len(keys))
+	for _, key := range keys {
+		known[key] = key
+	}
+	envKeys := make(map[string]string, len(keys))
+	for _, key := range keys {
+		envKeys[utils.EnvName(key)] = key
+	}
+	result := make(map[string]string, len(values))
+	for k, v := range values {
+		if _, ok := known[k]; ok {
+			result[k] = v
+		} else if key, ok := envKeys[utils.EnvName(k)]; ok {
+			if _, exact := values[key]; !exact {
+				result[key] = v
+			}
+		} else {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// Watch 启动所有实现了Watcher的来源，任一来源变化都会调用onChange；
+// ctx结束时返回nil，某个来源出错时返回该错误
+func Watch(ctx context.Context, sources []Source, onChange func()) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(sources))
+	count := 0
+	for _, source := range sources {
+		watcher, ok := source.(Watcher)
+		if !ok {
+			continue
+		}
+		count++
+		go func(name string, watcher Watcher) {
+			if err := watcher.Watch(ctx, onChange); err != nil {
+				errs <- fmt.Errorf("watch %s: %v", name, err)
+				return
+			}
+			errs <- nil
+		}(source.Name(), watcher)
+	}
+	for i := 0; i < count; i++ {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+	return nil
+}
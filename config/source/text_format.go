@@ -0,0 +1,197 @@
+package config_source
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// decodeDotenv 解析 KEY=value 格式的.env文件，支持export前缀、引号和行尾注释；
+// SERVER_PORT 这类key由RelaxKeys映射到 server.port
+func decodeDotenv(data []byte) (map[string]string, error) {
+	values, _, err := parseDotenv(data)
+	return values, err
+}
+
+// parseDotenv 与decodeDotenv相同，同时返回每个值所在的行和列，错误为带行号的*Error
+func parseDotenv(data []byte) (map[string]string, map[string]Position, error) {
+	values := make(map[string]string)
+	positions := make(map[string]Position)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := scanner.Text()
+		text := strings.TrimSpace(raw)
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		// start text在raw中的字节偏移，用于计算列
+		start := strings.Index(raw, text)
+		if strings.HasPrefix(text, "export ") {
+			trimmed := strings.TrimSpace(text[len("export "):])
+			start += len(text) - len(trimmed)
+			text = trimmed
+		}
+		index := strings.Index(text, "=")
+		if index <= 0 {
+			return nil, nil, &Error{Pos: Position{Line: line, Column: columnOf(raw, start)}, Message: "expected KEY=value"}
+		}
+		key := strings.TrimSpace(text[:index])
+		value := strings.TrimSpace(text[index+1:])
+		column := columnOf(raw, start+len(text)-len(value))
+		switch {
+		case strings.HasPrefix(value, `"`):
+			end := closingQuote(value)
+			if end == -1 {
+				return nil, nil, &Error{Pos: Position{Line: line, Column: column}, Message: "unterminated quoted value"}
+			}
+			unquoted, err := strconv.Unquote(value[:end+1])
+			if err != nil {
+				return nil, nil, &Error{Pos: Position{Line: line, Column: column}, Message: err.Error()}
+			}
+			value = unquoted
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end == -1 {
+				return nil, nil, &Error{Pos: Position{Line: line, Column: column}, Message: "unterminated quoted value"}
+			}
+			value = value[1 : end+1]
+		default:
+			if index := strings.Index(value, " #"); index != -1 {
+				value = strings.TrimSpace(value[:index])
+			}
+		}
+		values[key] = value
+		positions[key] = Position{Line: line, Column: column}
+	}
+	return values, positions, scanner.Err()
+}
+
+// columnOf 字节偏移offset在行中对应的列，从1开始按字符计算
+func columnOf(line string, offset int) int {
+	if offset < 0 || offset > len(line) {
+		return 1
+	}
+	return utf8.RuneCountInString(line[:offset]) + 1
+}
+
+func closingQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// decodeProperties 解析Java .properties文件，支持 = : 和空白分隔、续行以及转义字符
+func decodeProperties(data []byte) (map[string]string, error) {
+	values, _, err := parseProperties(data)
+	return values, err
+}
+
+// parseProperties 与decodeProperties相同，同时返回每个值开始的行和列，错误为带行号的*Error
+func parseProperties(data []byte) (map[string]string, map[string]Position, error) {
+	values := make(map[string]string)
+	positions := make(map[string]Position)
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		text := strings.TrimLeft(lines[i], " \t\f")
+		if text == "" || text[0] == '#' || text[0] == '!' {
+			continue
+		}
+		// first 第一行中属于text的长度，续行的反斜杠不算在内
+		line, indent, first := i+1, len(lines[i])-len(text), len(text)
+		for continuesLine(text) && i+1 < len(lines) {
+			if i+1 == line {
+				first--
+			}
+			i++
+			text = text[:len(text)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		keyEnd := len(text)
+		for j := 0; j < len(text); j++ {
+			if text[j] == '\\' {
+				j++
+				continue
+			}
+			if text[j] == '=' || text[j] == ':' || text[j] == ' ' || text[j] == '\t' || text[j] == '\f' {
+				keyEnd = j
+				break
+			}
+		}
+		rest := strings.TrimLeft(text[keyEnd:], " \t\f")
+		if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+			rest = strings.TrimLeft(rest[1:], " \t\f")
+		}
+		// 值从续行开始时使用key的位置
+		pos := Position{Line: line, Column: columnOf(lines[line-1], indent)}
+		if offset := len(text) - len(rest); rest != "" && offset < first {
+			pos.Column = columnOf(lines[line-1], indent+offset)
+		}
+		key, err := unescapeProperty(text[:keyEnd])
+		if err != nil {
+			return nil, nil, &Error{Pos: Position{Line: line, Column: columnOf(lines[line-1], indent)}, Message: err.Error()}
+		}
+		value, err := unescapeProperty(rest)
+		if err != nil {
+			return nil, nil, &Error{Pos: pos, Message: err.Error()}
+		}
+		values[key] = value
+		positions[key] = pos
+	}
+	return values, positions, nil
+}
+
+// continuesLine 行尾有奇数个反斜杠时表示下一行是续行
+func continuesLine(text string) bool {
+	count := 0
+	for i := len(text) - 1; i >= 0 && text[i] == '\\'; i-- {
+		count++
+	}
+	return count%2 == 1
+}
+
+func unescapeProperty(text string) (string, error) {
+	if !strings.Contains(text, "\\") {
+		return text, nil
+	}
+	var builder strings.Builder
+	for i := 0; i < len(text); i++ {
+		if text[i] != '\\' || i+1 == len(text) {
+			builder.WriteByte(text[i])
+			continue
+		}
+		i++
+		switch text[i] {
+		case 't':
+			builder.WriteByte('\t')
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 'f':
+			builder.WriteByte('\f')
+		case 'u':
+			if i+5 > len(text) {
+				return "", fmt.Errorf("malformed \\u escape")
+			}
+			code, err := strconv.ParseUint(text[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("malformed \\u escape: %v", err)
+			}
+			builder.WriteRune(rune(code))
+			i += 4
+		default:
+			builder.WriteByte(text[i])
+		}
+	}
+	return builder.String(), nil
+}
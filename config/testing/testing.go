@@ -0,0 +1,95 @@
+package config_testing
+
+import (
+	"context"
+	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/lifecycle"
+	"github.com/orange0224/go-injector-yaml/config/source"
+	"reflect"
+	"sync/atomic"
+)
+
+// T testing.T、testing.B中用到的方法，本包不导入testing，生成的config包可以使用它
+type T interface {
+	Helper()
+	Cleanup(func())
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+var sourceID int64
+
+// nextID 每个来源不同的名称，错误信息中可以区分
+func nextID(kind string) string {
+	return fmt.Sprintf("%s%d", kind, atomic.AddInt64(&sourceID, 1))
+}
+
+// Map 内存中的配置来源，values可以嵌套，例如 {"server": {"port": 8080}}，也可以写成 {"server.port": 8080}；
+// 任意类型的slice展开为 name[0]，key为string的map展开为 name.key
+func Map(values map[string]interface{}) config_source.Source {
+	flat := make(map[string]string)
+	config_source.Flatten("", tree(values), flat)
+	return &config_source.Memory{ID: nextID("map"), Values: flat}
+}
+
+// YAML yaml文本作为配置来源，profiles选择 --- 分隔的文档，为空时只使用没有声明profile的文档，不读取CONFIG_PROFILES
+func YAML(text string, profiles ...string) config_source.Source {
+	return &config_source.Text{ID: nextID("yaml"), Content: text, Profiles: profiles}
+}
+
+// Set 覆盖单个配置项，放在其他来源之后，value为map或slice时展开为下一级的key
+func Set(key string, value interface{}) config_source.Source {
+	values := make(map[string]string)
+	config_source.Flatten(key, tree(value), values)
+	return &config_source.Memory{ID: "set " + key, Values: values}
+}
+
+// tree 把任意类型的slice和key为string的map转换为Flatten可以展开的[]interface{}、map[string]interface{}，[]byte作为字符串
+func tree(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes())
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = tree(v.Index(i).Interface())
+		}
+		return items
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return value
+		}
+		items := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			items[key.String()] = tree(v.MapIndex(key).Interface())
+		}
+		return items
+	}
+	return value
+}
+
+// Start 启动lifecycle中的组件，测试结束时停止；启动失败时调用t.Fatalf
+func Start(t T, lifecycle *config_lifecycle.Lifecycle) {
+	t.Helper()
+	if err := lifecycle.Start(context.Background()); err != nil {
+		t.Fatalf("cannot start components: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := lifecycle.Stop(context.Background()); err != nil {
+			t.Errorf("cannot stop components: %v", err)
+		}
+	})
+}
+
+// Scope 返回带有新Scope的ctx，用于获取request组件，测试结束时停止其中创建的组件
+func Scope(t T) context.Context {
+	scope := config_lifecycle.NewScope()
+	t.Cleanup(func() {
+		if err := scope.End(context.Background()); err != nil {
+			t.Errorf("cannot end scope: %v", err)
+		}
+	})
+	return config_lifecycle.WithScope(context.Background(), scope)
+}
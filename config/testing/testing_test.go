@@ -0,0 +1,84 @@
+package config_testing
+
+import (
+	"context"
+	"github.com/orange0224/go-injector-yaml/config/source"
+	"os"
+	"reflect"
+	"testing"
+)
+
+var testKeys = config_source.Keys{
+	"server":       config_source.KeySection,
+	"server.port":  config_source.KeyValue,
+	"server.tags":  config_source.KeyList,
+	"server.extra": config_source.KeyMap,
+}
+
+func TestSources(t *testing.T) {
+	want := map[string]string{"server.tags[0]": "a", "server.tags[1]": "b", "server.extra.k": "v", "server.extra.n": "1"}
+	tests := []struct {
+		name   string
+		source config_source.Source
+		want   map[string]string
+	}{
+		{"yaml", YAML("server:\n  tags: [a, b]\n  extra:\n    k: v\n    n: 1\n"), want},
+		{"nested map", Map(map[string]interface{}{"server": map[string]interface{}{
+			"tags":  []string{"a", "b"},
+			"extra": map[string]interface{}{"k": "v", "n": 1},
+		}}), want},
+		{"flat map", Map(map[string]interface{}{"server.tags": []interface{}{"a", "b"}, "server.extra": map[string]int{"n": 1}}),
+			map[string]string{"server.tags[0]": "a", "server.tags[1]": "b", "server.extra.n": "1"}},
+		{"set list", Set("server.tags", []string{"a", "b"}), map[string]string{"server.tags[0]": "a", "server.tags[1]": "b"}},
+		{"set map", Set("server.extra", map[string]string{"k": "v"}), map[string]string{"server.extra.k": "v"}},
+		{"set value", Set("server.port", 8080), map[string]string{"server.port": "8080"}},
+		{"set bytes", Set("server.port", []byte("80")), map[string]string{"server.port": "80"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := test.source.Load(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(values, test.want) {
+				t.Fatalf("values %v, want %v", values, test.want)
+			}
+			if errs := config_source.UnknownKeys(test.source, values, testKeys); len(errs) > 0 {
+				t.Fatalf("unknown keys: %v", errs)
+			}
+		})
+	}
+}
+
+func TestYAMLProfiles(t *testing.T) {
+	previous, ok := os.LookupEnv(config_source.ProfilesEnv)
+	os.Setenv(config_source.ProfilesEnv, "prod")
+	defer func() {
+		if ok {
+			os.Setenv(config_source.ProfilesEnv, previous)
+		} else {
+			os.Unsetenv(config_source.ProfilesEnv)
+		}
+	}()
+	text := "server:\n  port: 80\n---\nprofiles: [prod]\nserver:\n  port: 443\n"
+	tests := []struct {
+		name     string
+		profiles []string
+		want     string
+	}{
+		{"no profiles ignores env", nil, "80"},
+		{"explicit profile", []string{"prod"}, "443"},
+		{"other profile", []string{"dev"}, "80"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, err := YAML(text, test.profiles...).Load(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if values["server.port"] != test.want {
+				t.Fatalf("server.port is %q, want %q", values["server.port"], test.want)
+			}
+		})
+	}
+}
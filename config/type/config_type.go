@@ -2,21 +2,50 @@ package config_type
 
 import (
 	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/model"
 	"github.com/orange0224/go-injector-yaml/config/utils"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"io/ioutil"
-	"log"
 	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 )
 
 type TypeScanner struct {
 	fileSeparator string
 	ConfigDir     string
-	importMap     map[string]int
-	typeMap       map[string][]string
-	orderMap      map[string]int
-	typeAlias     map[string]string
+	// SchemaPath 不为空时同时把JSON Schema写入该文件
+	SchemaPath string
+	// DocsPath 不为空时同时把Markdown格式的配置参考文档写入该文件
+	DocsPath string
+	// SamplePath 不为空时同时生成带注释的示例配置文件，例如application.example.yaml
+	SamplePath string
+	// TagKey 读取配置项名称的标签，为空时使用yaml；必须与Generator.TagKey相同
+	TagKey string
+	// EnvPrefix 与Loader.EnvPrefix相同，用于生成文档中的环境变量名；为空时Loader不读取环境变量，文档中不列出
+	EnvPrefix string
+	// Packages 扫描的目录或go包模式，相对于ConfigDir，例如 ./...、./internal/...；为空时只扫描 ./config。
+	// 配置类型可以放在使用它的包中，生成的ApplicationConfig会导入这些包
+	Packages []string
+	// Include、Exclude 相对于ConfigDir的路径或文件名的glob，dir/... 表示目录下的所有文件
+	Include []string
+	Exclude []string
+	// BuildTags 扫描时启用的构建标签；_test.go和生成的文件总是跳过
+	BuildTags []string
+	orderMap  map[string]int
+	typeAlias map[string]string
+	models    map[string]*config_model.Type
+	// declarations 同名类型的所有声明，用于发现不同包中重名的类型
+	declarations map[string][]*config_model.Type
+	graph        *config_model.Graph
+	// components 扫描到的@Component，生成代码之前检查它们的依赖
+	components []*config_model.Component
+	methods    []*config_model.LifecycleMethod
+	executes   []*config_model.Execute
 }
 
 func (t *TypeScanner) Begin() {
@@ -24,9 +53,35 @@ func (t *TypeScanner) Begin() {
 	t.initVariable()
 	t.scanTypeInfo(t.ConfigDir)
 	topType := t.getTopType()
+	if err := t.check(topType); err != nil {
+		panic(err)
+	}
 	applicationDefinition := t.combinationType(topType)
 	fmt.Println(applicationDefinition)
 	t.writeResultToFile(applicationDefinition, t.ConfigDir+t.fileSeparator+"config")
+	if utils.NotBlank(t.SchemaPath) {
+		schema, err := t.buildSchema(topType)
+		if err != nil {
+			panic(fmt.Errorf("cannot generate schema: %v", err))
+		}
+		if err := ioutil.WriteFile(t.SchemaPath, append(schema, '\n'), 0644); err != nil {
+			fmt.Println("Cannot create file", t.SchemaPath)
+		}
+	}
+	if utils.NotBlank(t.DocsPath) {
+		if err := ioutil.WriteFile(t.DocsPath, t.buildDocs(topType), 0644); err != nil {
+			fmt.Println("Cannot create file", t.DocsPath)
+		}
+	}
+	if utils.NotBlank(t.SamplePath) {
+		sample, err := t.buildSample(topType)
+		if err != nil {
+			panic(fmt.Errorf("cannot generate sample config: %v", err))
+		}
+		if err := ioutil.WriteFile(t.SamplePath, sample, 0644); err != nil {
+			fmt.Println("Cannot create file", t.SamplePath)
+		}
+	}
 }
 
 func (t *TypeScanner) checkConfig() {
@@ -48,246 +103,226 @@ func (t *TypeScanner) writeResultToFile(result, configPath string) {
 func (t *TypeScanner) combinationType(topType []string) string {
 	header := `
 package config
-type ApplicationConfig struct{
 `
+	models := make([]*config_model.Type, 0, len(topType))
+	for _, name := range topType {
+		if typ := t.models[name]; typ != nil {
+			models = append(models, typ)
+		}
+	}
+	qualifiers := config_model.Qualifiers(config_model.ImportPath(t.ConfigDir+t.fileSeparator+"config"), models)
+	if imports := config_model.ImportLines(qualifiers, models); len(imports) > 0 {
+		header += "import (\n" + strings.Join(imports, "\n") + "\n)\n"
+	}
+	header += "type ApplicationConfig struct{\n"
 	types := ""
 	for i := range topType {
-		types += strings.ToUpper(t.typeAlias[topType[i]][0:1]) + t.typeAlias[topType[i]][1:] + "  " + topType[i] + " `yaml:\"" + t.typeAlias[topType[i]] + "\"`" + "\n"
+		typeName := topType[i]
+		if typ := t.models[topType[i]]; typ != nil {
+			typeName = typ.QualifiedName(qualifiers)
+		}
+		types += strings.ToUpper(t.typeAlias[topType[i]][0:1]) + t.typeAlias[topType[i]][1:] + "  " + typeName + " `" + t.tagKey() + ":\"" + t.typeAlias[topType[i]] + "\"`" + "\n"
 	}
 	footer := `}
 var applicationConfig ApplicationConfig
 
 func GetApplicationConfig() ApplicationConfig{
+configLock.RLock()
+defer configLock.RUnlock()
 return applicationConfig
 }
 `
 	return header + types + footer
 }
 
+func (t *TypeScanner) scanOptions(dir string) config_model.ScanOptions {
+	packages := t.Packages
+	if len(packages) == 0 {
+		packages = []string{"./config"}
+	}
+	return config_model.ScanOptions{Root: dir, Patterns: packages, Include: t.Include, Exclude: t.Exclude, BuildTags: t.BuildTags}
+}
+
+func (t *TypeScanner) tagKey() string {
+	if utils.IsBlank(t.TagKey) {
+		return config_model.DefaultTagKey
+	}
+	return t.TagKey
+}
+
 func (t *TypeScanner) initVariable() {
 	if runtime.GOOS == "windows" {
 		t.fileSeparator = "\\"
 	} else {
 		t.fileSeparator = "/"
 	}
-	t.importMap = make(map[string]int, 0)
-	t.typeMap = make(map[string][]string)
 	t.orderMap = make(map[string]int)
 	t.typeAlias = make(map[string]string)
+	t.models = make(map[string]*config_model.Type)
+	t.declarations = make(map[string][]*config_model.Type)
+	t.components = nil
+	t.methods = nil
+	t.executes = nil
 }
 
 func (t *TypeScanner) scanTypeInfo(dir string) {
-	files, _ := t.GetScanFiles(dir + t.fileSeparator + "config")
+	files, err := config_model.ScanFiles(t.scanOptions(dir))
+	if err != nil {
+		panic(fmt.Errorf("cannot scan config types: %v", err))
+	}
+	fset := token.NewFileSet()
 	for i := range files {
 		filename := files[i][strings.LastIndex(files[i], t.fileSeparator)+1:]
 		if filename == "config_loader.go" {
 			continue
 		}
-		types, imports, _, aliasMap := t.GetConfigurations(files[i])
-		for _, imp := range imports {
-			t.importMap[imp] = 1
+		file, err := config_model.ParseFile(fset, files[i], t.TagKey)
+		if err != nil {
+			panic(fmt.Errorf("cannot parse %s: %v", files[i], err))
 		}
-		for _, typ := range types {
-			name := t.GetTypeName(typ[0])
-			t.typeMap[name] = typ
-			t.orderMap[name] = 0
+		for _, typ := range file.Types {
+			if _, ok := t.models[typ.Name]; !ok {
+				t.models[typ.Name] = typ
+			}
+			t.declarations[typ.Name] = append(t.declarations[typ.Name], typ)
 		}
-		for typ, value := range aliasMap {
-			t.typeAlias[typ] = value
+		t.components = append(t.components, file.Components...)
+		t.methods = append(t.methods, file.Methods...)
+		t.executes = append(t.executes, file.Executes...)
+	}
+	for name, typ := range t.models {
+		if typ.Configuration {
+			t.orderMap[name] = 0
+			t.typeAlias[name] = typ.Alias
 		}
 	}
+	t.graph = config_model.NewGraph(t.models)
 }
 
+// getTopType 没有被其他@Configuration类型的字段引用的类型，它们成为ApplicationConfig的字段
 func (t *TypeScanner) getTopType() []string {
-	for typ1 := range t.typeMap {
-		for typ2 := range t.typeMap {
-			if typ1 == typ2 {
-				continue
-			}
-			if t.ContainsType(typ1, typ2) {
-				t.orderMap[typ2] = -1
-			}
-		}
+	names := make([]string, 0, len(t.orderMap))
+	for name := range t.orderMap {
+		names = append(names, name)
 	}
-	top := make([]string, 0)
-	for k, v := range t.orderMap {
-		if v == 0 {
-			top = append(top, k)
-		}
+	top := t.graph.Roots(names)
+	for name := range t.orderMap {
+		t.orderMap[name] = -1
+	}
+	for _, name := range top {
+		t.orderMap[name] = 0
 	}
 	return top
 }
 
+// ContainsType type1的字段类型中是否引用了type2，包括指针、切片、map和 pkg.Type
 func (t *TypeScanner) ContainsType(type1, type2 string) bool {
-	typeInfo := t.typeMap[type1]
-	for i := range typeInfo {
-		strs := strings.Split(typeInfo[i], " ")
-		for j := range strs {
-			if strs[j] == type2 {
-				return true
-			}
-		}
-	}
-	return false
+	return t.graph.Contains(type1, type2)
+}
+
+// Graph 扫描到的结构体之间的依赖图
+func (t *TypeScanner) Graph() *config_model.Graph {
+	return t.graph
 }
 
+// GetTypeName 返回 type Xxx struct 一行中的类型名，不是结构体定义时返回空字符串
+//
+// Deprecated: 配置类型改为通过go/ast扫描，类型名使用 config_model.Type 的Name
 func (t *TypeScanner) GetTypeName(line string) string {
 	typeIndex := strings.Index(line, "type ")
-	structIndex := strings.Index(line, "struct ")
+	structIndex := strings.Index(line, "struct")
 	if typeIndex != -1 && structIndex != -1 && typeIndex < structIndex {
 		return strings.TrimSpace(line[typeIndex+len("type ") : structIndex])
 	}
 	return ""
 }
 
+// GetTypeAlias 返回注释行中的 @Alias=，没有时返回首字母小写的类型名
+//
+// Deprecated: 配置类型改为通过go/ast扫描，别名使用 config_model.Type 的Alias
 func (t *TypeScanner) GetTypeAlias(line, typeName string) string {
-	index := strings.Index(line, "@Alias=")
-	if index == -1 {
-		return strings.ToLower(typeName[0:1]) + typeName[1:]
+	if index := strings.Index(line, "@Alias="); index != -1 {
+		if fields := strings.Fields(line[index+len("@Alias="):]); len(fields) > 0 {
+			return fields[0]
+		}
 	}
-	split := strings.Split(line[index+len("@Alias="):], " ")
-	return split[0]
+	return config_model.LowerCamel(typeName)
 }
 
+// GetScanFiles 返回root下的go文件和不以 . 开头的子目录，不递归
+//
+// Deprecated: 使用 config_model.ScanFiles，它支持包模式、include/exclude和构建标签
 func (t *TypeScanner) GetScanFiles(root string) (files, dirs []string) {
-	//获取文件或目录相关信息
-	fileInfoList, err := ioutil.ReadDir(root)
+	infos, err := ioutil.ReadDir(root)
 	if err != nil {
-		log.Fatal(err)
-		return
+		panic(fmt.Errorf("cannot scan config types: %v", err))
 	}
-	for _, item := range fileInfoList {
-		if item.IsDir() && item.Name()[0] != '.' {
-			dirs = append(dirs, item.Name())
-		} else {
-			if strings.Index(item.Name(), ".go") != -1 {
-				files = append(files, item.Name())
-			}
+	for _, info := range infos {
+		path := filepath.Join(root, info.Name())
+		if info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+			dirs = append(dirs, path)
+		} else if !info.IsDir() && strings.HasSuffix(info.Name(), ".go") {
+			files = append(files, path)
 		}
 	}
-	for i := range files {
-		files[i] = root + t.fileSeparator + files[i]
-	}
-	for i := range dirs {
-		dirs[i] = root + t.fileSeparator + dirs[i]
-	}
-	return
+	return files, dirs
 }
 
-// GetConfigurations 获取配置类型和所在包名，再获取import
+// GetConfigurations 返回文件中每个@Configuration结构体的源码行、文件的import、包名以及类型名到@Alias的映射
+//
+// Deprecated: 使用 config_model.ParseFile，它返回解析后的字段、标签、注解和默认值
 func (t *TypeScanner) GetConfigurations(filePath string) (types [][]string, imports []string, packageName string, aliasMap map[string]string) {
 	types = make([][]string, 0)
-	fileContent, err := t.getFileContentAsStringLines(filePath)
+	imports = make([]string, 0)
+	aliasMap = make(map[string]string)
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments)
 	if err != nil {
-		return
+		return types, imports, "", aliasMap
 	}
-	indexes := t.findConfigurationsPosition(fileContent)
-	configurations, aliasMap := t.getConfiguration(fileContent, indexes)
-	packageName = t.getPackage(fileContent)
-	imports = t.getImports(fileContent)
-	return configurations, imports, packageName, aliasMap
-}
-
-func (t *TypeScanner) getFileContentAsStringLines(filePath string) ([]string, error) {
-	var result []string
-	by, err := ioutil.ReadFile(filePath)
+	content, err := ioutil.ReadFile(filePath)
 	if err != nil {
-		return nil, err
+		return types, imports, "", aliasMap
 	}
-	str := string(by)
-	for _, lineStr := range strings.Split(str, "\n") {
-		lineStr = strings.TrimSpace(lineStr)
-		if lineStr == "" {
-			continue
+	lines := strings.Split(string(content), "\n")
+	for _, spec := range file.Imports {
+		if path, err := strconv.Unquote(spec.Path.Value); err == nil {
+			imports = append(imports, path)
 		}
-		result = append(result, lineStr)
-	}
-	return result, nil
-}
-
-//判断本行是否是注释
-func (t *TypeScanner) IsCodeAnnotation(str string) bool {
-	if strings.TrimSpace(str)[:2] == "//" {
-		return true
 	}
-	return false
-}
-
-//获取Configuration注解出现的位置
-func (t *TypeScanner) findConfigurationsPosition(fileContent []string) []int {
-	indexes := make([]int, 0)
-	for i, line := range fileContent {
-		if strings.Index(line, "//@Configuration") != -1 && !t.IsCodeAnnotation(fileContent[i+1]) {
-			indexes = append(indexes, i)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
 		}
-	}
-	return indexes
-}
-
-func (t *TypeScanner) getConfiguration(fileContent []string, indexes []int) (types [][]string, aliasMap map[string]string) {
-	types = make([][]string, 0)
-	aliasMap = make(map[string]string)
-	for _, index := range indexes {
-		count := 0
-		type_ := make([]string, 0)
-		pos := index + 1
-		typeAnnotation := fileContent[index]
-		typeInfo := fileContent[pos]
-		name := t.GetTypeName(typeInfo)
-		alias := t.GetTypeAlias(typeAnnotation, name)
-		aliasMap[name] = alias
-		for ; ; pos++ {
-			leftCount := strings.Count(fileContent[pos], "{")
-			count += leftCount
-
-			rightCount := strings.Count(fileContent[pos], "}")
-			count -= rightCount
-
-			if count == 0 {
-				pos++
-				break
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			doc := typeSpec.Doc
+			if doc == nil && len(gen.Specs) == 1 {
+				doc = gen.Doc
 			}
-		}
-		type_ = append(type_, fileContent[index+1:pos]...)
-		types = append(types, type_)
-	}
-	return
-}
-
-func (t *TypeScanner) getPackage(fileContent []string) string {
-	for i := range fileContent {
-		if strings.Index(fileContent[i], "package") != -1 {
-			return strings.TrimSpace(fileContent[i][len("package "):])
-		}
-	}
-	panic("cannot find package name")
-}
-
-func (t *TypeScanner) getImports(fileContent []string) (imports []string) {
-	imports = make([]string, 0)
-	for i, line := range fileContent {
-		if strings.Index(line, "import") != -1 {
-			index := i
-			left := -1
-			for ; ; index++ {
-				first := strings.Index(fileContent[index], "\"")
-				end := strings.LastIndex(fileContent[index], "\"")
-				l := strings.Index(fileContent[index], "(")
-				if l != -1 {
-					left = index
-				}
-				if first != -1 && end != -1 {
-					item := fileContent[index][first+1 : end]
-					imports = append(imports, item)
-					if left == -1 {
-						break
-					}
-				}
-				if strings.Contains(fileContent[index], ")") {
-					break
+			if _, ok := typeSpec.Type.(*ast.StructType); !ok || doc == nil || !config_model.HasAnnotation(doc, "@Configuration") {
+				continue
+			}
+			aliasMap[typeSpec.Name.Name] = config_model.Annotation(doc, "@Alias=")
+			if aliasMap[typeSpec.Name.Name] == "" {
+				aliasMap[typeSpec.Name.Name] = config_model.LowerCamel(typeSpec.Name.Name)
+			}
+			source := make([]string, 0)
+			for _, line := range lines[fset.Position(typeSpec.Pos()).Line-1 : fset.Position(typeSpec.End()).Line] {
+				if line = strings.TrimSpace(line); line != "" {
+					source = append(source, line)
 				}
 			}
+			types = append(types, source)
 		}
 	}
-	return imports
+	return types, imports, file.Name.Name, aliasMap
+}
+
+// IsCodeAnnotation 判断一行源码是否为注释
+//
+// Deprecated: 配置类型改为通过go/ast扫描，注解使用 config_model.HasAnnotation 判断
+func (t *TypeScanner) IsCodeAnnotation(str string) bool {
+	return strings.HasPrefix(strings.TrimSpace(str), "//")
 }
@@ -0,0 +1,123 @@
+package config_type
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestScanTypeInfo(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		top    []string
+		alias  map[string]string
+	}{
+		{
+			name:   "braces in tags",
+			source: "package config\n\n//@Configuration\ntype Brace struct {\n\tPattern string `yaml:\"pattern\" default:\"{{\"`\n}\n",
+			top:    []string{"Brace"},
+			alias:  map[string]string{"Brace": "brace"},
+		},
+		{
+			name:   "annotation followed by a comment",
+			source: "package config\n\n//@Configuration @Alias=srv\n// Server 服务配置\ntype Server struct {\n\tPort int `yaml:\"port\"`\n}\n",
+			top:    []string{"Server"},
+			alias:  map[string]string{"Server": "srv"},
+		},
+		{
+			name:   "nested configuration",
+			source: "package config\n\n//@Configuration\ntype Server struct {\n\tDB DB `yaml:\"db\"`\n}\n\n//@Configuration\ntype DB struct {\n\tURL string `yaml:\"url\"`\n}\n",
+			top:    []string{"Server"},
+			alias:  map[string]string{"Server": "server", "DB": "dB"},
+		},
+		{
+			name:   "annotation in a string",
+			source: "package config\n\nconst usage = \"//@Configuration\"\n\ntype Plain struct {\n\tName string\n}\n",
+			top:    []string{},
+			alias:  map[string]string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scanner := scanSource(t, test.source)
+			top := scanner.getTopType()
+			sort.Strings(top)
+			if !reflect.DeepEqual(top, test.top) {
+				t.Fatalf("top types %v, want %v", top, test.top)
+			}
+			if !reflect.DeepEqual(scanner.typeAlias, test.alias) {
+				t.Fatalf("aliases %v, want %v", scanner.typeAlias, test.alias)
+			}
+		})
+	}
+}
+
+// scanSource 把source写入临时目录的config/types.go并扫描，返回扫描后的TypeScanner
+func scanSource(t *testing.T, source string) *TypeScanner {
+	root, err := ioutil.TempDir("", "config-type")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+	if err := os.MkdirAll(filepath.Join(root, "config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "config", "types.go"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	scanner := &TypeScanner{ConfigDir: root}
+	scanner.initVariable()
+	scanner.scanTypeInfo(root)
+	return scanner
+}
+
+func TestDeprecatedHelpers(t *testing.T) {
+	const source = "package config\n\nimport (\n\t\"time\"\n)\n\n" +
+		"// Server 服务配置\n//@Configuration @Alias=server\ntype Server struct {\n\tPort int\n\n\tTimeout time.Duration\n}\n\n" +
+		"//@Configuration\ntype DBConfig struct{ URL string }\n\ntype Plain struct{}\n"
+	scanner := scanSource(t, source)
+	files, dirs := scanner.GetScanFiles(scanner.ConfigDir)
+	if len(files) != 0 || len(dirs) != 1 || filepath.Base(dirs[0]) != "config" {
+		t.Fatalf("GetScanFiles = %v, %v", files, dirs)
+	}
+	files, _ = scanner.GetScanFiles(dirs[0])
+	if len(files) != 1 || filepath.Base(files[0]) != "types.go" {
+		t.Fatalf("GetScanFiles(config) = %v", files)
+	}
+	types, imports, packageName, aliasMap := scanner.GetConfigurations(files[0])
+	wantTypes := [][]string{
+		{"type Server struct {", "Port int", "Timeout time.Duration", "}"},
+		{"type DBConfig struct{ URL string }"},
+	}
+	if !reflect.DeepEqual(types, wantTypes) {
+		t.Errorf("types %q, want %q", types, wantTypes)
+	}
+	if !reflect.DeepEqual(imports, []string{"time"}) || packageName != "config" {
+		t.Errorf("imports %v, package %s", imports, packageName)
+	}
+	if want := map[string]string{"Server": "server", "DBConfig": "dBConfig"}; !reflect.DeepEqual(aliasMap, want) {
+		t.Errorf("aliases %v, want %v", aliasMap, want)
+	}
+	tests := []struct {
+		line    string
+		name    string
+		alias   string
+		comment bool
+	}{
+		{"type Server struct {", "Server", "server", false},
+		{"//@Configuration @Alias=http", "", "http", true},
+		{"  // note", "", "server", true},
+		{"}", "", "server", false},
+	}
+	for _, test := range tests {
+		name := scanner.GetTypeName(test.line)
+		alias := scanner.GetTypeAlias(test.line, "Server")
+		if name != test.name || alias != test.alias || scanner.IsCodeAnnotation(test.line) != test.comment {
+			t.Errorf("%q: name %q, alias %q, comment %v", test.line, name, alias, scanner.IsCodeAnnotation(test.line))
+		}
+	}
+}
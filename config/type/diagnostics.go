@@ -0,0 +1,267 @@
+package config_type
+
+import (
+	"fmt"
+	"github.com/orange0224/go-injector-yaml/config/model"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Diagnostic 扫描配置类型时发现的问题，Pos为出问题的声明所在位置
+type Diagnostic = config_model.Diagnostic
+
+// Diagnostics 扫描时发现的所有问题，按文件和行号排序输出
+type Diagnostics = config_model.Diagnostics
+
+// reservedKeys 顶层的这些key用于profile激活，不能作为@Alias
+var reservedKeys = map[string]bool{"profiles": true, "on-profile": true}
+
+// Check 扫描配置类型并检查重名的类型、冲突的@Alias、循环引用和@Component的依赖，没有问题时返回nil
+func (t *TypeScanner) Check() error {
+	t.checkConfig()
+	t.initVariable()
+	t.scanTypeInfo(t.ConfigDir)
+	return t.check(t.getTopType())
+}
+
+func (t *TypeScanner) check(topType []string) error {
+	diagnostics := make(Diagnostics, 0)
+	diagnostics = append(diagnostics, t.checkDuplicateTypes()...)
+	diagnostics = append(diagnostics, t.checkAliases(topType)...)
+	diagnostics = append(diagnostics, t.checkCycles()...)
+	diagnostics = append(diagnostics, t.checkExported(topType)...)
+	diagnostics = append(diagnostics, t.checkComponents(topType)...)
+	if len(diagnostics) == 0 {
+		return nil
+	}
+	return diagnostics
+}
+
+// checkDuplicateTypes 不同包中同名的配置类型按名称保存时会互相覆盖
+func (t *TypeScanner) checkDuplicateTypes() Diagnostics {
+	referenced := make(map[string]bool)
+	for _, declarations := range t.declarations {
+		for _, typ := range declarations {
+			if !typ.Configuration {
+				continue
+			}
+			for _, field := range typ.Fields {
+				if nested := t.nestedType(field.Expr); nested != nil {
+					referenced[nested.Name] = true
+				}
+			}
+		}
+	}
+	diagnostics := make(Diagnostics, 0)
+	for name, declarations := range t.declarations {
+		if len(declarations) < 2 || !referenced[name] && !anyConfiguration(declarations) {
+			continue
+		}
+		for _, typ := range declarations[1:] {
+			diagnostics = append(diagnostics, Diagnostic{
+				Pos:     typ.Pos,
+				Message: fmt.Sprintf("type %s is also declared at %s; config types are matched by name and must be unique", name, declarations[0].Pos),
+			})
+		}
+	}
+	return diagnostics
+}
+
+func anyConfiguration(types []*config_model.Type) bool {
+	for _, typ := range types {
+		if typ.Configuration {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAliases 顶层类型的@Alias会成为ApplicationConfig的字段名和顶层key，不能重复，也必须是合法的标识符
+func (t *TypeScanner) checkAliases(topType []string) Diagnostics {
+	topType = append([]string(nil), topType...)
+	sort.Strings(topType)
+	diagnostics := make(Diagnostics, 0)
+	fields := make(map[string]string)
+	for _, name := range topType {
+		alias := t.typeAlias[name]
+		pos := token.Position{}
+		if typ := t.models[name]; typ != nil {
+			pos = typ.Pos
+		}
+		switch {
+		case !isIdentifier(alias):
+			diagnostics = append(diagnostics, Diagnostic{Pos: pos, Message: fmt.Sprintf("@Alias=%s of %s is not a valid Go identifier", alias, name)})
+			continue
+		case reservedKeys[alias]:
+			diagnostics = append(diagnostics, Diagnostic{Pos: pos, Message: fmt.Sprintf("@Alias=%s of %s is reserved for profile activation", alias, name)})
+			continue
+		}
+		field := strings.ToUpper(alias[0:1]) + alias[1:]
+		if other, ok := fields[field]; ok {
+			diagnostics = append(diagnostics, Diagnostic{
+				Pos:     pos,
+				Message: fmt.Sprintf("@Alias=%s of %s collides with @Alias=%s of %s", alias, name, t.typeAlias[other], other),
+			})
+			continue
+		}
+		fields[field] = name
+	}
+	return diagnostics
+}
+
+// checkExported 其他包中的配置类型会被config包引用，必须是导出的
+func (t *TypeScanner) checkExported(topType []string) Diagnostics {
+	configPath := config_model.ImportPath(t.ConfigDir + t.fileSeparator + "config")
+	checked := make(map[string]bool)
+	diagnostics := make(Diagnostics, 0)
+	for _, top := range topType {
+		for _, name := range t.graph.Reachable(top) {
+			typ := t.models[name]
+			if checked[name] || typ == nil || typ.ImportPath == configPath || ast.IsExported(name) {
+				continue
+			}
+			checked[name] = true
+			diagnostics = append(diagnostics, Diagnostic{Pos: typ.Pos, Message: fmt.Sprintf("config type %s must be exported to be used from package %s", name, configPath)})
+		}
+	}
+	return diagnostics
+}
+
+// checkComponents 在生成依赖注入代码之前检查@Component：缺少或有多个提供者的依赖、依赖环、
+// @Value中不存在或需要经过指针字段的配置key，@PostConstruct、@PreDestroy方法，以及@AutoExecute的参数和顺序
+func (t *TypeScanner) checkComponents(topType []string) Diagnostics {
+	if len(t.components) == 0 && len(t.methods) == 0 && len(t.executes) == 0 && !t.hasConditions() {
+		return nil
+	}
+	configPath := config_model.ImportPath(t.ConfigDir + t.fileSeparator + "config")
+	sections := map[string]*config_model.Provider{configPath + ".ApplicationConfig": {}}
+	keys := make(map[string]bool)
+	pointers := make(map[string]bool)
+	for _, name := range topType {
+		if typ := t.models[name]; typ != nil {
+			alias := t.typeAlias[name]
+			sections[typ.ImportPath+"."+typ.Name] = &config_model.Provider{Section: strings.ToUpper(alias[0:1]) + alias[1:]}
+			keys[alias] = true
+		}
+	}
+	t.walkKeys(topType, func(key configKey) {
+		keys[key.Key] = true
+		if _, pointer := config_model.StructRef(key.Field.Expr); pointer && key.Nested != nil {
+			pointers[key.Key] = true
+		}
+	})
+	diagnostics := config_model.BindLifecycle(t.components, t.methods)
+	checkKey := func(key string) error {
+		parts := strings.Split(key, ".")
+		for i := range parts {
+			prefix := strings.Join(parts[:i+1], ".")
+			if !keys[prefix] {
+				return fmt.Errorf("unknown config key %s", prefix)
+			}
+			if pointers[prefix] && i < len(parts)-1 {
+				return fmt.Errorf("%s is a pointer and may be nil", prefix)
+			}
+		}
+		return nil
+	}
+	wiring, wireDiagnostics := config_model.Wire(t.components, sections, checkKey)
+	configWiring, _ := config_model.Wire(nil, sections, checkKey)
+	_, _, executeDiagnostics := config_model.PlanExecutes(t.executes, configPath, configWiring, wiring, checkKey)
+	diagnostics = append(append(diagnostics, wireDiagnostics...), executeDiagnostics...)
+	hasKey := func(key string) bool {
+		return keys[key]
+	}
+	diagnostics = append(diagnostics, wiring.CheckConditions(hasKey)...)
+	top := make(map[string]bool, len(topType))
+	for _, name := range topType {
+		top[name] = true
+	}
+	for name, typ := range t.models {
+		if len(typ.Conditions) == 0 {
+			continue
+		}
+		if !top[name] {
+			diagnostics = append(diagnostics, Diagnostic{Pos: typ.Conditions[0].Pos, Message: fmt.Sprintf("%s: conditions are only supported on top-level config sections", name)})
+			continue
+		}
+		diagnostics = append(diagnostics, config_model.CheckConditions("section "+t.typeAlias[name], typ.Conditions, hasKey, nil)...)
+	}
+	return diagnostics
+}
+
+// hasConditions 是否有配置类型带有条件注解
+func (t *TypeScanner) hasConditions() bool {
+	for _, typ := range t.models {
+		if len(typ.Conditions) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkCycles 配置类型通过字段引用自身时，配置的key没有尽头，顶层类型的计算也会漏掉这些类型
+func (t *TypeScanner) checkCycles() Diagnostics {
+	names := make([]string, 0, len(t.models))
+	for name, typ := range t.models {
+		if typ.Configuration {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	diagnostics := make(Diagnostics, 0)
+	reported := make(map[string]bool)
+	for _, name := range names {
+		t.findCycles(t.models[name], nil, map[string]bool{}, func(cycle []string, field *config_model.Field) {
+			key := cycleKey(cycle)
+			if reported[key] {
+				return
+			}
+			reported[key] = true
+			diagnostics = append(diagnostics, Diagnostic{Pos: field.Pos, Message: "recursive config type: " + strings.Join(cycle, " -> ")})
+		})
+	}
+	return diagnostics
+}
+
+func (t *TypeScanner) findCycles(typ *config_model.Type, path []string, visiting map[string]bool, report func(cycle []string, field *config_model.Field)) {
+	visiting[typ.Name] = true
+	defer delete(visiting, typ.Name)
+	for _, dependency := range t.graph.Edges[typ.Name] {
+		field, nested := dependency.Field, t.models[dependency.To]
+		step := append(append([]string(nil), path...), typ.Name+"."+field.Name)
+		if visiting[nested.Name] {
+			start := 0
+			for i, item := range step {
+				if strings.HasPrefix(item, nested.Name+".") {
+					start = i
+					break
+				}
+			}
+			report(append(append([]string(nil), step[start:]...), nested.Name), field)
+			continue
+		}
+		t.findCycles(nested, step, visiting, report)
+	}
+}
+
+// cycleKey 同一个环从不同的类型出发只报告一次
+func cycleKey(cycle []string) string {
+	items := append([]string(nil), cycle[:len(cycle)-1]...)
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
@@ -0,0 +1,100 @@
+package config_type
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const diagnosticsServer = `package config
+
+//@Configuration @Alias=server
+type Server struct {
+	Host string ` + "`yaml:\"host\"`" + `
+	DB   *DB    ` + "`yaml:\"db\"`" + `
+}
+
+type DB struct {
+	URL string ` + "`yaml:\"url\"`" + `
+}
+`
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// errors 期望的诊断，文件为 types.go，空表示没有问题
+		errors []string
+	}{
+		{
+			name: "valid components",
+			source: diagnosticsServer + "\n//@Component\ntype Repo struct {\n\t//@Inject\n\tServer Server\n\t//@Value(\"server.host\")\n\tHost string\n}\n\n" +
+				"//@Component\nfunc NewService(repo *Repo, cfg ApplicationConfig) *Service { return nil }\n\ntype Service struct{}\n\n" +
+				"type ApplicationConfig struct{}\n",
+		},
+		{
+			name: "component cycle",
+			source: diagnosticsServer + "\n//@Component\nfunc NewA(b *B) *A { return nil }\n\ntype A struct{}\n\n" +
+				"//@Component\nfunc NewB(c *C) *B { return nil }\n\ntype B struct{}\n\n" +
+				"//@Component\nfunc NewC(a *A) *C { return nil }\n\ntype C struct{}\n",
+			errors: []string{"types.go:14:1: dependency cycle: A -> B -> C -> A"},
+		},
+		{
+			name: "unresolved dependencies",
+			source: diagnosticsServer + "\ntype Cache interface{}\n\n" +
+				"//@Component\ntype Service struct {\n\t//@Inject\n\tCache Cache\n\t//@Named(\"redis\")\n\tRepo *Repo\n" +
+				"\t//@Value(\"server.port\")\n\tPort int\n\t//@Value(\"server.db.url\")\n\tURL string\n}\n\n" +
+				"//@Component\ntype Repo struct{}\n",
+			errors: []string{
+				"types.go:18:2: Service.Cache: no component provides Cache",
+				"types.go:20:2: Service.Repo: no component named \"redis\" provides *Repo",
+				"types.go:22:2: Service.Port: @Value(\"server.port\"): unknown config key server.port",
+				"types.go:24:2: Service.URL: @Value(\"server.db.url\"): server.db is a pointer and may be nil",
+			},
+		},
+		{
+			name: "lifecycle methods",
+			source: diagnosticsServer + "\n//@Component\ntype Service struct{}\n\n" +
+				"//@PostConstruct @Timeout=soon\nfunc (s *Service) Start() {}\n\n" +
+				"//@PreDestroy\nfunc (s *Service) Stop(name string) {}\n\n" +
+				"type Other struct{}\n\n//@PostConstruct\nfunc (o *Other) Start() {}\n",
+			errors: []string{
+				"types.go:17:1: @PostConstruct Service.Start: invalid @Timeout=soon",
+				"types.go:20:1: @PreDestroy Service.Stop must take no arguments or a context.Context and return nothing or an error",
+				"types.go:25:1: @PostConstruct Other.Start: Other is not a @Component",
+			},
+		},
+		{
+			name: "config types",
+			source: "package config\n\n//@Configuration @Alias=profiles\ntype Profiles struct{}\n\n" +
+				"//@Configuration @Alias=http-server\ntype HTTP struct{}\n\n" +
+				"//@Configuration @Alias=node\ntype Node struct {\n\tChildren []Node\n}\n\n" +
+				"//@Configuration @Alias=Node\ntype Other struct{}\n",
+			errors: []string{
+				"types.go:4:6: @Alias=profiles of Profiles is reserved for profile activation",
+				"types.go:7:6: @Alias=http-server of HTTP is not a valid Go identifier",
+				"types.go:11:2: recursive config type: Node.Children -> Node",
+				"types.go:15:6: @Alias=Node of Other collides with @Alias=node of Node",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scanner := scanSource(t, test.source)
+			err := scanner.check(scanner.getTopType())
+			if test.errors == nil {
+				if err != nil {
+					t.Fatalf("unexpected error:\n%v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			prefix := filepath.Join(scanner.ConfigDir, "config") + string(filepath.Separator)
+			if got, want := strings.Replace(err.Error(), prefix, "", -1), strings.Join(test.errors, "\n"); got != want {
+				t.Fatalf("errors\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
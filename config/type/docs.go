@@ -0,0 +1,136 @@
+package config_type
+
+import (
+	"github.com/orange0224/go-injector-yaml/config/model"
+	"github.com/orange0224/go-injector-yaml/config/utils"
+	"strings"
+)
+
+// Docs 生成所有配置项的Markdown参考文档，按顶层alias分组
+func (t *TypeScanner) Docs() ([]byte, error) {
+	t.checkConfig()
+	t.initVariable()
+	t.scanTypeInfo(t.ConfigDir)
+	topType := t.getTopType()
+	if err := t.check(topType); err != nil {
+		return nil, err
+	}
+	return t.buildDocs(topType), nil
+}
+
+func (t *TypeScanner) buildDocs(topType []string) []byte {
+	var builder strings.Builder
+	env := utils.NotBlank(t.EnvPrefix)
+	builder.WriteString("# Configuration reference\n\n")
+	if env {
+		builder.WriteString("Every key can be set in the config file, as an environment variable or as a command line flag. ")
+		builder.WriteString("Later sources override earlier ones: file, mounted directory, cloud, environment, flags.\n")
+	} else {
+		builder.WriteString("Every key can be set in the config file or as a command line flag. ")
+		builder.WriteString("Later sources override earlier ones: file, mounted directory, cloud, flags.\n")
+	}
+	builder.WriteString("\nOutside the config file, lists are written comma separated (`-server.tags=a,b`) or by index (`-server.tags[0]=a`), ")
+	builder.WriteString("maps as `name=value` pairs (`-server.labels=a=1,b=2`) or by name (`-server.labels.a=1`).\n")
+	alias := ""
+	t.walkKeys(topType, func(key configKey) {
+		if key.Alias != alias {
+			alias = key.Alias
+			typ := t.models[t.typeOfAlias(alias)]
+			builder.WriteString("\n## " + alias + "\n\n")
+			if typ != nil && typ.Doc != "" {
+				builder.WriteString(typ.Doc + "\n\n")
+			}
+			if env {
+				builder.WriteString("| Key | Type | Default | Env | Flag | Validation | Description |\n")
+				builder.WriteString("|-----|------|---------|-----|------|------------|-------------|\n")
+			} else {
+				builder.WriteString("| Key | Type | Default | Flag | Validation | Description |\n")
+				builder.WriteString("|-----|------|---------|------|------------|-------------|\n")
+			}
+		}
+		if key.Nested != nil {
+			return
+		}
+		columns := []string{code(key.Key), code(key.Field.Type), code(defaultText(key))}
+		if env {
+			columns = append(columns, code(t.EnvPrefix+utils.EnvName(key.Key)))
+		}
+		columns = append(columns, code("-"+key.Key), cell(validationText(key.Field)),
+			cell(strings.TrimSpace(descriptionText(key.Field)+"\n"+aliasText(key))))
+		builder.WriteString("| " + strings.Join(columns, " | ") + " |\n")
+	})
+	return []byte(builder.String())
+}
+
+func (t *TypeScanner) typeOfAlias(alias string) string {
+	for name, typeAlias := range t.typeAlias {
+		if typeAlias == alias {
+			return name
+		}
+	}
+	return ""
+}
+
+// defaultText 默认值，敏感字段的默认值不写入文档
+func defaultText(key configKey) string {
+	if key.Default == nil {
+		return ""
+	}
+	if key.Field.Secret() {
+		return SecretPlaceholder
+	}
+	return key.Default.Literal
+}
+
+func validationText(field *config_model.Field) string {
+	rules := make([]string, 0)
+	for _, rule := range config_model.Rules(field.Tag) {
+		if rule.Param == "" {
+			rules = append(rules, rule.Name)
+		} else {
+			rules = append(rules, rule.Name+"="+rule.Param)
+		}
+	}
+	return strings.Join(rules, ", ")
+}
+
+// descriptionText 字段注释，Deprecated: 开头的段落会加粗显示
+func descriptionText(field *config_model.Field) string {
+	if field.Deprecated() == "" {
+		return field.Doc
+	}
+	return strings.Replace(field.Doc, "Deprecated:", "**Deprecated:**", 1)
+}
+
+// aliasText 字段的其他名称和已废弃的旧名称
+func aliasText(key configKey) string {
+	parent := key.Key[:strings.LastIndex(key.Key, ".")+1]
+	lines := make([]string, 0)
+	if len(key.Field.Alternates) > 0 {
+		lines = append(lines, "Also accepted: "+fullKeys(parent, key.Field.Alternates))
+	}
+	if len(key.Field.DeprecatedKeys) > 0 {
+		lines = append(lines, "Deprecated names: "+fullKeys(parent, key.Field.DeprecatedKeys))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func fullKeys(parent string, names []string) string {
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		keys = append(keys, code(parent+name))
+	}
+	return strings.Join(keys, ", ")
+}
+
+func code(text string) string {
+	if text == "" {
+		return ""
+	}
+	return "`" + text + "`"
+}
+
+func cell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.ReplaceAll(strings.TrimSpace(text), "\n", "<br>")
+}
@@ -0,0 +1,49 @@
+package config_type
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden 对比生成内容和testdata中的golden文件，-update 时重写golden文件
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s mismatch, run go test -update to rewrite it\ngot:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+func TestDocs(t *testing.T) {
+	tests := []struct {
+		golden    string
+		envPrefix string
+	}{
+		{"docs.golden.md", ""},
+		{"docs_env.golden.md", "APP_"},
+	}
+	for _, test := range tests {
+		t.Run(test.golden, func(t *testing.T) {
+			scanner := &TypeScanner{ConfigDir: filepath.Join("testdata", "project"), EnvPrefix: test.envPrefix}
+			docs, err := scanner.Docs()
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, test.golden, docs)
+		})
+	}
+}
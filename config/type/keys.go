@@ -0,0 +1,73 @@
+package config_type
+
+import (
+	"github.com/orange0224/go-injector-yaml/config/model"
+	"go/ast"
+	"sort"
+)
+
+// configKey ApplicationConfig中的一个配置项
+type configKey struct {
+	Key   string
+	Alias string
+	Owner *config_model.Type
+	Field *config_model.Field
+	// Nested 字段是嵌套的配置类型，它的字段会作为单独的配置项出现
+	Nested  *config_model.Type
+	Default *config_model.Value
+}
+
+// walkKeys 按alias排序遍历所有顶层配置类型，深度优先访问每个配置项；
+// 默认值取上层@DefaultConfig字面量中对应的值，没有时取default标签
+func (t *TypeScanner) walkKeys(topType []string, visit func(key configKey)) {
+	aliases := make([]string, 0, len(topType))
+	types := make(map[string]*config_model.Type)
+	for _, name := range topType {
+		if typ := t.models[name]; typ != nil {
+			aliases = append(aliases, typ.Alias)
+			types[typ.Alias] = typ
+		}
+	}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		typ := types[alias]
+		t.walkType(alias, alias, typ, &config_model.Value{Fields: typ.Default}, map[string]bool{}, visit)
+	}
+}
+
+func (t *TypeScanner) walkType(alias, prefix string, typ *config_model.Type, defaults *config_model.Value, visiting map[string]bool, visit func(key configKey)) {
+	if visiting[typ.Name] {
+		return
+	}
+	visiting[typ.Name] = true
+	defer delete(visiting, typ.Name)
+	for _, field := range typ.Fields {
+		key := configKey{Key: prefix + "." + field.Key, Alias: alias, Owner: typ, Field: field}
+		var fieldDefaults *config_model.Value
+		if defaults != nil {
+			fieldDefaults = defaults.Fields[field.Name]
+		}
+		if fieldDefaults != nil && fieldDefaults.Fields == nil {
+			key.Default = fieldDefaults
+		} else if def, ok := field.Tag.Lookup("default"); ok {
+			key.Default = &config_model.Value{Literal: def}
+		}
+		key.Nested = t.nestedType(field.Expr)
+		if field.Inline && key.Nested != nil {
+			t.walkType(alias, prefix, key.Nested, fieldDefaults, visiting, visit)
+			continue
+		}
+		visit(key)
+		if key.Nested != nil {
+			t.walkType(alias, key.Key, key.Nested, fieldDefaults, visiting, visit)
+		}
+	}
+}
+
+// nestedType 字段类型为扫描到的结构体（或其指针，包括 pkg.Type）时返回该类型
+func (t *TypeScanner) nestedType(expr ast.Expr) *config_model.Type {
+	if name, _ := config_model.StructRef(expr); name != "" {
+		return t.models[name]
+	}
+	return nil
+}
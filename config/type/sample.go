@@ -0,0 +1,128 @@
+package config_type
+
+import (
+	"bytes"
+	"github.com/orange0224/go-injector-yaml/config/model"
+	"go/ast"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SecretPlaceholder 示例文件中敏感字段的占位值
+const SecretPlaceholder = "<secret>"
+
+// Sample 生成包含所有配置项的示例application.yaml，值为默认值或零值，注释来自字段的文档注释
+func (t *TypeScanner) Sample() ([]byte, error) {
+	t.checkConfig()
+	t.initVariable()
+	t.scanTypeInfo(t.ConfigDir)
+	topType := t.getTopType()
+	if err := t.check(topType); err != nil {
+		return nil, err
+	}
+	return t.buildSample(topType)
+}
+
+func (t *TypeScanner) buildSample(topType []string) ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	mappings := make(map[string]*yaml.Node)
+	t.walkKeys(topType, func(key configKey) {
+		parent := key.Key[:strings.LastIndex(key.Key, ".")]
+		if _, ok := mappings[key.Alias]; !ok {
+			mapping := &yaml.Node{Kind: yaml.MappingNode}
+			mappings[key.Alias] = mapping
+			comment := ""
+			if typ := t.models[t.typeOfAlias(key.Alias)]; typ != nil {
+				comment = typ.Doc
+			}
+			root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key.Alias, HeadComment: comment}, mapping)
+		}
+		name := &yaml.Node{Kind: yaml.ScalarNode, Value: key.Field.Key, HeadComment: sampleComment(key.Field)}
+		var value *yaml.Node
+		if key.Nested != nil {
+			value = &yaml.Node{Kind: yaml.MappingNode}
+			mappings[key.Key] = value
+		} else {
+			value = sampleValue(key)
+		}
+		mappings[parent].Content = append(mappings[parent].Content, name, value)
+	})
+	var buffer bytes.Buffer
+	buffer.WriteString("# Example configuration generated from the @Configuration types.\n")
+	buffer.WriteString("# Values are the defaults, or zero values when there is no default.\n\n")
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func sampleComment(field *config_model.Field) string {
+	lines := make([]string, 0)
+	if field.Doc != "" {
+		lines = append(lines, field.Doc)
+	}
+	if rules := validationText(field); rules != "" {
+		lines = append(lines, "validate: "+rules)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sampleValue 敏感字段使用占位值，其余字段使用默认值或类型的零值
+func sampleValue(key configKey) *yaml.Node {
+	if key.Field.Secret() {
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: SecretPlaceholder, LineComment: "encrypt with: go-injector-yaml encrypt"}
+	}
+	expr := key.Field.Expr
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch expr := expr.(type) {
+	case *ast.ArrayType:
+		if ident, ok := expr.Elt.(*ast.Ident); !ok || ident.Name != "byte" {
+			return &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		}
+	case *ast.MapType:
+		return &yaml.Node{Kind: yaml.MappingNode, Style: yaml.FlowStyle}
+	}
+	value := ""
+	if key.Default != nil {
+		value = key.Default.Literal
+	} else {
+		value = zeroValue(key.Field)
+	}
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	if kind := typeKind(expr); kind == "string" || kind == "" {
+		node.Style = yaml.DoubleQuotedStyle
+	}
+	if enum := key.Field.Enum(); enum != nil {
+		node.LineComment = "one of: " + strings.Join(enum, ", ")
+	}
+	return node
+}
+
+func typeKind(expr ast.Expr) string {
+	if ident, ok := expr.(*ast.Ident); ok {
+		return jsonKind(ident.Name)
+	}
+	return ""
+}
+
+func zeroValue(field *config_model.Field) string {
+	switch field.Type {
+	case "time.Duration", "*time.Duration":
+		return "0s"
+	}
+	switch typeKind(field.Expr) {
+	case "integer", "number":
+		return "0"
+	case "boolean":
+		return "false"
+	}
+	return ""
+}
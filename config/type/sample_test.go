@@ -0,0 +1,15 @@
+package config_type
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	scanner := &TypeScanner{ConfigDir: filepath.Join("testdata", "project")}
+	sample, err := scanner.Sample()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "sample.golden.yaml", sample)
+}
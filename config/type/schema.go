@@ -0,0 +1,263 @@
+package config_type
+
+import (
+	"encoding/json"
+	"github.com/orange0224/go-injector-yaml/config/model"
+	"go/ast"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern time.ParseDuration接受的格式，包括 0、-1m、.5s
+const durationPattern = `^[-+]?(0|(([0-9]+(\.[0-9]*)?|\.[0-9]+)(ns|us|µs|μs|ms|s|m|h))+)$`
+
+// Schema 生成ApplicationConfig的JSON Schema (draft 2020-12)，可以交给yaml-language-server做补全和校验
+func (t *TypeScanner) Schema() ([]byte, error) {
+	t.checkConfig()
+	t.initVariable()
+	t.scanTypeInfo(t.ConfigDir)
+	topType := t.getTopType()
+	if err := t.check(topType); err != nil {
+		return nil, err
+	}
+	return t.buildSchema(topType)
+}
+
+func (t *TypeScanner) buildSchema(topType []string) ([]byte, error) {
+	topType = append([]string(nil), topType...)
+	sort.Strings(topType)
+
+	defs := make(map[string]interface{})
+	properties := map[string]interface{}{
+		"profiles":   map[string]interface{}{"description": "profiles that activate this document", "type": []string{"string", "array"}, "items": map[string]interface{}{"type": "string"}},
+		"on-profile": map[string]interface{}{"description": "profile expression that activates this document", "type": "string"},
+	}
+	for _, name := range topType {
+		properties[t.typeAlias[name]] = map[string]interface{}{"$ref": "#/$defs/" + name}
+		t.schemaDefinition(name, defs)
+	}
+	schema := map[string]interface{}{
+		"$schema":              SchemaDraft,
+		"title":                "ApplicationConfig",
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+		"$defs":                defs,
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+func (t *TypeScanner) schemaDefinition(name string, defs map[string]interface{}) {
+	if _, ok := defs[name]; ok {
+		return
+	}
+	typ := t.models[name]
+	if typ == nil {
+		return
+	}
+	definition := map[string]interface{}{"type": "object", "additionalProperties": false}
+	defs[name] = definition
+	if typ.Doc != "" {
+		definition["description"] = typ.Doc
+	}
+	properties := make(map[string]interface{})
+	required := t.schemaProperties(typ, properties, defs)
+	definition["properties"] = properties
+	if len(required) > 0 {
+		definition["required"] = required
+	}
+}
+
+// schemaProperties 添加typ的字段，inline字段的嵌套结构体的字段直接加到同一层，返回必填的key
+func (t *TypeScanner) schemaProperties(typ *config_model.Type, properties map[string]interface{}, defs map[string]interface{}) []string {
+	required := make([]string, 0)
+	for _, field := range typ.Fields {
+		if nested := t.nestedType(field.Expr); field.Inline && nested != nil {
+			required = append(required, t.schemaProperties(nested, properties, defs)...)
+			continue
+		}
+		properties[field.Key] = t.fieldSchema(typ, field, defs)
+		for _, name := range field.Alternates {
+			properties[name] = t.fieldSchema(typ, field, defs)
+		}
+		for _, name := range field.DeprecatedKeys {
+			schema := t.fieldSchema(typ, field, defs)
+			schema["deprecated"] = true
+			schema["description"] = "Deprecated: use " + field.Key
+			properties[name] = schema
+		}
+		if field.Required() {
+			required = append(required, field.Key)
+		}
+	}
+	return required
+}
+
+func (t *TypeScanner) fieldSchema(owner *config_model.Type, field *config_model.Field, defs map[string]interface{}) map[string]interface{} {
+	schema := t.typeSchema(field.Expr, defs)
+	if field.Doc != "" {
+		schema["description"] = field.Doc
+	}
+	if value, ok := owner.DefaultValue(field); ok {
+		if def := t.defaultJSON(value, field.Expr); def != nil {
+			schema["default"] = def
+		}
+	}
+	if enum := field.Enum(); enum != nil {
+		values := make([]interface{}, 0, len(enum))
+		for _, item := range enum {
+			values = append(values, t.literalJSON(item, field.Expr))
+		}
+		schema["enum"] = values
+	}
+	for _, rule := range config_model.Rules(field.Tag) {
+		t.applyRule(schema, rule, field.Expr)
+	}
+	return schema
+}
+
+// applyRule 把validate标签的规则转换为对应的JSON Schema关键字
+func (t *TypeScanner) applyRule(schema map[string]interface{}, rule config_model.Rule, expr ast.Expr) {
+	number, err := strconv.ParseFloat(rule.Param, 64)
+	hasNumber := err == nil
+	kind := schema["type"]
+	switch rule.Name {
+	case "min", "max", "len", "gte", "lte", "gt", "lt":
+		if !hasNumber {
+			return
+		}
+		var keywords map[string]string
+		switch kind {
+		case "string":
+			keywords = map[string]string{"min": "minLength", "gte": "minLength", "max": "maxLength", "lte": "maxLength"}
+		case "array":
+			keywords = map[string]string{"min": "minItems", "gte": "minItems", "max": "maxItems", "lte": "maxItems"}
+		case "object":
+			keywords = map[string]string{"min": "minProperties", "gte": "minProperties", "max": "maxProperties", "lte": "maxProperties"}
+		default:
+			keywords = map[string]string{"min": "minimum", "gte": "minimum", "max": "maximum", "lte": "maximum", "gt": "exclusiveMinimum", "lt": "exclusiveMaximum"}
+		}
+		if rule.Name == "len" {
+			t.applyRule(schema, config_model.Rule{Name: "min", Param: rule.Param}, expr)
+			t.applyRule(schema, config_model.Rule{Name: "max", Param: rule.Param}, expr)
+			return
+		}
+		if keyword, ok := keywords[rule.Name]; ok {
+			schema[keyword] = number
+		}
+	case "email":
+		schema["format"] = "email"
+	case "url", "uri":
+		schema["format"] = "uri"
+	case "hostname":
+		schema["format"] = "hostname"
+	case "ipv4":
+		schema["format"] = "ipv4"
+	case "ipv6":
+		schema["format"] = "ipv6"
+	}
+}
+
+// typeSchema 根据字段的类型表达式生成schema，@Configuration类型引用$defs
+func (t *TypeScanner) typeSchema(expr ast.Expr, defs map[string]interface{}) map[string]interface{} {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if kind := jsonKind(expr.Name); kind != "" {
+			schema := map[string]interface{}{"type": kind}
+			if strings.HasPrefix(expr.Name, "uint") {
+				schema["minimum"] = 0
+			}
+			return schema
+		}
+		if _, ok := t.models[expr.Name]; ok {
+			t.schemaDefinition(expr.Name, defs)
+			return map[string]interface{}{"$ref": "#/$defs/" + expr.Name}
+		}
+	case *ast.StarExpr:
+		return t.typeSchema(expr.X, defs)
+	case *ast.ArrayType:
+		if ident, ok := expr.Elt.(*ast.Ident); ok && ident.Name == "byte" {
+			return map[string]interface{}{"type": "string"}
+		}
+		return map[string]interface{}{"type": "array", "items": t.typeSchema(expr.Elt, defs)}
+	case *ast.MapType:
+		return map[string]interface{}{"type": "object", "additionalProperties": t.typeSchema(expr.Value, defs)}
+	case *ast.SelectorExpr:
+		switch config_model.ExprString(expr) {
+		case "time.Duration":
+			return map[string]interface{}{"type": "string", "pattern": durationPattern}
+		case "time.Time":
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		if _, ok := t.models[expr.Sel.Name]; ok {
+			t.schemaDefinition(expr.Sel.Name, defs)
+			return map[string]interface{}{"$ref": "#/$defs/" + expr.Sel.Name}
+		}
+	}
+	return map[string]interface{}{}
+}
+
+func jsonKind(name string) string {
+	switch name {
+	case "string":
+		return "string"
+	case "bool":
+		return "boolean"
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return "integer"
+	case "float32", "float64":
+		return "number"
+	}
+	return ""
+}
+
+// defaultJSON 把@DefaultConfig中的字面量转换为对应类型的JSON值
+func (t *TypeScanner) defaultJSON(value *config_model.Value, expr ast.Expr) interface{} {
+	if value.Fields == nil {
+		return t.literalJSON(value.Literal, expr)
+	}
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	ident, ok := expr.(*ast.Ident)
+	if !ok || t.models[ident.Name] == nil {
+		return nil
+	}
+	result := make(map[string]interface{})
+	for _, field := range t.models[ident.Name].Fields {
+		if inner, ok := value.Fields[field.Name]; ok {
+			if def := t.defaultJSON(inner, field.Expr); def != nil {
+				result[field.Key] = def
+			}
+		}
+	}
+	return result
+}
+
+func (t *TypeScanner) literalJSON(literal string, expr ast.Expr) interface{} {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	ident, ok := expr.(*ast.Ident)
+	if !ok {
+		return literal
+	}
+	switch jsonKind(ident.Name) {
+	case "integer":
+		if i, err := strconv.ParseInt(literal, 0, 64); err == nil {
+			return i
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(literal, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(literal); err == nil {
+			return b
+		}
+	}
+	return literal
+}
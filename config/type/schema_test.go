@@ -0,0 +1,129 @@
+package config_type
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+const schemaSource = `package config
+
+import "time"
+
+// Common 公共配置
+type Common struct {
+	Region string ` + "`yaml:\"region\" validate:\"oneof=cn us\"`" + `
+}
+
+// Server 服务配置
+//@Configuration @Alias=server
+type Server struct {
+	// Host 监听地址
+	Host    string            ` + "`yaml:\"host\" validate:\"required,hostname\"`" + `
+	Port    uint16            ` + "`yaml:\"port\" validate:\"max=65535\"`" + `
+	Workers uint              ` + "`yaml:\"workers\"`" + `
+	Code    string            ` + "`yaml:\"code\" validate:\"len=3\"`" + `
+	Tags    []string          ` + "`yaml:\"tags\" validate:\"len=2\"`" + `
+	Ratio   float64           ` + "`yaml:\"ratio\" validate:\"gt=0,lt=1\"`" + `
+	Labels  map[string]string ` + "`yaml:\"labels\" validate:\"min=1\"`" + `
+	Timeout time.Duration     ` + "`yaml:\"timeout\" default:\"5s\"`" + `
+	Common  ` + "`yaml:\",inline\"`" + `
+	//@DeprecatedKey=maxConn
+	MaxConn int ` + "`yaml:\"maxConnections\" alternate:\"max_conn\"`" + `
+	DB      DB  ` + "`yaml:\"db\"`" + `
+}
+
+//@Configuration
+type DB struct {
+	URL  string ` + "`yaml:\"url\"`" + `
+	Pool int    ` + "`yaml:\"pool\"`" + `
+}
+
+//@DefaultConfig
+func DefaultServer() Server {
+	return Server{Port: 8080, DB: DB{Pool: 10}}
+}
+`
+
+func TestSchema(t *testing.T) {
+	scanner := scanSource(t, schemaSource)
+	data, err := scanner.buildSchema(scanner.getTopType())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	server := "$defs/Server/properties/"
+	tests := []struct {
+		path string
+		// want 期望的值，nil表示路径不存在
+		want interface{}
+	}{
+		{"properties/server/$ref", "#/$defs/Server"},
+		{"properties/dB", nil},
+		{"$defs/Server/description", "Server 服务配置"},
+		{"$defs/Server/required", []interface{}{"host"}},
+		{"$defs/Server/additionalProperties", false},
+		{server + "host/description", "Host 监听地址"},
+		{server + "host/format", "hostname"},
+		{server + "port/type", "integer"},
+		{server + "port/minimum", 0.0},
+		{server + "port/maximum", 65535.0},
+		{server + "port/default", 8080.0},
+		{server + "workers/minimum", 0.0},
+		{server + "code/minLength", 3.0},
+		{server + "code/maxLength", 3.0},
+		{server + "tags/minItems", 2.0},
+		{server + "tags/maxItems", 2.0},
+		{server + "ratio/exclusiveMinimum", 0.0},
+		{server + "ratio/exclusiveMaximum", 1.0},
+		{server + "labels/minProperties", 1.0},
+		{server + "timeout/pattern", durationPattern},
+		{server + "timeout/default", "5s"},
+		{server + "region/enum", []interface{}{"cn", "us"}},
+		{server + "common", nil},
+		{server + "maxConnections/type", "integer"},
+		{server + "max_conn/type", "integer"},
+		{server + "max_conn/deprecated", nil},
+		{server + "maxConn/deprecated", true},
+		{server + "maxConn/description", "Deprecated: use maxConnections"},
+		{server + "db/$ref", "#/$defs/DB"},
+		{server + "db/default", map[string]interface{}{"pool": 10.0}},
+		{"$defs/DB/properties/url/type", "string"},
+	}
+	for _, test := range tests {
+		if got := schemaPath(schema, test.path); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s = %#v, want %#v", test.path, got, test.want)
+		}
+	}
+}
+
+// schemaPath 按 / 分隔的路径取出JSON中的值，路径不存在时返回nil
+func schemaPath(value interface{}, path string) interface{} {
+	for _, name := range strings.Split(path, "/") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+func TestDurationPattern(t *testing.T) {
+	pattern := regexp.MustCompile(durationPattern)
+	for _, value := range []string{
+		"0", "-0", "5s", "1.5h", "1h30m", ".5s", "1.s", "-1m", "+2us", "300ms", "1µs", "1μs", "10ns",
+		"", "5", "00", "s", "1x", "1 s", "+", ".s", "1h-1m",
+	} {
+		_, err := time.ParseDuration(value)
+		if matched := pattern.MatchString(value); matched != (err == nil) {
+			t.Errorf("pattern matches %q: %v, time.ParseDuration error: %v", value, matched, err)
+		}
+	}
+}
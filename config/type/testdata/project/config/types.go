@@ -0,0 +1,37 @@
+package config
+
+import "time"
+
+// Server 服务配置
+// @Configuration @Alias=server
+type Server struct {
+	// Host 监听地址
+	Host    string            `yaml:"host" validate:"required,hostname"`
+	Port    int               `yaml:"port" validate:"max=65535"`
+	Mode    string            `yaml:"mode" validate:"oneof=debug release"`
+	Timeout time.Duration     `yaml:"timeout" default:"5s"`
+	Tags    []string          `yaml:"tags"`
+	Labels  map[string]string `yaml:"labels"`
+	// Workers 工作协程数
+	// Deprecated: use pool.size
+	Workers int `yaml:"workers"`
+	//@DeprecatedKey=maxConn
+	MaxConn int `yaml:"maxConnections" alternate:"max_conn"`
+	DB      DB  `yaml:"db"`
+}
+
+// DB 数据库配置
+// @Configuration
+type DB struct {
+	URL      string `yaml:"url"`
+	Password string `yaml:"password"`
+	APIKey   string `yaml:"apiKey" secret:"true"`
+	// Token 不是敏感信息
+	Token string `yaml:"token" secret:"false"`
+	Pool  int    `yaml:"pool"`
+}
+
+// @DefaultConfig
+func DefaultServer() Server {
+	return Server{Port: 8080, Mode: "release", DB: DB{Password: "changeme", Token: "public", Pool: 10}}
+}
@@ -1,6 +1,17 @@
 package utils
 
-import "reflect"
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
 
 func IsBlank(str string) bool {
 	stringRune := []rune(str)
@@ -19,6 +30,187 @@ func NotBlank(str string) bool {
 	return !IsBlank(str)
 }
 
+// EnvName 返回配置key对应的环境变量名，例如 server.db.maxConn 对应 SERVER_DB_MAXCONN
+func EnvName(key string) string {
+	var builder strings.Builder
+	for _, r := range key {
+		switch r {
+		case '.', '[':
+			if !strings.HasSuffix(builder.String(), "_") {
+				builder.WriteByte('_')
+			}
+		case ']', '-':
+		default:
+			builder.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return builder.String()
+}
+
+// ParseBool 解析bool类型的配置值
+func ParseBool(raw string) (bool, error) {
+	b, err := strconv.ParseBool(strings.TrimSpace(raw))
+	if err != nil {
+		return false, fmt.Errorf("cannot use %q as bool", raw)
+	}
+	return b, nil
+}
+
+// ParseInt 解析整数类型的配置值，bitSize为0时表示int
+func ParseInt(raw string, bitSize int) (int64, error) {
+	i, err := strconv.ParseInt(strings.TrimSpace(raw), 0, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("cannot use %q as %s", raw, sizedName("int", bitSize))
+	}
+	return i, nil
+}
+
+// ParseUint 解析无符号整数类型的配置值，bitSize为0时表示uint
+func ParseUint(raw string, bitSize int) (uint64, error) {
+	u, err := strconv.ParseUint(strings.TrimSpace(raw), 0, bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("cannot use %q as %s", raw, sizedName("uint", bitSize))
+	}
+	return u, nil
+}
+
+// ParseFloat 解析浮点类型的配置值
+func ParseFloat(raw string, bitSize int) (float64, error) {
+	f, err := strconv.ParseFloat(strings.TrimSpace(raw), bitSize)
+	if err != nil {
+		return 0, fmt.Errorf("cannot use %q as %s", raw, sizedName("float", bitSize))
+	}
+	return f, nil
+}
+
+// ParseDuration 解析 10s、1m30s 形式的时间间隔
+func ParseDuration(raw string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("cannot use %q as duration", raw)
+	}
+	return d, nil
+}
+
+func sizedName(name string, bitSize int) string {
+	if bitSize == 0 {
+		return name
+	}
+	return name + strconv.Itoa(bitSize)
+}
+
+// Indexed 返回 key[0]、key[1]... 形式的列表配置值，遇到第一个缺失的下标为止；没有时返回nil
+func Indexed(values map[string]string, key string) []string {
+	var items []string
+	for i := 0; ; i++ {
+		value, ok := values[key+"["+strconv.Itoa(i)+"]"]
+		if !ok {
+			return items
+		}
+		items = append(items, value)
+	}
+}
+
+// Prefixed 返回以prefix开头的配置值，key去掉prefix；没有时返回nil
+func Prefixed(values map[string]string, prefix string) map[string]string {
+	var items map[string]string
+	for key, value := range values {
+		if strings.HasPrefix(key, prefix) && len(key) > len(prefix) {
+			if items == nil {
+				items = make(map[string]string)
+			}
+			items[key[len(prefix):]] = value
+		}
+	}
+	return items
+}
+
+// Items 列表配置项的值：优先使用 key[0]、key[1]...，没有时把key的值按逗号分隔，
+// 例如命令行参数 -server.tags=a,b；都没有时返回nil
+func Items(values map[string]string, key string) []string {
+	if items := Indexed(values, key); items != nil {
+		return items
+	}
+	raw, ok := values[key]
+	if !ok || IsBlank(raw) {
+		return nil
+	}
+	items := strings.Split(raw, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
+
+// Entries map配置项的值：优先使用 key.name，没有时把key的值解析为逗号分隔的 name=value，
+// 例如命令行参数 -server.labels=a=1,b=2；都没有时返回nil
+func Entries(values map[string]string, key string) (map[string]string, error) {
+	if items := Prefixed(values, key+"."); items != nil {
+		return items, nil
+	}
+	raw, ok := values[key]
+	if !ok || IsBlank(raw) {
+		return nil, nil
+	}
+	items := make(map[string]string)
+	for _, entry := range strings.Split(raw, ",") {
+		index := strings.Index(entry, "=")
+		if index <= 0 {
+			return nil, fmt.Errorf("cannot use %q as map entries, use name=value,name2=value2", raw)
+		}
+		items[strings.TrimSpace(entry[:index])] = strings.TrimSpace(entry[index+1:])
+	}
+	return items, nil
+}
+
+// HasPrefix 是否存在以prefix开头的配置值
+func HasPrefix(values map[string]string, prefix string) bool {
+	for key := range values {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Formats CheckFormat支持的validate格式规则
+var Formats = []string{"email", "url", "uri", "hostname", "ipv4", "ipv6"}
+
+var hostnamePattern = regexp.MustCompile(`^([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]{0,61}[a-zA-Z0-9])(\.([a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]{0,61}[a-zA-Z0-9]))*\.?$`)
+
+// CheckFormat 校验value是否符合validate标签中的格式规则，format为Formats之一
+func CheckFormat(format, value string) error {
+	switch format {
+	case "email":
+		if address, err := mail.ParseAddress(value); err != nil || address.Address != value {
+			return fmt.Errorf("must be a valid email address")
+		}
+	case "url":
+		if u, err := url.Parse(value); err != nil || u.Scheme == "" {
+			return fmt.Errorf("must be a valid URL")
+		}
+	case "uri":
+		if _, err := url.ParseRequestURI(value); err != nil {
+			return fmt.Errorf("must be a valid URI")
+		}
+	case "hostname":
+		if len(value) > 253 || !hostnamePattern.MatchString(value) {
+			return fmt.Errorf("must be a valid hostname")
+		}
+	case "ipv4":
+		if ip := net.ParseIP(value); ip == nil || ip.To4() == nil || strings.Contains(value, ":") {
+			return fmt.Errorf("must be a valid IPv4 address")
+		}
+	case "ipv6":
+		if ip := net.ParseIP(value); ip == nil || !strings.Contains(value, ":") {
+			return fmt.Errorf("must be a valid IPv6 address")
+		}
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	return nil
+}
+
 func GetDefaultValidators() map[reflect.Kind]func(data interface{}) bool {
 	validators := make(map[reflect.Kind]func(data interface{}) bool)
 	validators[reflect.String] = func(data interface{}) bool {
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestItems(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]string
+		want   []string
+	}{
+		{"indexed", map[string]string{"tags[0]": "a", "tags[1]": "b"}, []string{"a", "b"}},
+		{"indexed wins", map[string]string{"tags": "x,y", "tags[0]": "a"}, []string{"a"}},
+		{"comma separated", map[string]string{"tags": "a, b ,c"}, []string{"a", "b", "c"}},
+		{"single", map[string]string{"tags": "a"}, []string{"a"}},
+		{"blank", map[string]string{"tags": " "}, nil},
+		{"missing", map[string]string{"other": "a"}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if items := Items(test.values, "tags"); !reflect.DeepEqual(items, test.want) {
+				t.Fatalf("Items = %q, want %q", items, test.want)
+			}
+		})
+	}
+}
+
+func TestEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"by name", map[string]string{"labels.a": "1", "labels.b.c": "2"}, map[string]string{"a": "1", "b.c": "2"}, false},
+		{"by name wins", map[string]string{"labels": "x=1", "labels.a": "1"}, map[string]string{"a": "1"}, false},
+		{"pairs", map[string]string{"labels": "a=1, b = 2,c=x=y"}, map[string]string{"a": "1", "b": "2", "c": "x=y"}, false},
+		{"empty value", map[string]string{"labels": "a="}, map[string]string{"a": ""}, false},
+		{"missing", map[string]string{"other": "a=1"}, nil, false},
+		{"no separator", map[string]string{"labels": "a=1,b"}, nil, true},
+		{"no name", map[string]string{"labels": "=1"}, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			items, err := Entries(test.values, "labels")
+			if (err != nil) != test.wantErr {
+				t.Fatalf("error %v, want error %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(items, test.want) {
+				t.Fatalf("Entries = %v, want %v", items, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckFormat(t *testing.T) {
+	tests := []struct {
+		format string
+		value  string
+		valid  bool
+	}{
+		{"email", "dev@example.com", true},
+		{"email", "Dev <dev@example.com>", false},
+		{"email", "dev", false},
+		{"url", "https://example.com/path", true},
+		{"url", "example.com", false},
+		{"uri", "/api/v1", true},
+		{"uri", "api", false},
+		{"hostname", "db-1.internal", true},
+		{"hostname", "localhost", true},
+		{"hostname", "-db", false},
+		{"hostname", "bad_host", false},
+		{"ipv4", "10.0.0.1", true},
+		{"ipv4", "::1", false},
+		{"ipv4", "10.0.0", false},
+		{"ipv6", "::1", true},
+		{"ipv6", "10.0.0.1", false},
+		{"mac", "00:00:5e:00:53:01", false},
+	}
+	for _, test := range tests {
+		if err := CheckFormat(test.format, test.value); (err == nil) != test.valid {
+			t.Errorf("CheckFormat(%q, %q) = %v, want valid %v", test.format, test.value, err, test.valid)
+		}
+	}
+}